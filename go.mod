@@ -6,6 +6,7 @@ tool (
 	chezmoi.io/chezmoi/v2
 	chezmoi.io/chezmoi/v2/internal/cmds/execute-template
 	chezmoi.io/chezmoi/v2/internal/cmds/generate-commit
+	chezmoi.io/chezmoi/v2/internal/cmds/generate-config-schema
 	chezmoi.io/chezmoi/v2/internal/cmds/generate-helps
 	chezmoi.io/chezmoi/v2/internal/cmds/generate-install.sh
 	chezmoi.io/chezmoi/v2/internal/cmds/generate-license
@@ -55,7 +56,9 @@ require (
 	github.com/muesli/combinator v0.3.0
 	github.com/muesli/termenv v0.16.0
 	github.com/nwaples/rardecode/v2 v2.2.2
+	github.com/pelletier/go-toml/v2 v2.3.1
 	github.com/rogpeppe/go-internal v1.14.1
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
 	github.com/spf13/cobra v1.10.2
 	github.com/spf13/pflag v1.0.10
 	github.com/tailscale/hujson v0.0.0-20260302212456-ecc657c15afd
@@ -191,7 +194,6 @@ require (
 	github.com/muesli/ansi v0.0.0-20230316100256-276c6243b2f6 // indirect
 	github.com/muesli/cancelreader v0.2.2 // indirect
 	github.com/muesli/reflow v0.3.0 // indirect
-	github.com/pelletier/go-toml/v2 v2.3.1 // indirect
 	github.com/peterbourgon/diskv v2.0.1+incompatible // indirect
 	github.com/pierrec/lz4/v4 v4.1.26 // indirect
 	github.com/pjbgf/sha1cd v0.6.0 // indirect
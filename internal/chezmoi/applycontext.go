@@ -0,0 +1,82 @@
+package chezmoi
+
+import (
+	"slices"
+	"sync"
+)
+
+// An ApplyContext records the operations performed so far during an apply. It
+// is passed to scripts so that they can determine what has changed.
+type ApplyContext struct {
+	mutex   sync.Mutex
+	dryRun  bool
+	filter  *EntryTypeFilter
+	written []AbsPath
+	removed []AbsPath
+	chmoded []AbsPath
+}
+
+// An ApplyContextData is the serialized form of an ApplyContext.
+type ApplyContextData struct {
+	DryRun  bool             `json:"dryRun"  yaml:"dryRun"`
+	Filter  *EntryTypeFilter `json:"filter"  yaml:"filter"`
+	Written []AbsPath        `json:"written" yaml:"written"`
+	Removed []AbsPath        `json:"removed" yaml:"removed"`
+	Chmoded []AbsPath        `json:"chmoded" yaml:"chmoded"`
+}
+
+// NewApplyContext returns a new ApplyContext.
+func NewApplyContext(dryRun bool, filter *EntryTypeFilter) *ApplyContext {
+	return &ApplyContext{
+		dryRun: dryRun,
+		filter: filter,
+	}
+}
+
+// Data returns a snapshot of c's data.
+func (c *ApplyContext) Data() *ApplyContextData {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return &ApplyContextData{
+		DryRun:  c.dryRun,
+		Filter:  c.filter,
+		Written: nonNilAbsPaths(c.written),
+		Removed: nonNilAbsPaths(c.removed),
+		Chmoded: nonNilAbsPaths(c.chmoded),
+	}
+}
+
+// Changed returns all target paths changed so far.
+func (c *ApplyContext) Changed() []AbsPath {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	changed := slices.Concat(c.written, c.removed, c.chmoded)
+	slices.Sort(changed)
+	return slices.Compact(changed)
+}
+
+// record records that targetAbsPath was changed from actualEntryState to
+// targetEntryState.
+func (c *ApplyContext) record(targetAbsPath AbsPath, targetEntryState, actualEntryState *EntryState) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	switch {
+	case targetEntryState == nil || targetEntryState.Type == EntryStateTypeRemove:
+		c.removed = append(c.removed, targetAbsPath)
+	case targetEntryState.Type == EntryStateTypeScript:
+		// Scripts do not change targets.
+	case actualEntryState != nil &&
+		actualEntryState.Type == targetEntryState.Type &&
+		actualEntryState.Mode.Perm() != targetEntryState.Mode.Perm() &&
+		slices.Equal(actualEntryState.ContentsSHA256, targetEntryState.ContentsSHA256):
+		c.chmoded = append(c.chmoded, targetAbsPath)
+	default:
+		c.written = append(c.written, targetAbsPath)
+	}
+}
+
+// nonNilAbsPaths returns a copy of absPaths that is never nil, so that it is
+// serialized as an empty list rather than null.
+func nonNilAbsPaths(absPaths []AbsPath) []AbsPath {
+	return append(make([]AbsPath, 0, len(absPaths)), absPaths...)
+}
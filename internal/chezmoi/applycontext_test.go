@@ -0,0 +1,65 @@
+package chezmoi
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestApplyContextRecord(t *testing.T) {
+	applyContext := NewApplyContext(false, NewEntryTypeFilter(EntryTypesAll, EntryTypesNone))
+	applyContext.record(NewAbsPath("/home/user/.chmod"), &EntryState{
+		Type:           EntryStateTypeFile,
+		Mode:           0o755,
+		ContentsSHA256: HexBytes{1},
+	}, &EntryState{
+		Type:           EntryStateTypeFile,
+		Mode:           0o644,
+		ContentsSHA256: HexBytes{1},
+	})
+	applyContext.record(NewAbsPath("/home/user/.new"), &EntryState{
+		Type:           EntryStateTypeFile,
+		Mode:           0o644,
+		ContentsSHA256: HexBytes{1},
+	}, nil)
+	applyContext.record(NewAbsPath("/home/user/.modified"), &EntryState{
+		Type:           EntryStateTypeFile,
+		Mode:           0o644,
+		ContentsSHA256: HexBytes{2},
+	}, &EntryState{
+		Type:           EntryStateTypeFile,
+		Mode:           0o644,
+		ContentsSHA256: HexBytes{1},
+	})
+	applyContext.record(NewAbsPath("/home/user/.remove"), &EntryState{
+		Type: EntryStateTypeRemove,
+	}, &EntryState{
+		Type: EntryStateTypeFile,
+		Mode: 0o644,
+	})
+	applyContext.record(NewAbsPath("/home/user/script.sh"), &EntryState{
+		Type: EntryStateTypeScript,
+	}, nil)
+	applyContext.record(NewAbsPath("/home/user/.dir"), &EntryState{
+		Type: EntryStateTypeDir,
+		Mode: fs.ModeDir | 0o755,
+	}, nil)
+
+	data := applyContext.Data()
+	assert.False(t, data.DryRun)
+	assert.Equal(t, []AbsPath{
+		NewAbsPath("/home/user/.new"),
+		NewAbsPath("/home/user/.modified"),
+		NewAbsPath("/home/user/.dir"),
+	}, data.Written)
+	assert.Equal(t, []AbsPath{NewAbsPath("/home/user/.remove")}, data.Removed)
+	assert.Equal(t, []AbsPath{NewAbsPath("/home/user/.chmod")}, data.Chmoded)
+	assert.Equal(t, []AbsPath{
+		NewAbsPath("/home/user/.chmod"),
+		NewAbsPath("/home/user/.dir"),
+		NewAbsPath("/home/user/.modified"),
+		NewAbsPath("/home/user/.new"),
+		NewAbsPath("/home/user/.remove"),
+	}, applyContext.Changed())
+}
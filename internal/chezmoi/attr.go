@@ -41,9 +41,10 @@ type ScriptOrder int
 
 // Script orders.
 const (
-	ScriptOrderBefore ScriptOrder = -1
-	ScriptOrderDuring ScriptOrder = 0
-	ScriptOrderAfter  ScriptOrder = 1
+	ScriptOrderBefore  ScriptOrder = -1
+	ScriptOrderDuring  ScriptOrder = 0
+	ScriptOrderAfter   ScriptOrder = 1
+	ScriptOrderFinally ScriptOrder = 2
 )
 
 // A ScriptCondition defines under what conditions a script should be executed.
@@ -227,6 +228,9 @@ func parseFileAttr(name, encryptedSuffix string) (FileAttr, error) {
 		case strings.HasPrefix(name, afterPrefix):
 			name = name[len(afterPrefix):]
 			order = ScriptOrderAfter
+		case strings.HasPrefix(name, finallyPrefix):
+			name = name[len(finallyPrefix):]
+			order = ScriptOrderFinally
 		}
 	case strings.HasPrefix(name, symlinkPrefix):
 		sourceFileType = SourceFileTypeSymlink
@@ -363,6 +367,8 @@ func (fa FileAttr) SourceName(encryptedSuffix string) string {
 			sourceName += beforePrefix
 		case ScriptOrderAfter:
 			sourceName += afterPrefix
+		case ScriptOrderFinally:
+			sourceName += finallyPrefix
 		}
 	case SourceFileTypeSymlink:
 		sourceName = symlinkPrefix
@@ -173,7 +173,7 @@ func TestFileAttr(t *testing.T) {
 			ScriptConditionOnChange,
 		},
 		TargetName: targetNames,
-		Order:      []ScriptOrder{ScriptOrderBefore, ScriptOrderDuring, ScriptOrderAfter},
+		Order:      []ScriptOrder{ScriptOrderBefore, ScriptOrderDuring, ScriptOrderAfter, ScriptOrderFinally},
 	}))
 	assert.NoError(t, combinator.Generate(&fileAttrs, struct {
 		Type       SourceFileTargetType
@@ -129,6 +129,9 @@ func (b *BoltPersistentState) DeleteBucket(bucket []byte) error {
 	}
 
 	return b.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(bucket) == nil {
+			return nil
+		}
 		return tx.DeleteBucket(bucket)
 	})
 }
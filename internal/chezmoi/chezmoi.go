@@ -44,6 +44,7 @@ const (
 	exactPrefix      = "exact_"
 	executablePrefix = "executable_"
 	externalPrefix   = "external_"
+	finallyPrefix    = "finally_"
 	literalPrefix    = "literal_"
 	modifyPrefix     = "modify_"
 	oncePrefix       = "once_"
@@ -61,21 +62,27 @@ const (
 const (
 	Prefix = ".chezmoi"
 
-	RootName         = Prefix + "root"
-	TemplatesDirName = Prefix + "templates"
-	VersionName      = Prefix + "version"
-	dataName         = Prefix + "data"
-	externalName     = Prefix + "external"
-	externalsDirName = Prefix + "externals"
-	ignoreName       = Prefix + "ignore"
-	removeName       = Prefix + "remove"
-	scriptsDirName   = Prefix + "scripts"
+	RootName              = Prefix + "root"
+	TemplatesDirName      = Prefix + "templates"
+	TestsDirName          = Prefix + "tests"
+	VersionName           = Prefix + "version"
+	DataSchemaName        = dataName + ".schema.json"
+	dataName              = Prefix + "data"
+	dataGeneratorsDirName = dataName + ".d"
+	externalName          = Prefix + "external"
+	externalsDirName      = Prefix + "externals"
+	ignoreName            = Prefix + "ignore"
+	moduleName            = Prefix + "module"
+	reloadName            = Prefix + "reload"
+	removeName            = Prefix + "remove"
+	scriptsDirName        = Prefix + "scripts"
+	validateName          = Prefix + "validate"
 )
 
 var (
 	dirPrefixRx  = regexp.MustCompile(`\A(dot|exact|literal|readonly|private)_`)
 	filePrefixRx = regexp.MustCompile(
-		`\A(after|before|create|dot|empty|encrypted|executable|literal|modify|once|private|readonly|remove|run|symlink)_`,
+		`\A(after|before|create|dot|empty|encrypted|executable|finally|literal|modify|once|private|readonly|remove|run|symlink)_`,
 	)
 	fileSuffixRx = regexp.MustCompile(`\.(literal|tmpl)\z`)
 	whitespaceRx = regexp.MustCompile(`\s+`)
@@ -88,6 +95,7 @@ var knownPrefixedFiles = chezmoiset.New(
 	Prefix+".yaml"+TemplateSuffix,
 	RootName,
 	VersionName,
+	DataSchemaName,
 	dataName+".json",
 	dataName+".toml",
 	dataName+".yaml",
@@ -99,13 +107,22 @@ var knownPrefixedFiles = chezmoiset.New(
 	externalName+".yaml",
 	ignoreName+TemplateSuffix,
 	ignoreName,
+	moduleName+".json",
+	moduleName+".toml",
+	moduleName+".yaml",
+	reloadName+TemplateSuffix,
+	reloadName,
 	removeName+TemplateSuffix,
 	removeName,
+	validateName+TemplateSuffix,
+	validateName,
 )
 
 // knownPrefixedDirs is a set of known dirnames with the .chezmoi prefix.
 var knownPrefixedDirs = chezmoiset.New(
 	TemplatesDirName,
+	TestsDirName,
+	dataGeneratorsDirName,
 	dataName,
 	externalsDirName,
 	scriptsDirName,
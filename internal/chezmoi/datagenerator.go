@@ -0,0 +1,156 @@
+package chezmoi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoilog"
+)
+
+// DataGeneratorsCacheDirName is the name of the directory in the cache
+// directory that contains the cached output of data generators.
+const DataGeneratorsCacheDirName = "data"
+
+// dataGeneratorFormats are the formats that the output of data generators is
+// parsed as, in order. YAML is last because many invalid documents in other
+// formats are valid YAML.
+var dataGeneratorFormats = []struct {
+	name   string
+	format Format
+}{
+	{"JSON", FormatJSON},
+	{"TOML", FormatTOML},
+	{"YAML", FormatYAML},
+}
+
+// addTemplateDataGeneratorsDir runs all data generators in the directory
+// sourceAbsPath in lexical order and adds their output to the template data
+// for dirRelPath.
+func (s *SourceState) addTemplateDataGeneratorsDir(sourceAbsPath AbsPath, fileInfo fs.FileInfo, dirRelPath RelPath) error {
+	walkFunc := func(generatorAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
+		if generatorAbsPath == sourceAbsPath {
+			return nil
+		}
+		if err == nil && fileInfo.Mode().Type() == fs.ModeSymlink {
+			fileInfo, err = s.system.Stat(generatorAbsPath)
+		}
+		switch {
+		case err != nil:
+			return err
+		case strings.HasPrefix(fileInfo.Name(), Prefix):
+			return fmt.Errorf("%s: not allowed in %s directory", generatorAbsPath, dataGeneratorsDirName)
+		case strings.HasPrefix(fileInfo.Name(), ignorePrefix):
+			if fileInfo.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		case fileInfo.Mode().IsRegular():
+			return s.addTemplateDataGenerator(generatorAbsPath, dirRelPath)
+		case fileInfo.IsDir():
+			return fs.SkipDir
+		default:
+			return &UnsupportedFileTypeError{
+				absPath: generatorAbsPath,
+				mode:    fileInfo.Mode(),
+			}
+		}
+	}
+	return walkSourceDirHelper(s.system, sourceAbsPath, fileInfo, walkFunc)
+}
+
+// addTemplateDataGenerator runs the data generator at generatorAbsPath and adds
+// its output to the template data for dirRelPath. In restricted mode the data
+// generator is not run and is reported by checkRestricted.
+func (s *SourceState) addTemplateDataGenerator(generatorAbsPath AbsPath, dirRelPath RelPath) error {
+	if s.restricted {
+		s.blockedDataGenerators = append(s.blockedDataGenerators, generatorAbsPath.MustTrimDirPrefix(s.sourceLayer(generatorAbsPath)))
+		return nil
+	}
+	output, err := s.templateDataGeneratorOutput(generatorAbsPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", generatorAbsPath, err)
+	}
+	templateData, err := parseTemplateDataGeneratorOutput(output)
+	if err != nil {
+		return fmt.Errorf("%s: %w", generatorAbsPath, err)
+	}
+	s.mergeTemplateData(templateData, dirRelPath)
+	return nil
+}
+
+// templateDataGeneratorOutput returns the output of the data generator at
+// generatorAbsPath, using the cache if its output was cached less than
+// s.dataGeneratorCacheTTL ago.
+func (s *SourceState) templateDataGeneratorOutput(generatorAbsPath AbsPath) ([]byte, error) {
+	contents, err := s.system.ReadFile(generatorAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	generatorRelPath := generatorAbsPath.MustTrimDirPrefix(s.sourceLayer(generatorAbsPath))
+
+	// The cache key includes the generator's contents so that changes to the
+	// generator invalidate the cache.
+	var cachedOutputAbsPath AbsPath
+	if s.dataGeneratorCacheTTL > 0 && !s.cacheDirAbsPath.IsEmpty() {
+		cacheKeySHA256 := sha256.Sum256([]byte(generatorRelPath.String() + "\x00" + string(contents)))
+		cacheKey := hex.EncodeToString(cacheKeySHA256[:])
+		cachedOutputAbsPath = s.cacheDirAbsPath.JoinString(DataGeneratorsCacheDirName, cacheKey)
+		if fileInfo, err := s.baseSystem.Stat(cachedOutputAbsPath); err == nil {
+			if fileInfo.ModTime().Add(s.dataGeneratorCacheTTL).After(time.Now()) {
+				if output, err := s.baseSystem.ReadFile(cachedOutputAbsPath); err == nil {
+					return output, nil
+				}
+			}
+		}
+	}
+
+	// If the generator has an extension, determine if it indicates an
+	// interpreter to use.
+	extension := strings.ToLower(strings.TrimPrefix(generatorAbsPath.Ext(), "."))
+	interpreter := s.interpreters[extension]
+	cmd := interpreter.ExecCommand(generatorAbsPath.String())
+	cmd.Dir = generatorAbsPath.Dir().String()
+	cmd.Env = append(os.Environ(),
+		"CHEZMOI_SOURCE_FILE="+generatorRelPath.String(),
+	)
+	cmd.Stderr = os.Stderr
+	output, err := chezmoilog.LogCmdOutput(s.logger, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cachedOutputAbsPath.IsEmpty() {
+		if err := MkdirAll(s.baseSystem, cachedOutputAbsPath.Dir(), 0o700); err != nil {
+			return nil, err
+		}
+		if err := s.baseSystem.WriteFile(cachedOutputAbsPath, output, 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// parseTemplateDataGeneratorOutput parses output, the output of a data
+// generator, as template data.
+func parseTemplateDataGeneratorOutput(output []byte) (map[string]any, error) {
+	if len(strings.TrimSpace(string(output))) == 0 {
+		return nil, nil
+	}
+	errs := make([]error, 0, len(dataGeneratorFormats))
+	for _, dataGeneratorFormat := range dataGeneratorFormats {
+		var templateData map[string]any
+		err := dataGeneratorFormat.format.Unmarshal(output, &templateData)
+		if err == nil {
+			return templateData, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", dataGeneratorFormat.name, err))
+	}
+	return nil, fmt.Errorf("cannot parse output as JSON, TOML, or YAML: %w", errors.Join(errs...))
+}
@@ -0,0 +1,194 @@
+package chezmoi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// A TemplateDataSchema is a JSON Schema that template data must match.
+type TemplateDataSchema struct {
+	absPath AbsPath
+	doc     any
+	schema  *jsonschema.Schema
+}
+
+// ParseTemplateDataSchema parses a TemplateDataSchema from data, the contents
+// of the file at absPath.
+func ParseTemplateDataSchema(absPath AbsPath, data []byte) (*TemplateDataSchema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", absPath, err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(absPath.String(), doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", absPath, err)
+	}
+	schema, err := compiler.Compile(absPath.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", absPath, err)
+	}
+	return &TemplateDataSchema{
+		absPath: absPath,
+		doc:     doc,
+		schema:  schema,
+	}, nil
+}
+
+// addTemplateDataSchema reads the template data schema at sourceAbsPath in
+// dirRelPath. Only a schema in the root of the source directory is supported.
+func (s *SourceState) addTemplateDataSchema(sourceAbsPath AbsPath, dirRelPath RelPath) error {
+	if dirRelPath != DotRelPath {
+		return fmt.Errorf("%s: only allowed in the root of the source directory", sourceAbsPath)
+	}
+	data, err := s.system.ReadFile(sourceAbsPath)
+	if err != nil {
+		return err
+	}
+	templateDataSchema, err := ParseTemplateDataSchema(sourceAbsPath, data)
+	if err != nil {
+		return err
+	}
+	s.templateDataSchema = templateDataSchema
+	return nil
+}
+
+// Default returns the default value of the property at path, if any.
+func (s *TemplateDataSchema) Default(path []string) (any, bool) {
+	propertySchema, ok := s.propertySchema(path)
+	if !ok {
+		return nil, false
+	}
+	defaultValue, ok := propertySchema["default"]
+	if !ok {
+		return nil, false
+	}
+	return fromJSONNumber(defaultValue), true
+}
+
+// Enum returns the allowed values of the property at path, if any.
+func (s *TemplateDataSchema) Enum(path []string) []any {
+	propertySchema, ok := s.propertySchema(path)
+	if !ok {
+		return nil
+	}
+	enum, ok := propertySchema["enum"].([]any)
+	if !ok {
+		return nil
+	}
+	values := make([]any, 0, len(enum))
+	for _, value := range enum {
+		values = append(values, fromJSONNumber(value))
+	}
+	return values
+}
+
+// Validate returns an error listing every violation of s in templateData. The
+// .chezmoi key, which is set by chezmoi, is not validated.
+func (s *TemplateDataSchema) Validate(templateData map[string]any) error {
+	violations, err := s.violations(templateData)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%s: %w", s.absPath, err)
+	case len(violations) != 0:
+		return fmt.Errorf("%s: template data does not match schema\n%s", s.absPath, strings.Join(violations, "\n"))
+	default:
+		return nil
+	}
+}
+
+// violations returns a description of every violation of s in templateData,
+// each prefixed with the JSON pointer to the violating value.
+func (s *TemplateDataSchema) violations(templateData map[string]any) ([]string, error) {
+	templateData = copyTemplateData(templateData)
+	delete(templateData, "chezmoi")
+
+	// Round-trip templateData through JSON so that it only contains the types
+	// that the validator understands.
+	data, err := json.Marshal(templateData)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var validationError *jsonschema.ValidationError
+	switch err := s.schema.Validate(instance); {
+	case errors.As(err, &validationError):
+		printer := message.NewPrinter(language.English)
+		var violations []string
+		var addViolations func(*jsonschema.ValidationError)
+		addViolations = func(validationError *jsonschema.ValidationError) {
+			if len(validationError.Causes) == 0 {
+				instanceLocation := jsonPointer(validationError.InstanceLocation)
+				violations = append(violations, instanceLocation+": "+validationError.ErrorKind.LocalizedString(printer))
+			}
+			for _, cause := range validationError.Causes {
+				addViolations(cause)
+			}
+		}
+		addViolations(validationError)
+		slices.Sort(violations)
+		return violations, nil
+	case err != nil:
+		return nil, err
+	default:
+		return nil, nil
+	}
+}
+
+// propertySchema returns the schema of the property at path. It only follows
+// properties keywords.
+func (s *TemplateDataSchema) propertySchema(path []string) (map[string]any, bool) {
+	schema, ok := s.doc.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	for _, key := range path {
+		properties, ok := schema["properties"].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if schema, ok = properties[key].(map[string]any); !ok {
+			return nil, false
+		}
+	}
+	return schema, true
+}
+
+// fromJSONNumber converts value to an int64 or float64 if it is a
+// [json.Number].
+func fromJSONNumber(value any) any {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if intValue, err := number.Int64(); err == nil {
+		return intValue
+	}
+	floatValue, _ := number.Float64()
+	return floatValue
+}
+
+// jsonPointer returns the JSON pointer to tokens. The pointer to the root is
+// "/".
+func jsonPointer(tokens []string) string {
+	if len(tokens) == 0 {
+		return "/"
+	}
+	replacer := strings.NewReplacer("~", "~0", "/", "~1")
+	escapedTokens := slices.Clone(tokens)
+	for i, token := range escapedTokens {
+		escapedTokens[i] = replacer.Replace(token)
+	}
+	return "/" + strings.Join(escapedTokens, "/")
+}
@@ -0,0 +1,95 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestTemplateDataSchema(t *testing.T) {
+	templateDataSchema, err := ParseTemplateDataSchema(NewAbsPath("/home/user/.local/share/chezmoi/.chezmoidata.schema.json"), []byte(`{
+  "type": "object",
+  "properties": {
+    "editor": {
+      "enum": ["emacs", "vim"],
+      "default": "vim"
+    },
+    "git": {
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string"
+        },
+        "signingKeys": {
+          "type": "integer",
+          "default": 2
+        }
+      },
+      "required": ["email"]
+    },
+    "a/b": {
+      "type": "boolean"
+    }
+  }
+}`))
+	assert.NoError(t, err)
+
+	defaultValue, ok := templateDataSchema.Default([]string{"editor"})
+	assert.True(t, ok)
+	assert.Equal(t, any("vim"), defaultValue)
+
+	defaultValue, ok = templateDataSchema.Default([]string{"git", "signingKeys"})
+	assert.True(t, ok)
+	assert.Equal(t, any(int64(2)), defaultValue)
+
+	_, ok = templateDataSchema.Default([]string{"git", "email"})
+	assert.False(t, ok)
+
+	_, ok = templateDataSchema.Default([]string{"missing", "key"})
+	assert.False(t, ok)
+
+	assert.Equal(t, []any{"emacs", "vim"}, templateDataSchema.Enum([]string{"editor"}))
+	assert.Equal(t, nil, templateDataSchema.Enum([]string{"git"}))
+
+	for _, tc := range []struct {
+		name               string
+		templateData       map[string]any
+		expectedViolations []string
+	}{
+		{
+			name: "valid",
+			templateData: map[string]any{
+				"chezmoi": map[string]any{
+					"os": "linux",
+				},
+				"editor": "vim",
+				"git": map[string]any{
+					"email":       "me@example.com",
+					"signingKeys": int64(1),
+				},
+			},
+		},
+		{
+			name: "invalid",
+			templateData: map[string]any{
+				"editor": "nano",
+				"git": map[string]any{
+					"signingKeys": "one",
+				},
+				"a/b": "true",
+			},
+			expectedViolations: []string{
+				"/a~1b: got string, want boolean",
+				"/editor: value must be one of 'emacs', 'vim'",
+				"/git/signingKeys: got string, want integer",
+				"/git: missing property 'email'",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			violations, err := templateDataSchema.violations(tc.templateData)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedViolations, violations)
+		})
+	}
+}
@@ -170,6 +170,8 @@ func (s *DebugSystem) RunScript(scriptName RelPath, dir AbsPath, data []byte, op
 		chezmoilog.FirstFewBytes("data", data),
 		slog.Any("interpreter", options.Interpreter),
 		slog.String("condition", string(options.Condition)),
+		slog.Any("args", options.Args),
+		chezmoilog.Stringer("workDir", options.WorkDir),
 	}
 	attrs = chezmoilog.AppendExitErrorAttrs(attrs, err)
 	chezmoilog.InfoOrError(s.logger, "RunScript", err, attrs...)
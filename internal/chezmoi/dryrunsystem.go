@@ -1,8 +1,11 @@
 package chezmoi
 
 import (
+	"fmt"
+	"io"
 	"io/fs"
 	"os/exec"
+	"slices"
 	"time"
 
 	vfs "github.com/twpayne/go-vfs/v5"
@@ -11,15 +14,40 @@ import (
 // DryRunSystem is an System that reads from, but does not write to, to
 // a wrapped System.
 type DryRunSystem struct {
-	system   System
-	modified bool
+	system       System
+	modified     bool
+	runScripts   bool
+	scriptWriter io.Writer
+}
+
+// A DryRunSystemOption sets an option on a DryRunSystem.
+type DryRunSystemOption func(*DryRunSystem)
+
+// DryRunSystemWithRunScripts sets whether scripts that support dry-run mode
+// are run.
+func DryRunSystemWithRunScripts(runScripts bool) DryRunSystemOption {
+	return func(s *DryRunSystem) {
+		s.runScripts = runScripts
+	}
+}
+
+// DryRunSystemWithScriptWriter sets the writer to which the names of scripts
+// that are run in dry-run mode are written.
+func DryRunSystemWithScriptWriter(scriptWriter io.Writer) DryRunSystemOption {
+	return func(s *DryRunSystem) {
+		s.scriptWriter = scriptWriter
+	}
 }
 
 // NewDryRunSystem returns a new DryRunSystem that wraps fs.
-func NewDryRunSystem(system System) *DryRunSystem {
-	return &DryRunSystem{
+func NewDryRunSystem(system System, options ...DryRunSystemOption) *DryRunSystem {
+	s := &DryRunSystem{
 		system: system,
 	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
 }
 
 // Chmod implements System.Chmod.
@@ -106,10 +134,30 @@ func (s *DryRunSystem) RunCmd(cmd *exec.Cmd) error {
 	return nil
 }
 
-// RunScript implements System.RunScript.
+// RunScript implements System.RunScript. Scripts are only run if s runs
+// scripts and the script supports dry-run mode, in which case
+// CHEZMOI_DRY_RUN=1 is set in the script's environment. If s has a script
+// writer then it reports whether each script is run.
 func (s *DryRunSystem) RunScript(scriptName RelPath, dir AbsPath, data []byte, options RunScriptOptions) error {
 	s.setModified()
-	return nil
+	if !s.runScripts {
+		return nil
+	}
+	if options.DryRun != ScriptDryRunRun {
+		if s.scriptWriter != nil {
+			if _, err := fmt.Fprintf(s.scriptWriter, "not running %s in dry-run mode\n", scriptName); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if s.scriptWriter != nil {
+		if _, err := fmt.Fprintf(s.scriptWriter, "running %s in dry-run mode\n", scriptName); err != nil {
+			return err
+		}
+	}
+	options.Env = append(slices.Clone(options.Env), "CHEZMOI_DRY_RUN=1")
+	return s.system.RunScript(scriptName, dir, data, options)
 }
 
 // Stat implements System.Stat.
@@ -56,6 +56,9 @@ type DumpSystemScriptData struct {
 	Contents    string             `json:"contents"              yaml:"contents"`
 	Condition   string             `json:"condition"             yaml:"condition"`
 	Interpreter *Interpreter       `json:"interpreter,omitempty" yaml:"interpreter,omitempty"`
+	Env         []string           `json:"env,omitempty"         yaml:"env,omitempty"`
+	Args        []string           `json:"args,omitempty"        yaml:"args,omitempty"`
+	WorkDir     AbsPath            `json:"workDir,omitempty"     yaml:"workDir,omitempty"`
 }
 
 // A DumpSystemSymlinkData contains data about a symlink.
@@ -112,6 +115,9 @@ func (s *DumpSystem) RunScript(scriptName RelPath, dir AbsPath, data []byte, opt
 	if !options.Interpreter.None() {
 		scriptData.Interpreter = options.Interpreter
 	}
+	scriptData.Env = options.Env
+	scriptData.Args = options.Args
+	scriptData.WorkDir = options.WorkDir
 	return s.setData(scriptNameStr, scriptData)
 }
 
@@ -6,8 +6,8 @@ import "io/fs"
 // in the include set is included, otherwise if the entry is in the exclude set
 // then it is excluded, otherwise it is included.
 type EntryTypeFilter struct {
-	Include *EntryTypeSet
-	Exclude *EntryTypeSet
+	Include *EntryTypeSet `json:"include" yaml:"include"`
+	Exclude *EntryTypeSet `json:"exclude" yaml:"exclude"`
 }
 
 // NewEntryTypeFilter returns a new EntryTypeFilter with the given entry type
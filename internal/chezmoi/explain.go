@@ -0,0 +1,237 @@
+package chezmoi
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoiset"
+)
+
+// An Explanation explains how a target is derived from the source state.
+type Explanation struct {
+	Target         string                  `json:"target"                   yaml:"target"`
+	Source         string                  `json:"source,omitempty"         yaml:"source,omitempty"`
+	Layer          string                  `json:"layer,omitempty"          yaml:"layer,omitempty"`
+	Origin         string                  `json:"origin,omitempty"         yaml:"origin,omitempty"`
+	Type           string                  `json:"type,omitempty"           yaml:"type,omitempty"`
+	Attributes     []string                `json:"attributes,omitempty"     yaml:"attributes,omitempty"`
+	Ignored        bool                    `json:"ignored"                  yaml:"ignored"`
+	IgnorePatterns []string                `json:"ignorePatterns,omitempty" yaml:"ignorePatterns,omitempty"`
+	Dependencies   *TemplateDependencies   `json:"dependencies,omitempty"   yaml:"dependencies,omitempty"`
+	EntryStates    *ExplanationEntryStates `json:"entryStates,omitempty"    yaml:"entryStates,omitempty"`
+	Error          string                  `json:"error,omitempty"          yaml:"error,omitempty"`
+}
+
+// ExplanationEntryStates are the entry states of a target.
+type ExplanationEntryStates struct {
+	LastWritten *EntryState `json:"lastWritten,omitempty" yaml:"lastWritten,omitempty"`
+	Actual      *EntryState `json:"actual,omitempty"      yaml:"actual,omitempty"`
+	Target      *EntryState `json:"target,omitempty"      yaml:"target,omitempty"`
+}
+
+// TemplateDependencies are the template data keys, template functions, and
+// templates in .chezmoitemplates referenced by a template.
+type TemplateDependencies struct {
+	DataKeys  []string `json:"dataKeys,omitempty"  yaml:"dataKeys,omitempty"`
+	Funcs     []string `json:"funcs,omitempty"     yaml:"funcs,omitempty"`
+	Templates []string `json:"templates,omitempty" yaml:"templates,omitempty"`
+}
+
+// Explain returns an explanation of targetRelPath. destSystem and
+// persistentState are used to get the actual and last written entry states.
+func (s *SourceState) Explain(
+	targetRelPath RelPath,
+	destSystem System,
+	persistentState PersistentState,
+) (*Explanation, error) {
+	explanation := &Explanation{
+		Target:         targetRelPath.String(),
+		IgnorePatterns: s.ignorePatterns(targetRelPath),
+	}
+	for relPath := targetRelPath; relPath != DotRelPath; relPath = relPath.Dir() {
+		if s.ignore.Match(relPath.String()) == PatternSetMatchInclude {
+			explanation.Ignored = true
+			break
+		}
+	}
+
+	sourceStateEntry := s.root.Get(targetRelPath)
+	if sourceStateEntry == nil {
+		if explanation.Ignored {
+			return explanation, nil
+		}
+		return nil, errors.New("not managed")
+	}
+	if sourceRelPath := sourceStateEntry.SourceRelPath(); !sourceRelPath.IsEmpty() {
+		sourceAbsPath := s.SourceAbsPath(sourceStateEntry)
+		explanation.Source = sourceAbsPath.String()
+		if len(s.sourceLayerAbsPaths) > 1 {
+			explanation.Layer = s.sourceLayer(sourceAbsPath).String()
+		}
+	}
+	if origin := sourceStateEntry.Origin(); origin != nil {
+		explanation.Origin = origin.OriginString()
+	}
+
+	switch sourceStateEntry := sourceStateEntry.(type) {
+	case *SourceStateDir:
+		explanation.Type = "dir"
+		explanation.Attributes = sourceStateEntry.attr.attributes()
+	case *SourceStateFile:
+		fileAttr := sourceStateEntry.Attr()
+		explanation.Type = sourceFileTypeStrs[fileAttr.Type]
+		explanation.Attributes = fileAttr.attributes()
+	case *SourceStateImplicitDir:
+		explanation.Type = "dir"
+	case *SourceStateRemove:
+		explanation.Type = "remove"
+	}
+
+	targetAbsPath := s.destDirAbsPath.Join(targetRelPath)
+	explanation.EntryStates = &ExplanationEntryStates{}
+
+	var lastWrittenEntryState EntryState
+	switch ok, err := PersistentStateGet(persistentState, EntryStateBucket, targetAbsPath.Bytes(), &lastWrittenEntryState); {
+	case err != nil:
+		return nil, err
+	case ok:
+		explanation.EntryStates.LastWritten = &lastWrittenEntryState
+	}
+
+	actualStateEntry, err := NewActualStateEntry(destSystem, targetAbsPath, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	explanation.EntryStates.Actual, err = actualStateEntry.EntryState()
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the dependencies of templates from the events traced while
+	// computing the target state.
+	var numTraceEvents int
+	if s.templateTracer != nil {
+		numTraceEvents = len(s.templateTracer.Events())
+		if sourceStateFile, ok := sourceStateEntry.(*SourceStateFile); ok && sourceStateFile.Attr().Template {
+			defer func() {
+				explanation.Dependencies = s.templateTraceDependencies(s.templateTracer.Events()[numTraceEvents:])
+			}()
+		}
+	}
+
+	targetStateEntry, err := sourceStateEntry.TargetStateEntry(destSystem, targetAbsPath)
+	if err != nil {
+		explanation.Error = err.Error()
+		return explanation, nil
+	}
+	explanation.EntryStates.Target, err = targetStateEntry.EntryState(s.umask)
+	if err != nil {
+		explanation.Error = err.Error()
+	}
+
+	return explanation, nil
+}
+
+// ignorePatterns returns the patterns in .chezmoiignore that match
+// targetRelPath or any of its parents. Patterns that exclude matches are
+// prefixed with a !.
+func (s *SourceState) ignorePatterns(targetRelPath RelPath) []string {
+	patterns := chezmoiset.New[string]()
+	for relPath := targetRelPath; relPath != DotRelPath; relPath = relPath.Dir() {
+		includePatterns, excludePatterns := s.ignore.matchingPatterns(relPath.String())
+		patterns.Add(includePatterns...)
+		for _, excludePattern := range excludePatterns {
+			patterns.Add("!" + excludePattern)
+		}
+	}
+	return slices.Sorted(patterns.Elements())
+}
+
+// templateTraceDependencies returns the template data keys, template
+// functions, and templates in .chezmoitemplates used in events.
+func (s *SourceState) templateTraceDependencies(events []*TemplateTraceEvent) *TemplateDependencies {
+	dataKeys := chezmoiset.New[string]()
+	funcs := chezmoiset.New[string]()
+	templates := chezmoiset.New[string]()
+	addTemplate := func(name string) {
+		if _, ok := s.templates[name]; ok {
+			templates.Add(name)
+		}
+	}
+	for _, event := range events {
+		switch event.Type {
+		case TemplateTraceEventTypeCall:
+			funcs.Add(event.Name)
+			if event.Name == "includeTemplate" && len(event.Args) > 0 {
+				if name, ok := event.Args[0].(string); ok {
+					addTemplate(name)
+				}
+			}
+		case TemplateTraceEventTypeLookup:
+			// Only record lookups of fields of the template data.
+			if dataKey := strings.TrimPrefix(event.Name, "$"); strings.HasPrefix(dataKey, ".") {
+				dataKeys.Add(dataKey)
+			}
+		case TemplateTraceEventTypeTemplate:
+			addTemplate(event.Name)
+		}
+	}
+	return &TemplateDependencies{
+		DataKeys:  slices.Sorted(dataKeys.Elements()),
+		Funcs:     slices.Sorted(funcs.Elements()),
+		Templates: slices.Sorted(templates.Elements()),
+	}
+}
+
+// attributes returns the names of da's attributes.
+func (da DirAttr) attributes() []string {
+	var attributes []string
+	for _, attribute := range []struct {
+		name  string
+		value bool
+	}{
+		{"exact", da.Exact},
+		{"external", da.External},
+		{"private", da.Private},
+		{"readonly", da.ReadOnly},
+		{"remove", da.Remove},
+	} {
+		if attribute.value {
+			attributes = append(attributes, attribute.name)
+		}
+	}
+	return attributes
+}
+
+// attributes returns the names of fa's attributes.
+func (fa FileAttr) attributes() []string {
+	var attributes []string
+	switch fa.Order {
+	case ScriptOrderBefore:
+		attributes = append(attributes, "before")
+	case ScriptOrderAfter:
+		attributes = append(attributes, "after")
+	case ScriptOrderFinally:
+		attributes = append(attributes, "finally")
+	}
+	if fa.Condition != ScriptConditionNone && fa.Condition != ScriptConditionAlways {
+		attributes = append(attributes, string(fa.Condition))
+	}
+	for _, attribute := range []struct {
+		name  string
+		value bool
+	}{
+		{"empty", fa.Empty},
+		{"encrypted", fa.Encrypted},
+		{"executable", fa.Executable},
+		{"private", fa.Private},
+		{"readonly", fa.ReadOnly},
+		{"template", fa.Template},
+	} {
+		if attribute.value {
+			attributes = append(attributes, attribute.name)
+		}
+	}
+	return attributes
+}
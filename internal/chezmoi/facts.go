@@ -0,0 +1,357 @@
+package chezmoi
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/twpayne/go-vfs/v5"
+)
+
+// A dmiVirtualization maps a prefix of a DMI field to the name of a
+// hypervisor, following systemd-detect-virt.
+type dmiVirtualization struct {
+	prefix         string
+	virtualization string
+}
+
+var (
+	dmiFilenames = []string{
+		"/sys/class/dmi/id/product_name",
+		"/sys/class/dmi/id/sys_vendor",
+		"/sys/class/dmi/id/board_vendor",
+		"/sys/class/dmi/id/bios_vendor",
+		"/sys/class/dmi/id/product_version",
+	}
+
+	dmiVirtualizations = []dmiVirtualization{
+		{prefix: "KVM", virtualization: "kvm"},
+		{prefix: "OpenStack", virtualization: "kvm"},
+		{prefix: "KubeVirt", virtualization: "kvm"},
+		{prefix: "Amazon EC2", virtualization: "amazon"},
+		{prefix: "QEMU", virtualization: "qemu"},
+		{prefix: "VMware", virtualization: "vmware"},
+		{prefix: "VMW", virtualization: "vmware"},
+		{prefix: "innotek GmbH", virtualization: "oracle"},
+		{prefix: "VirtualBox", virtualization: "oracle"},
+		{prefix: "Xen", virtualization: "xen"},
+		{prefix: "Bochs", virtualization: "bochs"},
+		{prefix: "Parallels", virtualization: "parallels"},
+		{prefix: "BHYVE", virtualization: "bhyve"},
+		{prefix: "Hyper-V", virtualization: "microsoft"},
+		{prefix: "Apple Virtualization", virtualization: "apple"},
+		{prefix: "Google Compute Engine", virtualization: "google"},
+	}
+)
+
+// Facts returns facts about the machine, read from /proc, /sys, and the
+// environment with lookupEnv. username is used to find the user's default
+// shell. No commands are run. Facts that cannot be determined are omitted, and
+// errors reading individual facts are returned alongside the remaining facts.
+func Facts(fileSystem vfs.FS, lookupEnv func(string) (string, bool), username string) (map[string]any, error) {
+	facts := make(map[string]any)
+	var errs []error
+
+	if cpu, err := cpuFacts(fileSystem); err != nil {
+		errs = append(errs, err)
+	} else if len(cpu) != 0 {
+		facts["cpu"] = cpu
+	}
+
+	if memory, err := memoryFacts(fileSystem); err != nil {
+		errs = append(errs, err)
+	} else if len(memory) != 0 {
+		facts["memory"] = memory
+	}
+
+	if container, err := containerFact(fileSystem, lookupEnv); err != nil {
+		errs = append(errs, err)
+	} else {
+		facts["container"] = container
+	}
+
+	if virtualization, err := virtualizationFact(fileSystem); err != nil {
+		errs = append(errs, err)
+	} else {
+		facts["virtualization"] = virtualization
+	}
+
+	if wsl, err := wslFact(fileSystem, lookupEnv); err != nil {
+		errs = append(errs, err)
+	} else {
+		facts["wsl"] = wsl
+	}
+
+	if initSystem, err := initSystemFact(fileSystem); err != nil {
+		errs = append(errs, err)
+	} else {
+		facts["initSystem"] = initSystem
+	}
+
+	facts["sessionType"] = sessionTypeFact(lookupEnv)
+
+	if shell, err := shellFact(fileSystem, lookupEnv, username); err != nil {
+		errs = append(errs, err)
+	} else {
+		facts["shell"] = shell
+	}
+
+	facts["ssh"] = sshFact(lookupEnv)
+
+	return facts, errors.Join(errs...)
+}
+
+// containerFact returns the name of the container manager that the machine is
+// running in, or the empty string if it is not running in a container.
+func containerFact(fileSystem vfs.FS, lookupEnv func(string) (string, bool)) (string, error) {
+	for _, marker := range []struct {
+		filename  string
+		container string
+	}{
+		{filename: "/run/.containerenv", container: "podman"},
+		{filename: "/.dockerenv", container: "docker"},
+	} {
+		switch ok, err := factFileExists(fileSystem, marker.filename); {
+		case err != nil:
+			return "", err
+		case ok:
+			return marker.container, nil
+		}
+	}
+
+	// systemd-nspawn and other container managers that follow systemd's
+	// container interface write their name to /run/systemd/container.
+	switch data, err := readFactFile(fileSystem, "/run/systemd/container"); {
+	case err != nil:
+		return "", err
+	case len(data) != 0:
+		return string(data), nil
+	}
+
+	// Otherwise, the container manager sets $container in the environment of
+	// PID 1, which is usually only readable by root, and often in the
+	// environment of all processes.
+	switch data, err := readFactFile(fileSystem, "/proc/1/environ"); {
+	case err != nil:
+		return "", err
+	default:
+		for variable := range bytes.SplitSeq(data, []byte{0}) {
+			if value, ok := bytes.CutPrefix(variable, []byte("container=")); ok && len(value) != 0 {
+				return string(value), nil
+			}
+		}
+	}
+	if container, ok := lookupEnv("container"); ok {
+		return container, nil
+	}
+
+	return "", nil
+}
+
+// cpuFacts returns the number and model of CPUs parsed from /proc/cpuinfo.
+func cpuFacts(fileSystem vfs.FS) (map[string]any, error) {
+	data, err := readFactFile(fileSystem, "/proc/cpuinfo")
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+
+	var count int
+	var model string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		switch key {
+		case "processor":
+			count++
+		case "model name", "Model", "Hardware", "cpu model":
+			if model == "" {
+				model = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	cpu := map[string]any{
+		"count": count,
+	}
+	if model != "" {
+		cpu["model"] = model
+	}
+	return cpu, nil
+}
+
+// initSystemFact returns the name of the init system.
+func initSystemFact(fileSystem vfs.FS) (string, error) {
+	for _, marker := range []struct {
+		filename   string
+		initSystem string
+	}{
+		{filename: "/run/systemd/system", initSystem: "systemd"},
+		{filename: "/run/openrc", initSystem: "openrc"},
+	} {
+		switch ok, err := factFileExists(fileSystem, marker.filename); {
+		case err != nil:
+			return "", err
+		case ok:
+			return marker.initSystem, nil
+		}
+	}
+	data, err := readFactFile(fileSystem, "/proc/1/comm")
+	return string(data), err
+}
+
+// memoryFacts returns the total memory in bytes parsed from /proc/meminfo.
+func memoryFacts(fileSystem vfs.FS) (map[string]any, error) {
+	data, err := readFactFile(fileSystem, "/proc/meminfo")
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "MemTotal:")
+		if !ok {
+			continue
+		}
+		kibibytesStr, ok := strings.CutSuffix(strings.TrimSpace(value), " kB")
+		if !ok {
+			continue
+		}
+		kibibytes, err := strconv.ParseInt(kibibytesStr, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"total": 1024 * kibibytes,
+		}, nil
+	}
+	return nil, scanner.Err()
+}
+
+// sessionTypeFact returns the type of the desktop session, for example
+// wayland or x11.
+func sessionTypeFact(lookupEnv func(string) (string, bool)) string {
+	if sessionType, ok := lookupEnv("XDG_SESSION_TYPE"); ok && sessionType != "" {
+		return sessionType
+	}
+	if waylandDisplay, ok := lookupEnv("WAYLAND_DISPLAY"); ok && waylandDisplay != "" {
+		return "wayland"
+	}
+	if display, ok := lookupEnv("DISPLAY"); ok && display != "" {
+		return "x11"
+	}
+	return ""
+}
+
+// shellFact returns the user's default shell, from $SHELL or from
+// /etc/passwd.
+func shellFact(fileSystem vfs.FS, lookupEnv func(string) (string, bool), username string) (string, error) {
+	if shell, ok := lookupEnv("SHELL"); ok && shell != "" {
+		return shell, nil
+	}
+	if username == "" {
+		return "", nil
+	}
+	data, err := readFactFile(fileSystem, "/etc/passwd")
+	if err != nil {
+		return "", err
+	}
+	for line := range bytes.Lines(data) {
+		fields := strings.Split(strings.TrimSpace(string(line)), ":")
+		if len(fields) == 7 && fields[0] == username {
+			return fields[6], nil
+		}
+	}
+	return "", nil
+}
+
+// sshFact returns whether the current process is running in an SSH session.
+func sshFact(lookupEnv func(string) (string, bool)) bool {
+	for _, key := range []string{
+		"SSH_CLIENT",
+		"SSH_CONNECTION",
+		"SSH_TTY",
+	} {
+		if value, ok := lookupEnv(key); ok && value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// virtualizationFact returns the name of the hypervisor that the machine is
+// running on, or the empty string if it is not running on a hypervisor.
+func virtualizationFact(fileSystem vfs.FS) (string, error) {
+	dmiFields := make([]string, 0, len(dmiFilenames))
+	for _, filename := range dmiFilenames {
+		data, err := readFactFile(fileSystem, filename)
+		if err != nil {
+			return "", err
+		}
+		dmiFields = append(dmiFields, string(data))
+	}
+	for _, dmiField := range dmiFields {
+		for _, dmiVirtualization := range dmiVirtualizations {
+			if strings.HasPrefix(dmiField, dmiVirtualization.prefix) {
+				return dmiVirtualization.virtualization, nil
+			}
+		}
+	}
+
+	// Microsoft Corporation is also the vendor of physical machines, so only
+	// detect Hyper-V virtual machines by their product name.
+	if dmiFields[0] == "Virtual Machine" && dmiFields[1] == "Microsoft Corporation" {
+		return "microsoft", nil
+	}
+
+	return "", nil
+}
+
+// wslFact returns whether the machine is running in Windows Subsystem for
+// Linux.
+func wslFact(fileSystem vfs.FS, lookupEnv func(string) (string, bool)) (bool, error) {
+	if _, ok := lookupEnv("WSL_DISTRO_NAME"); ok {
+		return true, nil
+	}
+	osRelease, err := readFactFile(fileSystem, "/proc/sys/kernel/osrelease")
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(bytes.ToLower(osRelease), []byte("microsoft")), nil
+}
+
+// factFileExists returns whether name exists. Permission errors are treated as
+// if name does not exist.
+func factFileExists(fileSystem vfs.FS, name string) (bool, error) {
+	switch _, err := fileSystem.Lstat(name); {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// readFactFile returns the contents of name with leading and trailing
+// whitespace removed. If name does not exist or cannot be read because of its
+// permissions then it returns nil.
+func readFactFile(fileSystem vfs.FS, name string) ([]byte, error) {
+	switch data, err := fileSystem.ReadFile(name); {
+	case err == nil:
+		return bytes.TrimSpace(data), nil
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
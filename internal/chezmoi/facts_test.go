@@ -0,0 +1,226 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-vfs/v5"
+	"github.com/twpayne/go-vfs/v5/vfst"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestFacts(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		root          any
+		env           map[string]string
+		username      string
+		expectedFacts map[string]any
+		expectedErr   bool
+	}{
+		{
+			name: "empty",
+			root: map[string]any{
+				"/proc": &vfst.Dir{Perm: 0o755},
+			},
+			expectedFacts: map[string]any{
+				"container":      "",
+				"initSystem":     "",
+				"sessionType":    "",
+				"shell":          "",
+				"ssh":            false,
+				"virtualization": "",
+				"wsl":            false,
+			},
+		},
+		{
+			name: "desktop",
+			root: map[string]any{
+				"/etc/passwd": "root:x:0:0:root:/root:/bin/bash\n" +
+					"user:x:1000:1000::/home/user:/usr/bin/zsh\n",
+				"/proc": map[string]any{
+					"1/comm": "systemd\n",
+					"cpuinfo": "processor\t: 0\n" +
+						"model name\t: AMD Ryzen 7 7840U\n" +
+						"\n" +
+						"processor\t: 1\n" +
+						"model name\t: AMD Ryzen 7 7840U\n",
+					"meminfo": "MemTotal:       32768000 kB\n" +
+						"MemFree:         1024000 kB\n",
+					"sys/kernel/osrelease": "6.8.0-generic\n",
+				},
+				"/run/systemd/system": &vfst.Dir{Perm: 0o755},
+				"/sys/class/dmi/id": map[string]any{
+					"product_name": "Framework Laptop 13\n",
+					"sys_vendor":   "Framework\n",
+				},
+			},
+			env: map[string]string{
+				"XDG_SESSION_TYPE": "wayland",
+			},
+			username: "user",
+			expectedFacts: map[string]any{
+				"container": "",
+				"cpu": map[string]any{
+					"count": 2,
+					"model": "AMD Ryzen 7 7840U",
+				},
+				"initSystem": "systemd",
+				"memory": map[string]any{
+					"total": int64(32768000 * 1024),
+				},
+				"sessionType":    "wayland",
+				"shell":          "/usr/bin/zsh",
+				"ssh":            false,
+				"virtualization": "",
+				"wsl":            false,
+			},
+		},
+		{
+			name: "docker",
+			root: map[string]any{
+				"/.dockerenv":  "",
+				"/proc/1/comm": "sh\n",
+			},
+			env: map[string]string{
+				"SHELL": "/bin/sh",
+			},
+			expectedFacts: map[string]any{
+				"container":      "docker",
+				"initSystem":     "sh",
+				"sessionType":    "",
+				"shell":          "/bin/sh",
+				"ssh":            false,
+				"virtualization": "",
+				"wsl":            false,
+			},
+		},
+		{
+			name: "systemd_nspawn",
+			root: map[string]any{
+				"/run/systemd": map[string]any{
+					"container": "systemd-nspawn\n",
+					"system":    &vfst.Dir{Perm: 0o755},
+				},
+			},
+			expectedFacts: map[string]any{
+				"container":      "systemd-nspawn",
+				"initSystem":     "systemd",
+				"sessionType":    "",
+				"shell":          "",
+				"ssh":            false,
+				"virtualization": "",
+				"wsl":            false,
+			},
+		},
+		{
+			name: "podman_pid_1_environ",
+			root: map[string]any{
+				"/proc/1/environ": "PATH=/usr/bin\x00container=podman\x00",
+			},
+			expectedFacts: map[string]any{
+				"container":      "podman",
+				"initSystem":     "",
+				"sessionType":    "",
+				"shell":          "",
+				"ssh":            false,
+				"virtualization": "",
+				"wsl":            false,
+			},
+		},
+		{
+			name: "virtual_machine_over_ssh",
+			root: map[string]any{
+				"/sys/class/dmi/id": map[string]any{
+					"product_name": "Standard PC (Q35 + ICH9, 2009)\n",
+					"sys_vendor":   "QEMU\n",
+				},
+			},
+			env: map[string]string{
+				"DISPLAY":        ":0",
+				"SSH_CONNECTION": "192.168.0.1 52000 192.168.0.2 22",
+			},
+			expectedFacts: map[string]any{
+				"container":      "",
+				"initSystem":     "",
+				"sessionType":    "x11",
+				"shell":          "",
+				"ssh":            true,
+				"virtualization": "qemu",
+				"wsl":            false,
+			},
+		},
+		{
+			name: "hyper_v",
+			root: map[string]any{
+				"/sys/class/dmi/id": map[string]any{
+					"product_name": "Virtual Machine\n",
+					"sys_vendor":   "Microsoft Corporation\n",
+				},
+			},
+			expectedFacts: map[string]any{
+				"container":      "",
+				"initSystem":     "",
+				"sessionType":    "",
+				"shell":          "",
+				"ssh":            false,
+				"virtualization": "microsoft",
+				"wsl":            false,
+			},
+		},
+		{
+			name: "wsl",
+			root: map[string]any{
+				"/proc/sys/kernel/osrelease": "5.15.153.1-microsoft-standard-WSL2\n",
+			},
+			expectedFacts: map[string]any{
+				"container":      "",
+				"initSystem":     "",
+				"sessionType":    "",
+				"shell":          "",
+				"ssh":            false,
+				"virtualization": "",
+				"wsl":            true,
+			},
+		},
+		{
+			name: "unreadable_fact",
+			root: map[string]any{
+				"/proc": map[string]any{
+					"cpuinfo": &vfst.Dir{Perm: 0o755},
+					"meminfo": "MemTotal:       32768000 kB\n",
+				},
+			},
+			expectedFacts: map[string]any{
+				"container":  "",
+				"initSystem": "",
+				"memory": map[string]any{
+					"total": int64(32768000 * 1024),
+				},
+				"sessionType":    "",
+				"shell":          "",
+				"ssh":            false,
+				"virtualization": "",
+				"wsl":            false,
+			},
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lookupEnv := func(key string) (string, bool) {
+				value, ok := tc.env[key]
+				return value, ok
+			}
+			chezmoitest.WithTestFS(t, tc.root, func(fileSystem vfs.FS) {
+				actual, err := Facts(fileSystem, lookupEnv, tc.username)
+				if tc.expectedErr {
+					assert.Error(t, err)
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Equal(t, tc.expectedFacts, actual)
+			})
+		})
+	}
+}
@@ -0,0 +1,962 @@
+package chezmoi
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoiset"
+)
+
+// A LintProblemType is the type of a problem found by SourceState.Lint.
+type LintProblemType string
+
+// Lint problem types.
+const (
+	LintProblemTypeCollision              LintProblemType = "collision"
+	LintProblemTypeInvalid                LintProblemType = "invalid"
+	LintProblemTypeMissingDataKey         LintProblemType = "missing-data-key"
+	LintProblemTypeRedundantAttribute     LintProblemType = "redundant-attribute"
+	LintProblemTypeTemplateError          LintProblemType = "template-error"
+	LintProblemTypeUndefinedFunction      LintProblemType = "undefined-function"
+	LintProblemTypeUnmatchedIgnorePattern LintProblemType = "unmatched-ignore-pattern"
+	LintProblemTypeUnusedDataKey          LintProblemType = "unused-data-key"
+)
+
+// A LintProblem is a problem found by SourceState.Lint.
+type LintProblem struct {
+	Type    LintProblemType `json:"type"           yaml:"type"`
+	Path    string          `json:"path,omitempty" yaml:"path,omitempty"`
+	Line    int             `json:"line,omitempty" yaml:"line,omitempty"`
+	Message string          `json:"message"        yaml:"message"`
+}
+
+// maxLintTemplateDepth is the maximum depth of nested template invocations
+// that are followed when linting.
+const maxLintTemplateDepth = 16
+
+var (
+	templateErrorLineRx = regexp.MustCompile(`\A(\d+)(?::\d+)?: `)
+	undefinedFunctionRx = regexp.MustCompile(`\Afunction "(.*)" not defined\z`)
+)
+
+// A lintDataRef is a reference to template data.
+type lintDataRef struct {
+	keys  []string
+	whole bool
+	path  string
+	line  int
+}
+
+// A lintIgnorePattern is a pattern in a .chezmoiignore file.
+type lintIgnorePattern struct {
+	pattern string
+	path    string
+	line    int
+}
+
+// A lintScope is the scope in which a template node is evaluated. A nil dot,
+// dollar, or variable value means that the referenced data is unknown.
+type lintScope struct {
+	path     string
+	template *template.Template
+	tree     *parse.Tree
+	dot      []string
+	dollar   []string
+	vars     map[string][]string
+	visiting []string
+}
+
+// A linter accumulates lint problems in a source state.
+type linter struct {
+	s                 *SourceState
+	templateData      map[string]any
+	templates         map[string]*Template
+	templatePaths     map[string]string
+	refs              []lintDataRef
+	funcs             chezmoiset.Set[string]
+	includedTemplates chezmoiset.Set[string]
+	targets           map[RelPath][]SourceRelPath
+	externalTargets   []RelPath
+	ignorePatterns    []lintIgnorePattern
+	problems          []*LintProblem
+}
+
+func (p *LintProblem) String() string {
+	switch {
+	case p.Path == "":
+		return p.Message
+	case p.Line == 0:
+		return p.Path + ": " + p.Message
+	default:
+		return p.Path + ":" + strconv.Itoa(p.Line) + ": " + p.Message
+	}
+}
+
+// Lint checks the templates and special files in the source directory for
+// problems. s should be read with WithTemplateDataOnly(true) and
+// WithReadTemplates(false) so that only its template data is read and so that
+// errors in templates are reported as problems rather than errors.
+func (s *SourceState) Lint() ([]*LintProblem, error) {
+	templateData := s.TemplateData()
+	if chezmoiTemplateData, ok := templateData["chezmoi"].(map[string]any); ok {
+		chezmoiTemplateData["localData"] = map[string]any{}
+		chezmoiTemplateData["sourceFile"] = ""
+		chezmoiTemplateData["stdin"] = ""
+		chezmoiTemplateData["targetFile"] = ""
+	}
+	l := &linter{
+		s:                 s,
+		templateData:      templateData,
+		templates:         make(map[string]*Template),
+		templatePaths:     make(map[string]string),
+		funcs:             chezmoiset.New[string](),
+		includedTemplates: chezmoiset.New[string](),
+		targets:           make(map[RelPath][]SourceRelPath),
+	}
+
+	// Report template data schema violations once as problems, rather than as
+	// an error from every template.
+	if s.templateDataSchema != nil {
+		violations, err := s.templateDataSchema.violations(s.TemplateData())
+		if err != nil {
+			return nil, err
+		}
+		for _, violation := range violations {
+			l.addProblem(LintProblemTypeInvalid, DataSchemaName, 0, violation)
+		}
+		s.validateTemplateDataFunc = func() error { return nil }
+	}
+
+	switch fileInfo, err := s.system.Stat(s.sourceDirAbsPath); {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case !fileInfo.IsDir():
+		return nil, fmt.Errorf("%s: not a directory", s.sourceDirAbsPath)
+	}
+	if err := WalkSourceDir(s.system, s.sourceDirAbsPath, l.walkFunc); err != nil {
+		return nil, err
+	}
+
+	l.lintCollisions()
+	l.lintIgnorePatterns()
+	l.lintMissingDataKeys()
+	l.lintUnusedDataKeys()
+
+	slices.SortFunc(l.problems, func(a, b *LintProblem) int {
+		return cmp.Or(
+			cmp.Compare(a.Path, b.Path),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Type, b.Type),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+	return slices.CompactFunc(l.problems, func(a, b *LintProblem) bool {
+		return *a == *b
+	}), nil
+}
+
+// walkFunc lints a single entry in the source directory.
+func (l *linter) walkFunc(sourceAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
+	if sourceAbsPath == l.s.sourceDirAbsPath {
+		return nil
+	}
+
+	// Follow symlinks in the source directory.
+	if fileInfo.Mode().Type() == fs.ModeSymlink {
+		if strings.HasPrefix(fileInfo.Name(), ignorePrefix) && !strings.HasPrefix(fileInfo.Name(), Prefix) {
+			return nil
+		}
+		fileInfo, err = l.s.system.Stat(sourceAbsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	sourceRelPath := SourceRelPath{
+		relPath: sourceAbsPath.MustTrimDirPrefix(l.s.sourceDirAbsPath),
+		isDir:   fileInfo.IsDir(),
+	}
+	parentSourceRelPath, sourceName := sourceRelPath.Split()
+
+	switch name := fileInfo.Name(); {
+	case name == dataName:
+		return fs.SkipDir
+	case isPrefixDotFormat(name, dataName):
+		return nil
+	case name == TemplatesDirName:
+		return l.lintTemplatesDir(sourceAbsPath)
+	case isPrefixDotFormat(name, externalName) || isPrefixDotFormatDotTmpl(name, externalName):
+		parentAbsPath, _ := sourceAbsPath.Split()
+		l.lintExternal(sourceAbsPath, parentAbsPath)
+		return nil
+	case name == externalsDirName:
+		return l.lintExternalsDir(sourceAbsPath)
+	case name == ignoreName || name == ignoreName+TemplateSuffix:
+		l.lintIgnore(sourceAbsPath, parentSourceRelPath)
+		return nil
+	case name == removeName || name == removeName+TemplateSuffix,
+		name == reloadName || name == reloadName+TemplateSuffix:
+		l.executeTemplate(sourceAbsPath)
+		return nil
+	case name == scriptsDirName:
+		return l.lintScriptsDir(sourceAbsPath)
+	case strings.HasPrefix(name, Prefix) || strings.HasPrefix(name, ignorePrefix):
+		if fileInfo.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	case fileInfo.IsDir():
+		da, err := parseDirAttr(sourceName.String())
+		if err != nil {
+			l.addProblem(LintProblemTypeInvalid, sourceRelPath.String(), 0, err.Error())
+			return fs.SkipDir
+		}
+		targetRelPath, err := parentSourceRelPath.Dir().TargetRelPath(l.s.encryption.EncryptedSuffix())
+		if err != nil {
+			return err
+		}
+		l.addTarget(targetRelPath.JoinString(da.TargetName), sourceRelPath)
+		l.lintDirAttr(sourceRelPath, da)
+		if da.External {
+			return fs.SkipDir
+		}
+		return nil
+	case fileInfo.Mode().IsRegular():
+		fa, err := parseFileAttr(sourceName.String(), l.s.encryption.EncryptedSuffix())
+		if err != nil {
+			l.addProblem(LintProblemTypeInvalid, sourceRelPath.String(), 0, err.Error())
+			return nil
+		}
+		targetRelPath, err := parentSourceRelPath.Dir().TargetRelPath(l.s.encryption.EncryptedSuffix())
+		if err != nil {
+			return err
+		}
+		l.addTarget(targetRelPath.JoinString(fa.TargetName), sourceRelPath)
+		return l.lintFile(sourceAbsPath, sourceRelPath, fileInfo, fa)
+	default:
+		return &UnsupportedFileTypeError{
+			absPath: sourceAbsPath,
+			mode:    fileInfo.Mode(),
+		}
+	}
+}
+
+// addProblem adds a problem to l.
+func (l *linter) addProblem(problemType LintProblemType, path string, line int, message string) {
+	l.problems = append(l.problems, &LintProblem{
+		Type:    problemType,
+		Path:    path,
+		Line:    line,
+		Message: message,
+	})
+}
+
+// addTarget records that sourceRelPath has the target targetRelPath.
+func (l *linter) addTarget(targetRelPath RelPath, sourceRelPath SourceRelPath) {
+	l.targets[targetRelPath] = append(l.targets[targetRelPath], sourceRelPath)
+}
+
+// addTemplateErrorProblem adds a problem for err, which was returned by parsing
+// or executing the template name.
+func (l *linter) addTemplateErrorProblem(path, name string, err error) {
+	line, message := parseTemplateError(name, err)
+	l.addProblem(LintProblemTypeTemplateError, path, line, message)
+}
+
+// executeTemplate lints and executes the template at sourceAbsPath and returns
+// the result. If the template could not be executed then it returns false.
+func (l *linter) executeTemplate(sourceAbsPath AbsPath) ([]byte, bool) {
+	sourceRelPath := sourceAbsPath.MustTrimDirPrefix(l.s.sourceDirAbsPath)
+	data, err := l.s.system.ReadFile(sourceAbsPath)
+	if err != nil {
+		l.addProblem(LintProblemTypeInvalid, sourceRelPath.String(), 0, err.Error())
+		return nil, false
+	}
+	if _, ok := l.lintTemplate(sourceRelPath.String(), sourceRelPath.String(), data); !ok {
+		return nil, false
+	}
+	result, err := l.s.ExecuteTemplateData(ExecuteTemplateDataOptions{
+		NameRelPath: sourceRelPath,
+		Data:        data,
+	})
+	if err != nil {
+		l.addTemplateErrorProblem(sourceRelPath.String(), sourceRelPath.String(), err)
+		return nil, false
+	}
+	return result, true
+}
+
+// lintCollisions reports all targets that have more than one source.
+func (l *linter) lintCollisions() {
+	for _, sourceRelPaths := range l.targets {
+		if len(sourceRelPaths) < 2 {
+			continue
+		}
+		sourceRelPathStrs := make([]string, 0, len(sourceRelPaths))
+		for _, sourceRelPath := range sourceRelPaths {
+			sourceRelPathStrs = append(sourceRelPathStrs, sourceRelPath.String())
+		}
+		slices.Sort(sourceRelPathStrs)
+		for i, sourceRelPathStr := range sourceRelPathStrs {
+			others := slices.Concat(sourceRelPathStrs[:i], sourceRelPathStrs[i+1:])
+			l.addProblem(LintProblemTypeCollision, sourceRelPathStr, 0, "same target as "+strings.Join(others, ", "))
+		}
+	}
+}
+
+// lintDirAttr reports redundant attributes in a source directory name.
+func (l *linter) lintDirAttr(sourceRelPath SourceRelPath, da DirAttr) {
+	_, sourceName := sourceRelPath.Split()
+	switch {
+	case da.Remove && (da.Exact || da.Private || da.ReadOnly):
+		canonicalDirAttr := DirAttr{
+			TargetName: da.TargetName,
+			External:   da.External,
+			Remove:     true,
+		}
+		l.addProblem(LintProblemTypeRedundantAttribute, sourceRelPath.String(), 0,
+			"attributes have no effect on removed directories, use "+canonicalDirAttr.SourceName())
+	case len(da.SourceName()) < len(sourceName.String()):
+		l.addProblem(LintProblemTypeRedundantAttribute, sourceRelPath.String(), 0, "redundant attributes, use "+da.SourceName())
+	}
+}
+
+// lintExternal lints the external file at sourceAbsPath.
+func (l *linter) lintExternal(sourceAbsPath, parentAbsPath AbsPath) {
+	sourceRelPathStr := sourceAbsPath.MustTrimDirPrefix(l.s.sourceDirAbsPath).String()
+	data, ok := l.executeTemplate(sourceAbsPath)
+	if !ok {
+		return
+	}
+	format, err := FormatFromAbsPath(sourceAbsPath.TrimSuffix(TemplateSuffix))
+	if err != nil {
+		l.addProblem(LintProblemTypeInvalid, sourceRelPathStr, 0, err.Error())
+		return
+	}
+	externals := make(map[string]External)
+	if err := format.Unmarshal(data, &externals); err != nil {
+		l.addProblem(LintProblemTypeInvalid, sourceRelPathStr, 0, err.Error())
+		return
+	}
+	parentRelPath := parentAbsPath.MustTrimDirPrefix(l.s.sourceDirAbsPath)
+	parentTargetRelPath, err := NewSourceRelDirPath(parentRelPath.String()).TargetRelPath(l.s.encryption.EncryptedSuffix())
+	if err != nil {
+		l.addProblem(LintProblemTypeInvalid, sourceRelPathStr, 0, err.Error())
+		return
+	}
+	for targetPath, external := range externals {
+		if external.TargetPath != "" {
+			targetPath = external.TargetPath
+		}
+		l.externalTargets = append(l.externalTargets, parentTargetRelPath.JoinString(path.Clean(targetPath)))
+	}
+}
+
+// lintExternalsDir lints all externals in the directory externalsDirAbsPath.
+func (l *linter) lintExternalsDir(externalsDirAbsPath AbsPath) error {
+	if err := WalkSourceDir(l.s.system, externalsDirAbsPath, func(externalAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case externalAbsPath == externalsDirAbsPath:
+			return nil
+		case strings.HasPrefix(fileInfo.Name(), ignorePrefix):
+			if fileInfo.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		case fileInfo.IsDir():
+			return nil
+		default:
+			parentAbsPath, _ := externalAbsPath.Split()
+			l.lintExternal(externalAbsPath, parentAbsPath.Dir())
+			return nil
+		}
+	}); err != nil {
+		return err
+	}
+	return fs.SkipDir
+}
+
+// lintFile lints the source file at sourceAbsPath.
+func (l *linter) lintFile(sourceAbsPath AbsPath, sourceRelPath SourceRelPath, fileInfo fs.FileInfo, fa FileAttr) error {
+	_, sourceName := sourceRelPath.Split()
+	encryptedSuffix := l.s.encryption.EncryptedSuffix()
+	switch {
+	case fa.Empty && !fa.Encrypted && !fa.Template && fileInfo.Size() > 0:
+		canonicalFileAttr := fa
+		canonicalFileAttr.Empty = false
+		l.addProblem(LintProblemTypeRedundantAttribute, sourceRelPath.String(), 0,
+			"file is not empty, use "+canonicalFileAttr.SourceName(encryptedSuffix))
+	case len(fa.SourceName(encryptedSuffix)) < len(sourceName.String()):
+		l.addProblem(LintProblemTypeRedundantAttribute, sourceRelPath.String(), 0,
+			"redundant attributes, use "+fa.SourceName(encryptedSuffix))
+	}
+
+	// Encrypted files are not linted as decrypting them may require user
+	// interaction.
+	if fa.Encrypted {
+		return nil
+	}
+
+	var contents []byte
+	switch {
+	case fa.Template:
+		var err error
+		if contents, err = l.s.system.ReadFile(sourceAbsPath); err != nil {
+			return err
+		}
+	case fa.Type == SourceFileTypeModify:
+		data, err := l.s.system.ReadFile(sourceAbsPath)
+		if err != nil {
+			return err
+		}
+		if !modifyTemplateRx.Match(data) {
+			return nil
+		}
+		contents = modifyTemplateRx.ReplaceAll(data, nil)
+	default:
+		return nil
+	}
+	l.lintTemplate(sourceRelPath.String(), sourceRelPath.String(), contents)
+	return nil
+}
+
+// lintIgnore lints the .chezmoiignore file at sourceAbsPath and records its
+// patterns.
+func (l *linter) lintIgnore(sourceAbsPath AbsPath, parentSourceRelPath SourceRelPath) {
+	sourceRelPathStr := sourceAbsPath.MustTrimDirPrefix(l.s.sourceDirAbsPath).String()
+	data, ok := l.executeTemplate(sourceAbsPath)
+	if !ok {
+		return
+	}
+	dir, err := parentSourceRelPath.Dir().TargetRelPath("")
+	if err != nil {
+		l.addProblem(LintProblemTypeInvalid, sourceRelPathStr, 0, err.Error())
+		return
+	}
+	lineNumber := 0
+	for line := range bytes.Lines(data) {
+		lineNumber++
+		line = commentRx.ReplaceAll(line, nil)
+		line = bytes.TrimSpace(line)
+		line, _ = bytes.CutPrefix(line, []byte{'!'})
+		if len(line) == 0 {
+			continue
+		}
+		pattern := dir.JoinString(string(line)).String()
+		if !doublestar.ValidatePattern(pattern) {
+			l.addProblem(LintProblemTypeInvalid, sourceRelPathStr, lineNumber, pattern+": invalid pattern")
+			continue
+		}
+		l.ignorePatterns = append(l.ignorePatterns, lintIgnorePattern{
+			pattern: pattern,
+			path:    sourceRelPathStr,
+			line:    lineNumber,
+		})
+	}
+}
+
+// lintIgnorePatterns reports all ignore patterns that do not match any target
+// in the source state.
+func (l *linter) lintIgnorePatterns() {
+FOR:
+	for _, ignorePattern := range l.ignorePatterns {
+		for targetRelPath := range l.targets {
+			if ok, _ := doublestar.Match(ignorePattern.pattern, targetRelPath.String()); ok {
+				continue FOR
+			}
+		}
+		for _, externalTargetRelPath := range l.externalTargets {
+			if ok, _ := doublestar.Match(ignorePattern.pattern, externalTargetRelPath.String()); ok {
+				continue FOR
+			}
+			// Entries inside externals are not known without reading the
+			// external, so assume that patterns inside externals match.
+			if strings.HasPrefix(ignorePattern.pattern, externalTargetRelPath.String()+"/") {
+				continue FOR
+			}
+		}
+		l.addProblem(LintProblemTypeUnmatchedIgnorePattern, ignorePattern.path, ignorePattern.line,
+			ignorePattern.pattern+": pattern does not match any target")
+	}
+}
+
+// lintMissingDataKeys reports all references to template data that does not
+// exist.
+func (l *linter) lintMissingDataKeys() {
+	for _, ref := range l.refs {
+		value := any(l.templateData)
+		if localTemplateData := l.s.LocalTemplateData(NewRelPath(ref.path).Dir()); len(localTemplateData) != 0 {
+			templateData := copyTemplateData(l.templateData)
+			RecursiveMerge(templateData, copyTemplateData(localTemplateData))
+			if chezmoiTemplateData, ok := templateData["chezmoi"].(map[string]any); ok {
+				chezmoiTemplateData["localData"] = localTemplateData
+			}
+			value = templateData
+		}
+		for i, key := range ref.keys {
+			m, ok := value.(map[string]any)
+			if !ok {
+				break
+			}
+			if value, ok = m[key]; !ok {
+				l.addProblem(LintProblemTypeMissingDataKey, ref.path, ref.line, formatDataKeys(ref.keys[:i+1])+": data key not found")
+				break
+			}
+		}
+	}
+}
+
+// lintScriptsDir lints all scripts in the directory scriptsDirAbsPath.
+func (l *linter) lintScriptsDir(scriptsDirAbsPath AbsPath) error {
+	if err := WalkSourceDir(l.s.system, scriptsDirAbsPath, func(sourceAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case sourceAbsPath == scriptsDirAbsPath:
+			return nil
+		case strings.HasPrefix(fileInfo.Name(), ignorePrefix):
+			if fileInfo.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		case fileInfo.Mode().Type() == fs.ModeSymlink:
+			if fileInfo, err = l.s.system.Stat(sourceAbsPath); err != nil {
+				return err
+			}
+		}
+		if fileInfo.IsDir() {
+			return nil
+		}
+		sourceRelPath := NewSourceRelPath(sourceAbsPath.MustTrimDirPrefix(l.s.sourceDirAbsPath).String())
+		parentSourceRelPath, sourceName := sourceRelPath.Split()
+		fa, err := parseFileAttr(sourceName.String(), l.s.encryption.EncryptedSuffix())
+		switch {
+		case err != nil:
+			l.addProblem(LintProblemTypeInvalid, sourceRelPath.String(), 0, err.Error())
+			return nil
+		case fa.Type != SourceFileTypeScript:
+			l.addProblem(LintProblemTypeInvalid, sourceRelPath.String(), 0, "not a script")
+			return nil
+		}
+		targetRelPath, err := parentSourceRelPath.Dir().TargetRelPath(l.s.encryption.EncryptedSuffix())
+		if err != nil {
+			return err
+		}
+		l.addTarget(targetRelPath.JoinString(fa.TargetName), sourceRelPath)
+		return l.lintFile(sourceAbsPath, sourceRelPath, fileInfo, fa)
+	}); err != nil {
+		return err
+	}
+	return fs.SkipDir
+}
+
+// lintTemplate parses and analyzes the template name in data. It returns the
+// parsed template and whether the template was parsed without problems.
+func (l *linter) lintTemplate(path, name string, data []byte) (*Template, bool) {
+	tmpl, ok := l.parseTemplate(path, name, data)
+	if tmpl != nil {
+		l.analyzeTemplate(path, tmpl)
+	}
+	return tmpl, ok
+}
+
+// lintTemplatesDir parses all templates in templatesDirAbsPath and adds them to
+// the source state so that they can be used by other templates.
+func (l *linter) lintTemplatesDir(templatesDirAbsPath AbsPath) error {
+	if err := WalkSourceDir(l.s.system, templatesDirAbsPath, func(templateAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case templateAbsPath == templatesDirAbsPath:
+			return nil
+		case strings.HasPrefix(fileInfo.Name(), ignorePrefix):
+			if fileInfo.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		case fileInfo.IsDir():
+			return nil
+		}
+		contents, err := l.s.system.ReadFile(templateAbsPath)
+		if err != nil {
+			return err
+		}
+		name := templateAbsPath.MustTrimDirPrefix(templatesDirAbsPath).String()
+		path := templateAbsPath.MustTrimDirPrefix(l.s.sourceDirAbsPath).String()
+		tmpl, ok := l.parseTemplate(path, name, contents)
+		if tmpl == nil {
+			return nil
+		}
+		l.templates[name] = tmpl
+		l.templatePaths[name] = path
+		if ok {
+			l.s.templates[name] = tmpl
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return fs.SkipDir
+}
+
+// lintUnusedDataKeys reports all user template data keys that are not
+// referenced by any template.
+func (l *linter) lintUnusedDataKeys() {
+	l.s.mutex.Lock()
+	userTemplateData := make(map[string]any)
+	RecursiveMerge(userTemplateData, l.s.userTemplateData)
+	RecursiveMerge(userTemplateData, l.s.priorityTemplateData)
+	l.s.mutex.Unlock()
+
+	var lintKeys func([]string, map[string]any)
+	lintKeys = func(parentKeys []string, m map[string]any) {
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			keys := append(slices.Clip(parentKeys), key)
+			used, usedWhole := false, false
+			for _, ref := range l.refs {
+				switch {
+				case ref.whole && isKeyPrefix(ref.keys, keys):
+					usedWhole = true
+				case isKeyPrefix(keys, ref.keys):
+					used = true
+				}
+			}
+			switch {
+			case usedWhole:
+			case !used:
+				l.addProblem(LintProblemTypeUnusedDataKey, "", 0, formatDataKeys(keys)+": unused data key")
+			default:
+				if childMap, ok := m[key].(map[string]any); ok {
+					lintKeys(keys, childMap)
+				}
+			}
+		}
+	}
+	lintKeys(nil, userTemplateData)
+}
+
+// analyzeTemplate records all references to template data in tmpl.
+func (l *linter) analyzeTemplate(path string, tmpl *Template) {
+	if tmpl.template.Tree == nil {
+		return
+	}
+	l.walkNode(&lintScope{
+		path:     path,
+		template: tmpl.template,
+		tree:     tmpl.template.Tree,
+		dot:      []string{},
+		dollar:   []string{},
+		vars:     make(map[string][]string),
+	}, tmpl.template.Tree.Root)
+}
+
+// addRef records a reference to keys by node in scope.
+func (l *linter) addRef(scope *lintScope, node parse.Node, keys []string, whole bool) {
+	if keys == nil {
+		return
+	}
+	line := 0
+	if location, _ := scope.tree.ErrorContext(node); location != "" {
+		fields := strings.Split(location, ":")
+		if len(fields) >= 3 {
+			line, _ = strconv.Atoi(fields[len(fields)-2])
+		}
+	}
+	l.refs = append(l.refs, lintDataRef{
+		keys:  slices.Clone(keys),
+		whole: whole,
+		path:  scope.path,
+		line:  line,
+	})
+}
+
+// walkArg records all references in the argument node and returns the keys
+// that it references, or nil if they are not known.
+func (l *linter) walkArg(scope *lintScope, node parse.Node, whole bool) []string {
+	var keys []string
+	switch node := node.(type) {
+	case *parse.ChainNode:
+		var baseKeys []string
+		if pipeNode, ok := node.Node.(*parse.PipeNode); ok {
+			baseKeys = l.walkPipe(scope, pipeNode, false)
+		} else {
+			baseKeys = l.walkArg(scope, node.Node, false)
+		}
+		keys = appendKeys(baseKeys, node.Field...)
+	case *parse.DotNode:
+		keys = scope.dot
+	case *parse.FieldNode:
+		keys = appendKeys(scope.dot, node.Ident...)
+	case *parse.PipeNode:
+		return l.walkPipe(scope, node, whole)
+	case *parse.VariableNode:
+		if node.Ident[0] == "$" {
+			keys = appendKeys(scope.dollar, node.Ident[1:]...)
+		} else {
+			keys = appendKeys(scope.vars[node.Ident[0]], node.Ident[1:]...)
+		}
+	default:
+		return nil
+	}
+	l.addRef(scope, node, keys, whole)
+	return keys
+}
+
+// walkCommand records all references in the command node.
+func (l *linter) walkCommand(scope *lintScope, node *parse.CommandNode) {
+	args := node.Args
+	if identifierNode, ok := args[0].(*parse.IdentifierNode); ok {
+		if _, ok := l.s.templateFuncs[identifierNode.Ident]; ok {
+			l.funcs.Add(identifierNode.Ident)
+		}
+		args = args[1:]
+		switch {
+		case identifierNode.Ident == "index" && len(args) >= 1:
+			keys := l.walkArg(scope, args[0], false)
+			args = args[1:]
+			for len(args) > 0 {
+				stringNode, ok := args[0].(*parse.StringNode)
+				if !ok {
+					break
+				}
+				keys = appendKeys(keys, stringNode.Text)
+				args = args[1:]
+			}
+			l.addRef(scope, node, keys, true)
+		case identifierNode.Ident == "includeTemplate" && len(args) >= 1:
+			if stringNode, ok := args[0].(*parse.StringNode); ok {
+				var keys []string
+				if len(args) >= 2 {
+					keys = l.walkArg(scope, args[1], false)
+				}
+				l.walkTemplate(scope, stringNode.Text, keys, false)
+				args = args[min(len(args), 2):]
+			}
+		}
+	}
+	for _, arg := range args {
+		l.walkArg(scope, arg, true)
+	}
+}
+
+// walkNode records all references in node.
+func (l *linter) walkNode(scope *lintScope, node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ActionNode:
+		l.walkPipe(scope, node.Pipe, true)
+	case *parse.IfNode:
+		l.walkPipe(scope, node.Pipe, false)
+		l.walkNode(scope.child(scope.dot), node.List)
+		l.walkNode(scope.child(scope.dot), node.ElseList)
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, node := range node.Nodes {
+			l.walkNode(scope, node)
+		}
+	case *parse.RangeNode:
+		childScope := scope.child(scope.dot)
+		l.walkPipe(childScope, node.Pipe, true)
+		childScope.dot = nil
+		for _, variableNode := range node.Pipe.Decl {
+			childScope.vars[variableNode.Ident[0]] = nil
+		}
+		l.walkNode(childScope, node.List)
+		l.walkNode(scope.child(scope.dot), node.ElseList)
+	case *parse.TemplateNode:
+		var keys []string
+		if node.Pipe != nil {
+			keys = l.walkPipe(scope, node.Pipe, false)
+		}
+		l.walkTemplate(scope, node.Name, keys, true)
+	case *parse.WithNode:
+		childScope := scope.child(scope.dot)
+		childScope.dot = l.walkPipe(childScope, node.Pipe, false)
+		l.walkNode(childScope, node.List)
+		l.walkNode(scope.child(scope.dot), node.ElseList)
+	}
+}
+
+// walkPipe records all references in the pipe node and returns the keys that
+// it references, or nil if they are not known.
+func (l *linter) walkPipe(scope *lintScope, node *parse.PipeNode, whole bool) []string {
+	if node == nil {
+		return nil
+	}
+	var keys []string
+	if len(node.Cmds) == 1 && len(node.Cmds[0].Args) == 1 {
+		keys = l.walkArg(scope, node.Cmds[0].Args[0], whole && len(node.Decl) == 0)
+	} else {
+		for _, commandNode := range node.Cmds {
+			l.walkCommand(scope, commandNode)
+		}
+	}
+	for _, variableNode := range node.Decl {
+		if len(node.Decl) == 1 {
+			scope.vars[variableNode.Ident[0]] = keys
+		} else {
+			scope.vars[variableNode.Ident[0]] = nil
+		}
+	}
+	return keys
+}
+
+// walkTemplate records all references in the template name executed with
+// the data referenced by keys.
+func (l *linter) walkTemplate(scope *lintScope, name string, keys []string, local bool) {
+	if len(scope.visiting) >= maxLintTemplateDepth || slices.Contains(scope.visiting, name) {
+		return
+	}
+	var childScope *lintScope
+	if tmpl := scope.template.Lookup(name); local && tmpl != nil && tmpl.Tree != nil && tmpl.Tree.Root != nil {
+		childScope = &lintScope{
+			path:     scope.path,
+			template: scope.template,
+			tree:     tmpl.Tree,
+		}
+	} else if tmpl, ok := l.templates[name]; ok && tmpl.template.Tree != nil {
+		l.includedTemplates.Add(name)
+		childScope = &lintScope{
+			path:     l.templatePaths[name],
+			template: tmpl.template,
+			tree:     tmpl.template.Tree,
+		}
+	} else {
+		return
+	}
+	childScope.dot = keys
+	childScope.dollar = keys
+	childScope.vars = make(map[string][]string)
+	childScope.visiting = append(slices.Clip(scope.visiting), name)
+	l.walkNode(childScope, childScope.tree.Root)
+}
+
+// parseTemplate parses the template name in data, reporting undefined
+// functions and template errors as problems at path. Undefined functions are
+// replaced with stubs so that any further problems are found. It returns the
+// parsed template, or nil if it could not be parsed, and whether the template
+// was parsed without problems.
+func (l *linter) parseTemplate(path, name string, data []byte) (*Template, bool) {
+	funcs := l.s.templateFuncs
+	ok := true
+	for {
+		tmpl, err := ParseTemplate(name, data, TemplateOptions{
+			Funcs:   funcs,
+			Options: slices.Clone(l.s.templateOptions),
+		})
+		if err == nil {
+			return tmpl, ok
+		}
+		line, message := parseTemplateError(name, err)
+		match := undefinedFunctionRx.FindStringSubmatch(message)
+		if match == nil || funcs[match[1]] != nil {
+			l.addProblem(LintProblemTypeTemplateError, path, line, message)
+			return nil, false
+		}
+		l.addProblem(LintProblemTypeUndefinedFunction, path, line, match[1]+": undefined function")
+		funcs = maps.Clone(funcs)
+		funcs[match[1]] = func(...any) any { return nil }
+		ok = false
+	}
+}
+
+// child returns a new child scope of s with dot set to dot.
+func (s *lintScope) child(dot []string) *lintScope {
+	return &lintScope{
+		path:     s.path,
+		template: s.template,
+		tree:     s.tree,
+		dot:      dot,
+		dollar:   s.dollar,
+		vars:     maps.Clone(s.vars),
+		visiting: s.visiting,
+	}
+}
+
+// appendKeys returns keys with elems appended, or nil if keys is nil.
+func appendKeys(keys []string, elems ...string) []string {
+	if keys == nil {
+		return nil
+	}
+	return append(slices.Clip(keys), elems...)
+}
+
+// staticTemplateDependencies returns the dependencies of the template called
+// name with contents found by analyzing it, including references in branches
+// that are not executed.
+func (s *SourceState) staticTemplateDependencies(name string, contents []byte) (*TemplateDependencies, error) {
+	l := &linter{
+		s:                 s,
+		templates:         s.templates,
+		templatePaths:     make(map[string]string),
+		funcs:             chezmoiset.New[string](),
+		includedTemplates: chezmoiset.New[string](),
+	}
+	tmpl, err := ParseTemplate(name, contents, TemplateOptions{
+		Funcs:   s.templateFuncs,
+		Options: slices.Clone(s.templateOptions),
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.analyzeTemplate(name, tmpl)
+
+	dataKeys := chezmoiset.New[string]()
+	for _, ref := range l.refs {
+		if len(ref.keys) > 0 {
+			dataKeys.Add(formatDataKeys(ref.keys))
+		}
+	}
+	return &TemplateDependencies{
+		DataKeys:  slices.Sorted(dataKeys.Elements()),
+		Funcs:     slices.Sorted(l.funcs.Elements()),
+		Templates: slices.Sorted(l.includedTemplates.Elements()),
+	}, nil
+}
+
+// formatDataKeys returns keys formatted as a template field.
+func formatDataKeys(keys []string) string {
+	return "." + strings.Join(keys, ".")
+}
+
+// isKeyPrefix returns if prefix is a prefix of keys.
+func isKeyPrefix(prefix, keys []string) bool {
+	return len(prefix) <= len(keys) && slices.Equal(prefix, keys[:len(prefix)])
+}
+
+// parseTemplateError returns the line number and message from err, which was
+// returned when parsing or executing the template name.
+func parseTemplateError(name string, err error) (int, string) {
+	message := err.Error()
+	rest, ok := strings.CutPrefix(message, "template: "+name+":")
+	if !ok {
+		return 0, message
+	}
+	match := templateErrorLineRx.FindStringSubmatch(rest)
+	if match == nil {
+		return 0, message
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line, rest[len(match[0]):]
+}
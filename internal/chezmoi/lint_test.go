@@ -0,0 +1,77 @@
+package chezmoi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestIsKeyPrefix(t *testing.T) {
+	for _, tc := range []struct {
+		prefix   []string
+		keys     []string
+		expected bool
+	}{
+		{
+			prefix:   []string{},
+			keys:     []string{"a"},
+			expected: true,
+		},
+		{
+			prefix:   []string{"a"},
+			keys:     []string{"a", "b"},
+			expected: true,
+		},
+		{
+			prefix:   []string{"a", "b"},
+			keys:     []string{"a", "b"},
+			expected: true,
+		},
+		{
+			prefix:   []string{"a", "b"},
+			keys:     []string{"a"},
+			expected: false,
+		},
+		{
+			prefix:   []string{"b"},
+			keys:     []string{"a", "b"},
+			expected: false,
+		},
+	} {
+		assert.Equal(t, tc.expected, isKeyPrefix(tc.prefix, tc.keys))
+	}
+}
+
+func TestParseTemplateError(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		err             error
+		expectedLine    int
+		expectedMessage string
+	}{
+		{
+			name:            "parse_error",
+			err:             errors.New(`template: dot_bashrc.tmpl:3: function "foo" not defined`),
+			expectedLine:    3,
+			expectedMessage: `function "foo" not defined`,
+		},
+		{
+			name:            "execute_error",
+			err:             errors.New(`template: dot_bashrc.tmpl:2:3: executing "dot_bashrc.tmpl" at <.foo>: map has no entry for key "foo"`),
+			expectedLine:    2,
+			expectedMessage: `executing "dot_bashrc.tmpl" at <.foo>: map has no entry for key "foo"`,
+		},
+		{
+			name:            "other_error",
+			err:             errors.New("unknown encoding"),
+			expectedMessage: "unknown encoding",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			line, message := parseTemplateError("dot_bashrc.tmpl", tc.err)
+			assert.Equal(t, tc.expectedLine, line)
+			assert.Equal(t, tc.expectedMessage, message)
+		})
+	}
+}
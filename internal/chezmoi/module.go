@@ -0,0 +1,179 @@
+package chezmoi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoiset"
+)
+
+// A Module is a directory in the root of a source directory that contains a
+// .chezmoimodule.<format> manifest. The contents of a module are read as if
+// they were in the root of the source directory, but only if the module is
+// enabled.
+type Module struct {
+	Name            string   `json:"name"         toml:"name"         yaml:"name"`
+	Description     string   `json:"description"  toml:"description"  yaml:"description"`
+	Condition       string   `json:"condition"    toml:"condition"    yaml:"condition"`
+	Dependencies    []string `json:"dependencies" toml:"dependencies" yaml:"dependencies"`
+	absPath         AbsPath
+	manifestAbsPath AbsPath
+	enabled         bool
+}
+
+// AbsPath returns the absolute path of m's directory.
+func (m *Module) AbsPath() AbsPath {
+	return m.absPath
+}
+
+// Enabled returns whether m is enabled.
+func (m *Module) Enabled() bool {
+	return m.enabled
+}
+
+// Module returns the module with the given name.
+func (s *SourceState) Module(name string) (*Module, bool) {
+	module, ok := s.modules[name]
+	return module, ok
+}
+
+// Modules returns all modules, sorted by name.
+func (s *SourceState) Modules() []*Module {
+	return slices.SortedFunc(maps.Values(s.modules), func(a, b *Module) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// addModule adds module to s. A module in a later source directory layer
+// replaces a module with the same name in an earlier layer.
+func (s *SourceState) addModule(module *Module) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if oldModule, ok := s.modules[module.Name]; ok && oldModule.absPath.Dir() == module.absPath.Dir() {
+		return fmt.Errorf("%s: duplicate module %s, also defined in %s", module.manifestAbsPath, module.Name, oldModule.manifestAbsPath)
+	}
+	s.modules[module.Name] = module
+	return nil
+}
+
+// moduleDependencies returns module and its transitive dependencies.
+func (s *SourceState) moduleDependencies(module *Module) ([]*Module, error) {
+	var modules []*Module
+	names := chezmoiset.New[string]()
+	var addModule func(*Module) error
+	addModule = func(module *Module) error {
+		if names.Contains(module.Name) {
+			return nil
+		}
+		names.Add(module.Name)
+		modules = append(modules, module)
+		for _, dependency := range module.Dependencies {
+			dependencyModule, ok := s.modules[dependency]
+			if !ok {
+				return fmt.Errorf("%s: %s: unknown dependency", module.manifestAbsPath, dependency)
+			}
+			if err := addModule(dependencyModule); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := addModule(module); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// moduleEnabled returns whether module is enabled by the configuration or by
+// its condition.
+func (s *SourceState) moduleEnabled(module *Module) (bool, error) {
+	if enabled, ok := s.moduleChoices[module.Name]; ok {
+		return enabled, nil
+	}
+	if module.Condition == "" {
+		return false, nil
+	}
+	tmpl, err := ParseTemplate(module.manifestAbsPath.String(), []byte(module.Condition), TemplateOptions{
+		Funcs:   s.templateFuncs,
+		Options: slices.Clone(s.templateOptions),
+		Tracer:  s.templateTracer,
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: condition: %w", module.manifestAbsPath, err)
+	}
+	for _, t := range s.templates {
+		if tmpl, err = tmpl.AddParseTree(t); err != nil {
+			return false, err
+		}
+	}
+	result, err := tmpl.Execute(s.TemplateData())
+	if err != nil {
+		return false, fmt.Errorf("%s: condition: %w", module.manifestAbsPath, err)
+	}
+	enabled, err := strconv.ParseBool(strings.TrimSpace(string(result)))
+	if err != nil {
+		return false, fmt.Errorf("%s: condition: %w", module.manifestAbsPath, err)
+	}
+	return enabled, nil
+}
+
+// readModule returns the module in dirAbsPath, or nil if dirAbsPath does not
+// contain a module manifest.
+func (s *SourceState) readModule(dirAbsPath AbsPath) (*Module, error) {
+	for _, format := range []string{"json", "toml", "yaml"} {
+		manifestAbsPath := dirAbsPath.JoinString(moduleName + "." + format)
+		data, err := s.system.ReadFile(manifestAbsPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		module := &Module{
+			absPath:         dirAbsPath,
+			manifestAbsPath: manifestAbsPath,
+		}
+		if err := FormatsByExtension[format].Unmarshal(data, module); err != nil {
+			return nil, fmt.Errorf("%s: %w", manifestAbsPath, err)
+		}
+		if module.Name == "" {
+			module.Name = dirAbsPath.Base()
+		}
+		return module, nil
+	}
+	return nil, nil
+}
+
+// resolveModules determines which modules are enabled. Enabling a module
+// enables its dependencies, unless any of them are explicitly disabled, in which
+// case the module is not enabled.
+func (s *SourceState) resolveModules() error {
+MODULE:
+	for _, module := range s.Modules() {
+		switch enabled, err := s.moduleEnabled(module); {
+		case err != nil:
+			return err
+		case !enabled:
+			continue
+		}
+		modules, err := s.moduleDependencies(module)
+		if err != nil {
+			return err
+		}
+		for _, dependencyModule := range modules {
+			if enabled, ok := s.moduleChoices[dependencyModule.Name]; ok && !enabled {
+				s.warnFunc("%s: not enabled because it depends on disabled module %s\n", module.Name, dependencyModule.Name)
+				continue MODULE
+			}
+		}
+		for _, dependencyModule := range modules {
+			dependencyModule.enabled = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-vfs/v5"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestSourceStateReadModules(t *testing.T) {
+	root := map[string]any{
+		"/home/user/.local/share/chezmoi": map[string]any{
+			".chezmoidata.yaml": "gui: true\n",
+			"dot_config/file":   "# root\n",
+			"gui": map[string]any{
+				".chezmoimodule.yaml": `condition: "{{ .gui }}"` + "\n",
+				"dot_config/gui":      "# gui\n",
+			},
+			"shell": map[string]any{
+				".chezmoimodule.toml": `name = "sh"` + "\n",
+				"dot_shrc":            "# shell\n",
+			},
+			"work": map[string]any{
+				".chezmoimodule.json": `{"dependencies":["sh"]}`,
+				".chezmoidata.yaml":   "email: me@work.com\n",
+				"dot_work":            "# work\n",
+			},
+		},
+	}
+	for _, tc := range []struct {
+		name                   string
+		moduleChoices          map[string]bool
+		expectedEnabled        []string
+		expectedTargetRelPaths []RelPath
+		expectedTemplateData   map[string]any
+	}{
+		{
+			name:            "conditions",
+			expectedEnabled: []string{"gui"},
+			expectedTargetRelPaths: []RelPath{
+				NewRelPath(".config"),
+				NewRelPath(".config/file"),
+				NewRelPath(".config/gui"),
+			},
+			expectedTemplateData: map[string]any{
+				"gui": true,
+			},
+		},
+		{
+			name: "dependencies",
+			moduleChoices: map[string]bool{
+				"gui":  false,
+				"work": true,
+			},
+			expectedEnabled: []string{"sh", "work"},
+			expectedTargetRelPaths: []RelPath{
+				NewRelPath(".config"),
+				NewRelPath(".config/file"),
+				NewRelPath(".shrc"),
+				NewRelPath(".work"),
+			},
+			expectedTemplateData: map[string]any{
+				"email": "me@work.com",
+				"gui":   true,
+			},
+		},
+		{
+			name: "disabled_dependency",
+			moduleChoices: map[string]bool{
+				"sh":   false,
+				"work": true,
+			},
+			expectedEnabled: []string{"gui"},
+			expectedTargetRelPaths: []RelPath{
+				NewRelPath(".config"),
+				NewRelPath(".config/file"),
+				NewRelPath(".config/gui"),
+			},
+			expectedTemplateData: map[string]any{
+				"gui": true,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chezmoitest.WithTestFS(t, root, func(fileSystem vfs.FS) {
+				system := NewRealSystem(fileSystem)
+				s := NewSourceState(
+					WithBaseSystem(system),
+					WithDestDir(NewAbsPath("/home/user")),
+					WithModules(tc.moduleChoices),
+					WithSourceDir(NewAbsPath("/home/user/.local/share/chezmoi")),
+					WithSystem(system),
+					WithWarnFunc(func(string, ...any) {}),
+				)
+				assert.NoError(t, s.Read(t.Context(), nil))
+
+				var actualEnabled []string
+				for _, module := range s.Modules() {
+					if module.Enabled() {
+						actualEnabled = append(actualEnabled, module.Name)
+					}
+				}
+				assert.Equal(t, tc.expectedEnabled, actualEnabled)
+				assert.Equal(t, tc.expectedTargetRelPaths, s.TargetRelPaths())
+				assert.Equal(t, tc.expectedTemplateData, s.userTemplateData)
+			})
+		})
+	}
+}
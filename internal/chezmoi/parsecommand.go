@@ -0,0 +1,43 @@
+package chezmoi
+
+import (
+	"os"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// ParseCommand returns the command and arguments to run command with args. If
+// command is not an executable and contains whitespace then it is parsed as a
+// shell command.
+func ParseCommand(command string, args []string) (string, []string, error) {
+	// If command is found, then return it.
+	if commandPath, err := LookPath(command); err == nil {
+		return commandPath, args, nil
+	}
+
+	// Otherwise, if the command contains spaces, parse it as a shell command.
+	if whitespaceRx.MatchString(command) {
+		var words []*syntax.Word
+		for word, err := range syntax.NewParser().WordsSeq(strings.NewReader(command)) {
+			if err != nil {
+				return "", nil, err
+			}
+			words = append(words, word)
+		}
+		switch fields, err := expand.Fields(&expand.Config{
+			Env: expand.FuncEnviron(os.Getenv),
+		}, words...); {
+		case err != nil:
+			return "", nil, err
+		case len(fields) > 1:
+			return fields[0], append(fields[1:], args...), nil
+		case len(fields) == 1:
+			return fields[0], args, nil
+		}
+	}
+
+	// Fallback to the command only.
+	return command, args, nil
+}
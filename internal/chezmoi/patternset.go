@@ -95,6 +95,24 @@ func (ps *PatternSet) Glob(fileSystem vfs.FS, prefix string) ([]string, error) {
 	return sortedMatches, nil
 }
 
+// matchingPatterns returns the sorted include and exclude patterns in ps that
+// match name.
+func (ps *PatternSet) matchingPatterns(name string) (includePatterns, excludePatterns []string) {
+	for pattern := range ps.IncludePatterns {
+		if ok, _ := doublestar.Match(pattern, name); ok {
+			includePatterns = append(includePatterns, pattern)
+		}
+	}
+	for pattern := range ps.ExcludePatterns {
+		if ok, _ := doublestar.Match(pattern, name); ok {
+			excludePatterns = append(excludePatterns, pattern)
+		}
+	}
+	slices.Sort(includePatterns)
+	slices.Sort(excludePatterns)
+	return includePatterns, excludePatterns
+}
+
 // Match returns if name matches ps.
 func (ps *PatternSet) Match(name string) PatternSetMatchType {
 	// If name is explicitly excluded, then return exclude.
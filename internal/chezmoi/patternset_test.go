@@ -135,6 +135,41 @@ func TestPatternSetGlob(t *testing.T) {
 	}
 }
 
+func TestPatternSetMatchingPatterns(t *testing.T) {
+	ps := mustNewPatternSet(t, map[string]PatternSetIncludeType{
+		"**/foo": PatternSetInclude,
+		"b*":     PatternSetInclude,
+		"bar/*":  PatternSetInclude,
+		"baz":    PatternSetExclude,
+		"bar/f*": PatternSetExclude,
+	})
+	for _, tc := range []struct {
+		name                    string
+		expectedIncludePatterns []string
+		expectedExcludePatterns []string
+	}{
+		{
+			name: "qux",
+		},
+		{
+			name:                    "baz",
+			expectedIncludePatterns: []string{"b*"},
+			expectedExcludePatterns: []string{"baz"},
+		},
+		{
+			name:                    "bar/foo",
+			expectedIncludePatterns: []string{"**/foo", "bar/*"},
+			expectedExcludePatterns: []string{"bar/f*"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actualIncludePatterns, actualExcludePatterns := ps.matchingPatterns(tc.name)
+			assert.Equal(t, tc.expectedIncludePatterns, actualIncludePatterns)
+			assert.Equal(t, tc.expectedExcludePatterns, actualExcludePatterns)
+		})
+	}
+}
+
 func mustNewPatternSet(t *testing.T, patterns map[string]PatternSetIncludeType) *PatternSet {
 	t.Helper()
 	ps := NewPatternSet()
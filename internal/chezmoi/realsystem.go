@@ -120,13 +120,32 @@ func (s *RealSystem) RunScript(scriptName RelPath, dir AbsPath, data []byte, opt
 	}
 
 	cmd := options.Interpreter.ExecCommand(f.Name())
-	cmd.Dir, err = s.getScriptWorkingDir(dir)
+	cmd.Args = append(cmd.Args, options.Args...)
+	if options.WorkDir.IsEmpty() {
+		cmd.Dir, err = s.getScriptWorkingDir(dir)
+	} else {
+		var workDirRawAbsPath AbsPath
+		workDirRawAbsPath, err = s.RawPath(options.WorkDir)
+		cmd.Dir = workDirRawAbsPath.String()
+	}
 	if err != nil {
 		return err
 	}
 	cmd.Env = append(os.Environ(),
 		"CHEZMOI_SOURCE_FILE="+options.SourceRelPath.String(),
 	)
+	cmd.Env = append(cmd.Env, options.Env...)
+	if options.ApplyContext != nil {
+		var applyContextFilename string
+		applyContextFilename, err = s.writeApplyContextFile(options.ApplyContext)
+		if err != nil {
+			return err
+		}
+		defer chezmoierrors.CombineFunc(&err, func() error {
+			return os.RemoveAll(applyContextFilename)
+		})
+		cmd.Env = append(cmd.Env, "CHEZMOI_APPLY_CONTEXT="+applyContextFilename)
+	}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -144,6 +163,29 @@ func (s *RealSystem) UnderlyingFS() vfs.FS {
 	return s.fileSystem
 }
 
+// writeApplyContextFile writes applyContext to a private temporary file in the
+// script temporary directory and returns its filename.
+func (s *RealSystem) writeApplyContextFile(applyContext *ApplyContext) (string, error) {
+	data, err := FormatJSON.Marshal(applyContext.Data())
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(s.scriptTempDir.String(), "*.apply-context.json")
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS != "windows" {
+		if err := f.Chmod(0o600); err != nil {
+			return "", chezmoierrors.Combine(err, f.Close(), os.Remove(f.Name()))
+		}
+	}
+	_, err = f.Write(data)
+	if err := chezmoierrors.Combine(err, f.Close()); err != nil {
+		return "", chezmoierrors.Combine(err, os.Remove(f.Name()))
+	}
+	return f.Name(), nil
+}
+
 // getScriptWorkingDir returns the script's working directory.
 //
 // If this is a before_ script then the requested working directory may not
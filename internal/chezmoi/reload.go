@@ -0,0 +1,46 @@
+package chezmoi
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// A Reload is a command that is run after an apply if any target matching its
+// pattern was changed.
+type Reload struct {
+	Pattern       string
+	Command       string
+	SourceAbsPath AbsPath
+	LineNumber    int
+}
+
+// newReload returns a new Reload.
+func newReload(pattern, command string, sourceAbsPath AbsPath, lineNumber int) (*Reload, error) {
+	if !doublestar.ValidatePattern(pattern) {
+		return nil, fmt.Errorf("%s:%d: %s: invalid pattern", sourceAbsPath, lineNumber, pattern)
+	}
+	return &Reload{
+		Pattern:       pattern,
+		Command:       command,
+		SourceAbsPath: sourceAbsPath,
+		LineNumber:    lineNumber,
+	}, nil
+}
+
+// LogValue implements log/slog.LogValuer.LogValue.
+func (r *Reload) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("Pattern", r.Pattern),
+		slog.String("Command", r.Command),
+		slog.String("SourceAbsPath", r.SourceAbsPath.String()),
+		slog.Int("LineNumber", r.LineNumber),
+	)
+}
+
+// Match returns if r matches targetRelPath.
+func (r *Reload) Match(targetRelPath RelPath) bool {
+	match, _ := doublestar.Match(r.Pattern, targetRelPath.String())
+	return match
+}
@@ -0,0 +1,42 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestReloadMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern       string
+		targetRelPath string
+		expected      bool
+	}{
+		{
+			pattern:       ".config/tmux/**",
+			targetRelPath: ".config/tmux/tmux.conf",
+			expected:      true,
+		},
+		{
+			pattern:       ".config/tmux/**",
+			targetRelPath: ".config/sway/config",
+			expected:      false,
+		},
+		{
+			pattern:       ".config/{sway,waybar}/*",
+			targetRelPath: ".config/waybar/config",
+			expected:      true,
+		},
+		{
+			pattern:       ".bashrc",
+			targetRelPath: ".bashrc",
+			expected:      true,
+		},
+	} {
+		t.Run(tc.pattern+"_"+tc.targetRelPath, func(t *testing.T) {
+			reload, err := newReload(tc.pattern, "true", NewAbsPath("/home/user/.local/share/chezmoi/.chezmoireload"), 1)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, reload.Match(NewRelPath(tc.targetRelPath)))
+		})
+	}
+}
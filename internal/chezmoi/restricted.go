@@ -0,0 +1,113 @@
+package chezmoi
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// checkRestricted returns an error listing every use of a feature that is
+// blocked in restricted mode.
+func (s *SourceState) checkRestricted() error {
+	var errs []error
+	addBlocked := func(path, use string) {
+		errs = append(errs, fmt.Errorf("%s: %s blocked in restricted mode", path, use))
+	}
+
+	for _, dataGeneratorRelPath := range s.blockedDataGenerators {
+		addBlocked(dataGeneratorRelPath.String(), "data generator")
+	}
+
+	targetRelPaths := slices.SortedFunc(maps.Keys(s.root.GetMap()), CompareRelPaths)
+	for _, targetRelPath := range targetRelPaths {
+		sourceStateFile, ok := s.root.Get(targetRelPath).(*SourceStateFile)
+		if !ok || sourceStateFile.Origin().IsExternal() {
+			continue
+		}
+		sourceRelPathStr := sourceStateFile.sourceRelPath.String()
+		fileAttr := sourceStateFile.Attr()
+
+		var contents []byte
+		switch {
+		case fileAttr.Type == SourceFileTypeScript:
+			addBlocked(sourceRelPathStr, "script")
+			continue
+		case fileAttr.Encrypted:
+			// Encrypted files are not checked as decrypting them may require
+			// user interaction.
+			continue
+		case fileAttr.Type == SourceFileTypeModify:
+			data, err := sourceStateFile.Contents()
+			if err != nil {
+				return err
+			}
+			if !modifyTemplateRx.Match(data) {
+				addBlocked(sourceRelPathStr, "modify script")
+				continue
+			}
+			contents = modifyTemplateRx.ReplaceAll(data, nil)
+		case fileAttr.Template:
+			var err error
+			if contents, err = sourceStateFile.Contents(); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+
+		var templateOptions TemplateOptions
+		if _, err := templateOptions.parseAndRemoveDirectives(contents); err == nil {
+			for _, validator := range templateOptions.Validators {
+				if validator.Command != "" {
+					addBlocked(sourceRelPathStr, "validate command")
+				}
+			}
+		}
+
+		// Templates that cannot be parsed are reported when they are executed.
+		dependencies, err := s.staticTemplateDependencies(sourceRelPathStr, contents)
+		if err != nil {
+			continue
+		}
+		for _, funcName := range dependencies.Funcs {
+			if slices.Contains(s.blockedTemplateFuncs, funcName) {
+				addBlocked(sourceRelPathStr, funcName+" template function")
+			}
+		}
+	}
+
+	externalRelPaths := slices.SortedFunc(maps.Keys(s.externals), CompareRelPaths)
+	for _, externalRelPath := range externalRelPaths {
+		for _, external := range s.externals[externalRelPath] {
+			path := external.sourceAbsPath.String()
+			if sourceRelPath, err := external.sourceAbsPath.TrimDirPrefix(s.sourceLayer(external.sourceAbsPath)); err == nil {
+				path = sourceRelPath.String()
+			}
+			if external.Filter.Command != "" {
+				addBlocked(path+": "+externalRelPath.String(), "external filter command")
+			}
+			// Extra arguments to git can run arbitrary commands, for example
+			// with --upload-pack.
+			if external.Type == ExternalTypeGitRepo && (len(external.Clone.Args) > 0 || len(external.Pull.Args) > 0) {
+				addBlocked(path+": "+externalRelPath.String(), "git-repo external arguments")
+			}
+		}
+	}
+
+	for _, validatorPattern := range s.validatorPatterns {
+		if validatorPattern.validator.Command != "" {
+			addBlocked(validateName+": "+validatorPattern.pattern, "validate command")
+		}
+	}
+
+	for _, reload := range s.reloads {
+		path := reload.SourceAbsPath.String()
+		if sourceRelPath, err := reload.SourceAbsPath.TrimDirPrefix(s.sourceLayer(reload.SourceAbsPath)); err == nil {
+			path = sourceRelPath.String()
+		}
+		addBlocked(fmt.Sprintf("%s:%d", path, reload.LineNumber), "reload command")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,76 @@
+package chezmoi
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// A ScriptDryRun defines whether a script is run in dry-run mode.
+type ScriptDryRun string
+
+// Script dry-run modes.
+const (
+	ScriptDryRunSkip ScriptDryRun = ""
+	ScriptDryRunRun  ScriptDryRun = "run"
+)
+
+// ScriptDirectives are options set by chezmoi: directives in a script's
+// contents.
+type ScriptDirectives struct {
+	DryRun  ScriptDryRun
+	Env     []string
+	Args    []string
+	WorkDir string
+}
+
+// parseScriptDirectives parses all script directives in contents.
+func parseScriptDirectives(contents []byte) (ScriptDirectives, error) {
+	var scriptDirectives ScriptDirectives
+	for _, match := range scriptDryRunDirectiveRx.FindAllSubmatch(contents, -1) {
+		switch value := maybeUnquote(string(match[1])); value {
+		case "run":
+			scriptDirectives.DryRun = ScriptDryRunRun
+		case "skip":
+			scriptDirectives.DryRun = ScriptDryRunSkip
+		default:
+			return ScriptDirectives{}, fmt.Errorf("%s: unknown dry-run mode", value)
+		}
+	}
+	for _, match := range scriptEnvDirectiveRx.FindAllSubmatch(contents, -1) {
+		keyValuePairMatches := templateDirectiveKeyValuePairRx.FindAllSubmatch(match[1], -1)
+		if keyValuePairMatches == nil {
+			return ScriptDirectives{}, fmt.Errorf("%s: invalid env directive", match[1])
+		}
+		for _, keyValuePairMatch := range keyValuePairMatches {
+			key := string(keyValuePairMatch[1])
+			value := maybeUnquote(string(keyValuePairMatch[2]))
+			scriptDirectives.Env = append(scriptDirectives.Env, key+"="+value)
+		}
+	}
+	for _, match := range scriptArgsDirectiveRx.FindAllSubmatch(contents, -1) {
+		for _, argMatch := range scriptArgRx.FindAll(match[1], -1) {
+			scriptDirectives.Args = append(scriptDirectives.Args, maybeUnquote(string(argMatch)))
+		}
+	}
+	for _, match := range scriptWorkDirDirectiveRx.FindAllSubmatch(contents, -1) {
+		scriptDirectives.WorkDir = maybeUnquote(string(match[1]))
+	}
+	return scriptDirectives, nil
+}
+
+// workDirAbsPath returns the absolute path of d's working directory. A leading
+// ~ is expanded to homeDirAbsPath and relative paths are interpreted relative
+// to dirAbsPath. If d has no working directory then it returns EmptyAbsPath.
+func (d ScriptDirectives) workDirAbsPath(dirAbsPath, homeDirAbsPath AbsPath) (AbsPath, error) {
+	switch {
+	case d.WorkDir == "":
+		return EmptyAbsPath, nil
+	case d.WorkDir == "~" || strings.HasPrefix(d.WorkDir, "~/"):
+		return NewAbsPathFromExtPath(d.WorkDir, homeDirAbsPath)
+	case filepath.IsAbs(d.WorkDir):
+		return NewAbsPath(filepath.Clean(d.WorkDir)), nil
+	default:
+		return dirAbsPath.JoinString(d.WorkDir), nil
+	}
+}
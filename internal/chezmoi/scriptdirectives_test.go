@@ -0,0 +1,122 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestParseScriptDirectives(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contents    string
+		expected    ScriptDirectives
+		expectedErr string
+	}{
+		{
+			name:     "empty",
+			contents: "",
+		},
+		{
+			name:     "dry_run_run",
+			contents: "#!/bin/sh\n# chezmoi:dry-run=run\n",
+			expected: ScriptDirectives{
+				DryRun: ScriptDryRunRun,
+			},
+		},
+		{
+			name:     "dry_run_skip",
+			contents: "#!/bin/sh\n# chezmoi:dry-run=skip\n",
+			expected: ScriptDirectives{
+				DryRun: ScriptDryRunSkip,
+			},
+		},
+		{
+			name:        "dry_run_invalid",
+			contents:    "#!/bin/sh\n# chezmoi:dry-run=invalid\n",
+			expectedErr: "invalid: unknown dry-run mode",
+		},
+		{
+			name:     "env",
+			contents: "#!/bin/sh\n# chezmoi:env FOO=bar BAZ=\"qux quux\"\n# chezmoi:env EMPTY=\"\"\n",
+			expected: ScriptDirectives{
+				Env: []string{"FOO=bar", "BAZ=qux quux", "EMPTY="},
+			},
+		},
+		{
+			name:        "env_invalid",
+			contents:    "#!/bin/sh\n# chezmoi:env FOO\n",
+			expectedErr: "FOO: invalid env directive",
+		},
+		{
+			name:     "args",
+			contents: "#!/bin/sh\n# chezmoi:args --verbose \"hello world\"\r\n# chezmoi:args last\n",
+			expected: ScriptDirectives{
+				Args: []string{"--verbose", "hello world", "last"},
+			},
+		},
+		{
+			name:     "workdir",
+			contents: "#!/bin/sh\n# chezmoi:workdir=~/src\n",
+			expected: ScriptDirectives{
+				WorkDir: "~/src",
+			},
+		},
+		{
+			name:     "workdir_quoted",
+			contents: "#!/bin/sh\n# chezmoi:workdir=\"My Documents\"\n",
+			expected: ScriptDirectives{
+				WorkDir: "My Documents",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseScriptDirectives([]byte(tc.contents))
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestScriptDirectivesWorkDirAbsPath(t *testing.T) {
+	dirAbsPath := NewAbsPath("/home/user/.config")
+	homeDirAbsPath := NewAbsPath("/home/user")
+	for _, tc := range []struct {
+		workDir  string
+		expected AbsPath
+	}{
+		{
+			workDir:  "",
+			expected: EmptyAbsPath,
+		},
+		{
+			workDir:  "~",
+			expected: homeDirAbsPath,
+		},
+		{
+			workDir:  "~/src",
+			expected: NewAbsPath("/home/user/src"),
+		},
+		{
+			workDir:  "app",
+			expected: NewAbsPath("/home/user/.config/app"),
+		},
+		{
+			workDir:  "../src",
+			expected: NewAbsPath("/home/user/src"),
+		},
+	} {
+		t.Run(tc.workDir, func(t *testing.T) {
+			scriptDirectives := ScriptDirectives{
+				WorkDir: tc.workDir,
+			}
+			actual, err := scriptDirectives.workDirAbsPath(dirAbsPath, homeDirAbsPath)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
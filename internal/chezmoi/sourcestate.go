@@ -53,6 +53,11 @@ var (
 	commentRx                       = regexp.MustCompile(`(?:\A|\s+)#.*(?:\r?\n)?$`)
 	lineEndingRx                    = regexp.MustCompile(`(?m)(?:\r\n|\r|\n)`)
 	modifyTemplateRx                = regexp.MustCompile(`(?m)^.*chezmoi:modify-template.*$(?:\r?\n)?`)
+	scriptArgRx                     = regexp.MustCompile(`"(?:[^"]|\\")*"|\S+`)
+	scriptArgsDirectiveRx           = regexp.MustCompile(`(?m)^.*?chezmoi:args[ \t]+(.*?)\r?$`)
+	scriptDryRunDirectiveRx         = regexp.MustCompile(`(?m)^.*?chezmoi:dry-run=(\S+)`)
+	scriptEnvDirectiveRx            = regexp.MustCompile(`(?m)^.*?chezmoi:env[ \t]+(.*?)\r?$`)
+	scriptWorkDirDirectiveRx        = regexp.MustCompile(`(?m)^.*?chezmoi:workdir=("(?:[^"]|\\")*"|\S+)`)
 	templateDirectiveRx             = regexp.MustCompile(`(?m)^.*?chezmoi:template:(.*)$(?:\r?\n)?`)
 	templateDirectiveKeyValuePairRx = regexp.MustCompile(`\s*(\S+)=("(?:[^"]|\\")*"|\S+)`)
 
@@ -119,39 +124,54 @@ type External struct {
 
 // A SourceState is a source state.
 type SourceState struct {
-	mutex                   sync.Mutex
-	root                    SourceStateEntryTreeNode
-	removeDirs              chezmoiset.Set[RelPath]
-	baseSystem              System
-	system                  System
-	sourceDirAbsPath        AbsPath
-	destDirAbsPath          AbsPath
-	cacheDirAbsPath         AbsPath
-	createScriptTempDirOnce sync.Once
-	scriptTempDirAbsPath    AbsPath
-	umask                   fs.FileMode
-	encryption              Encryption
-	ignore                  *PatternSet
-	remove                  *PatternSet
-	interpreters            map[string]Interpreter
-	httpClient              *http.Client
-	logger                  *slog.Logger
-	version                 semver.Version
-	mode                    Mode
-	defaultTemplateDataFunc func() map[string]any
-	templateDataOnly        bool
-	readTemplateData        bool
-	readTemplates           bool
-	defaultTemplateData     map[string]any
-	userTemplateData        map[string]any
-	priorityTemplateData    map[string]any
-	templateData            map[string]any
-	templateFuncs           template.FuncMap
-	templateOptions         []string
-	templates               map[string]*Template
-	externals               map[RelPath][]*External
-	ignoredRelPaths         chezmoiset.Set[RelPath]
-	warnFunc                WarnFunc
+	mutex                        sync.Mutex
+	root                         SourceStateEntryTreeNode
+	removeDirs                   chezmoiset.Set[RelPath]
+	baseSystem                   System
+	system                       System
+	sourceDirAbsPath             AbsPath
+	sourceLayerAbsPaths          []AbsPath
+	destDirAbsPath               AbsPath
+	cacheDirAbsPath              AbsPath
+	createScriptTempDirOnce      sync.Once
+	scriptTempDirAbsPath         AbsPath
+	umask                        fs.FileMode
+	encryption                   Encryption
+	ignore                       *PatternSet
+	remove                       *PatternSet
+	reloads                      []*Reload
+	interpreters                 map[string]Interpreter
+	httpClient                   *http.Client
+	logger                       *slog.Logger
+	version                      semver.Version
+	mode                         Mode
+	defaultTemplateDataFunc      func() map[string]any
+	dataGeneratorCacheTTL        time.Duration
+	templateDataOnly             bool
+	readExternals                bool
+	readTemplateData             bool
+	readTemplates                bool
+	defaultTemplateData          map[string]any
+	userTemplateData             map[string]any
+	localTemplateData            map[RelPath]map[string]any
+	priorityTemplateData         map[string]any
+	templateData                 map[string]any
+	templateDataSchema           *TemplateDataSchema
+	validateTemplateDataFunc     func() error
+	templateFuncs                template.FuncMap
+	sourceLayerTemplateFuncsFunc func(AbsPath) template.FuncMap
+	templateOptions              []string
+	templateTracer               *TemplateTracer
+	templates                    map[string]*Template
+	externals                    map[RelPath][]*External
+	ignoredRelPaths              chezmoiset.Set[RelPath]
+	validatorPatterns            []*validatorPattern
+	restricted                   bool
+	blockedTemplateFuncs         []string
+	blockedDataGenerators        []RelPath
+	warnFunc                     WarnFunc
+	moduleChoices                map[string]bool
+	modules                      map[string]*Module
 }
 
 // A SourceStateOption sets an option on a source state.
@@ -171,6 +191,14 @@ func WithCacheDir(cacheDirAbsPath AbsPath) SourceStateOption {
 	}
 }
 
+// WithDataGeneratorCacheTTL sets the time for which the output of data
+// generators is cached.
+func WithDataGeneratorCacheTTL(dataGeneratorCacheTTL time.Duration) SourceStateOption {
+	return func(s *SourceState) {
+		s.dataGeneratorCacheTTL = dataGeneratorCacheTTL
+	}
+}
+
 // WithDefaultTemplateDataFunc sets the default template data function.
 func WithDefaultTemplateDataFunc(defaultTemplateDataFunc func() map[string]any) SourceStateOption {
 	return func(s *SourceState) {
@@ -220,6 +248,14 @@ func WithMode(mode Mode) SourceStateOption {
 	}
 }
 
+// WithModules sets which modules are explicitly enabled or disabled, overriding
+// their conditions.
+func WithModules(moduleChoices map[string]bool) SourceStateOption {
+	return func(s *SourceState) {
+		s.moduleChoices = moduleChoices
+	}
+}
+
 // WithPriorityTemplateData adds priority template data.
 func WithPriorityTemplateData(priorityTemplateData map[string]any) SourceStateOption {
 	return func(s *SourceState) {
@@ -227,6 +263,14 @@ func WithPriorityTemplateData(priorityTemplateData map[string]any) SourceStateOp
 	}
 }
 
+// WithReadExternals sets whether to read .chezmoiexternal.<format> files and
+// .chezmoiexternals directories.
+func WithReadExternals(readExternals bool) SourceStateOption {
+	return func(s *SourceState) {
+		s.readExternals = readExternals
+	}
+}
+
 // WithReadTemplateData sets whether to read .chezmoidata.<format> files.
 func WithReadTemplateData(readTemplateData bool) SourceStateOption {
 	return func(s *SourceState) {
@@ -241,6 +285,16 @@ func WithReadTemplates(readTemplates bool) SourceStateOption {
 	}
 }
 
+// WithRestricted enables restricted mode, in which scripts, modify_ scripts,
+// externals with filter commands, validate commands, and templates that call
+// any of blockedTemplateFuncs are refused.
+func WithRestricted(blockedTemplateFuncs []string) SourceStateOption {
+	return func(s *SourceState) {
+		s.restricted = true
+		s.blockedTemplateFuncs = blockedTemplateFuncs
+	}
+}
+
 // WithScriptTempDir sets the source directory.
 func WithScriptTempDir(scriptDirAbsPath AbsPath) SourceStateOption {
 	return func(s *SourceState) {
@@ -255,6 +309,15 @@ func WithSourceDir(sourceDirAbsPath AbsPath) SourceStateOption {
 	}
 }
 
+// WithSourceLayers sets the source directory layers, in the order in which
+// they are read. The source directory set with WithSourceDir should be one of
+// the layers, and is the layer that is written to.
+func WithSourceLayers(sourceLayerAbsPaths []AbsPath) SourceStateOption {
+	return func(s *SourceState) {
+		s.sourceLayerAbsPaths = sourceLayerAbsPaths
+	}
+}
+
 // WithSystem sets the system.
 func WithSystem(system System) SourceStateOption {
 	return func(s *SourceState) {
@@ -276,6 +339,15 @@ func WithTemplateFuncs(templateFuncs template.FuncMap) SourceStateOption {
 	}
 }
 
+// WithSourceLayerTemplateFuncs sets a function that returns the template
+// functions that depend on the source directory layer of the template being
+// executed, for example to find included files.
+func WithSourceLayerTemplateFuncs(sourceLayerTemplateFuncsFunc func(AbsPath) template.FuncMap) SourceStateOption {
+	return func(s *SourceState) {
+		s.sourceLayerTemplateFuncsFunc = sourceLayerTemplateFuncsFunc
+	}
+}
+
 // WithTemplateOptions sets the template options.
 func WithTemplateOptions(templateOptions []string) SourceStateOption {
 	return func(s *SourceState) {
@@ -283,6 +355,13 @@ func WithTemplateOptions(templateOptions []string) SourceStateOption {
 	}
 }
 
+// WithTemplateTracer sets the template tracer.
+func WithTemplateTracer(templateTracer *TemplateTracer) SourceStateOption {
+	return func(s *SourceState) {
+		s.templateTracer = templateTracer
+	}
+}
+
 // WithUmask sets the umask.
 func WithUmask(umask fs.FileMode) SourceStateOption {
 	return func(s *SourceState) {
@@ -318,15 +397,26 @@ func NewSourceState(options ...SourceStateOption) *SourceState {
 		remove:               NewPatternSet(),
 		httpClient:           http.DefaultClient,
 		logger:               slog.Default(),
+		readExternals:        true,
 		readTemplateData:     true,
 		readTemplates:        true,
 		priorityTemplateData: make(map[string]any),
 		userTemplateData:     make(map[string]any),
+		localTemplateData:    make(map[RelPath]map[string]any),
 		templateOptions:      DefaultTemplateOptions,
 		templates:            make(map[string]*Template),
 		externals:            make(map[RelPath][]*External),
 		ignoredRelPaths:      chezmoiset.New[RelPath](),
+		modules:              make(map[string]*Module),
 	}
+	// Validate the template data lazily, immediately before the first
+	// template is executed, so that all template data have been read.
+	s.validateTemplateDataFunc = sync.OnceValue(func() error {
+		if s.templateDataSchema == nil {
+			return nil
+		}
+		return s.templateDataSchema.Validate(s.TemplateData())
+	})
 	for _, option := range options {
 		option(s)
 	}
@@ -436,14 +526,16 @@ func (s *SourceState) Add(
 	}
 
 	type sourceUpdate struct {
-		destAbsPath    AbsPath
-		entryState     *EntryState
-		sourceRelPaths []SourceRelPath
+		destAbsPath      AbsPath
+		entryState       *EntryState
+		sourceDirAbsPath AbsPath
+		sourceRelPaths   []SourceRelPath
 	}
 
 	sourceUpdates := make([]sourceUpdate, 0, len(destAbsPaths))
 	newSourceStateEntries := make(map[SourceRelPath]SourceStateEntry)
 	newSourceStateEntriesByTargetRelPath := make(map[RelPath]SourceStateEntry)
+	newSourceDirAbsPaths := make(map[SourceRelPath]AbsPath)
 	nonEmptyDirs := chezmoiset.New[SourceRelPath]()
 	externalDirRelPaths := chezmoiset.New[RelPath]()
 	dirRenames := make(map[AbsPath]AbsPath)
@@ -556,12 +648,16 @@ DEST_ABS_PATH:
 			return err
 		}
 		update := sourceUpdate{
-			destAbsPath:    destAbsPath,
-			entryState:     entryState,
-			sourceRelPaths: []SourceRelPath{sourceEntryRelPath},
+			destAbsPath:      destAbsPath,
+			entryState:       entryState,
+			sourceDirAbsPath: s.addSourceDirAbsPath(targetRelPath),
+			sourceRelPaths:   []SourceRelPath{sourceEntryRelPath},
 		}
 
-		if oldSourceStateEntry := s.root.Get(targetRelPath); oldSourceStateEntry != nil {
+		// Entries from other source directory layers are overridden by the
+		// new entry rather than replaced.
+		if oldSourceStateEntry := s.root.Get(targetRelPath); oldSourceStateEntry != nil &&
+			s.SourceAbsPath(oldSourceStateEntry).HasDirPrefix(s.sourceDirAbsPath) {
 			oldSourceEntryRelPath := oldSourceStateEntry.SourceRelPath()
 			if !oldSourceEntryRelPath.IsEmpty() && oldSourceEntryRelPath != sourceEntryRelPath {
 				if options.ReplaceFunc != nil {
@@ -579,8 +675,8 @@ DEST_ABS_PATH:
 				_, newIsDir := newSourceStateEntry.(*SourceStateDir)
 				_, oldIsDir := oldSourceStateEntry.(*SourceStateDir)
 				if newIsDir && oldIsDir {
-					oldSourceAbsPath := s.sourceDirAbsPath.Join(oldSourceEntryRelPath.RelPath())
-					newSourceAbsPath := s.sourceDirAbsPath.Join(sourceEntryRelPath.RelPath())
+					oldSourceAbsPath := update.sourceDirAbsPath.Join(oldSourceEntryRelPath.RelPath())
+					newSourceAbsPath := update.sourceDirAbsPath.Join(sourceEntryRelPath.RelPath())
 					dirRenames[oldSourceAbsPath] = newSourceAbsPath
 					continue DEST_ABS_PATH
 				}
@@ -595,6 +691,7 @@ DEST_ABS_PATH:
 
 		newSourceStateEntries[sourceEntryRelPath] = newSourceStateEntry
 		newSourceStateEntriesByTargetRelPath[targetRelPath] = newSourceStateEntry
+		newSourceDirAbsPaths[sourceEntryRelPath] = update.sourceDirAbsPath
 
 		sourceUpdates = append(sourceUpdates, update)
 	}
@@ -615,12 +712,13 @@ DEST_ABS_PATH:
 				Type: EntryStateTypeFile,
 				Mode: 0o666 &^ s.umask,
 			},
-			sourceRelPaths: []SourceRelPath{dotKeepFileRelPath},
+			sourceDirAbsPath: newSourceDirAbsPaths[sourceEntryRelPath],
+			sourceRelPaths:   []SourceRelPath{dotKeepFileRelPath},
 		}
 		sourceUpdates = append(sourceUpdates, dotKeepFileSourceUpdate)
 
 		newSourceStateEntries[dotKeepFileRelPath] = &SourceStateFile{
-			origin: SourceStateOriginAbsPath(s.sourceDirAbsPath.Join(sourceEntryRelPath.RelPath())),
+			origin: SourceStateOriginAbsPath(dotKeepFileSourceUpdate.sourceDirAbsPath.Join(sourceEntryRelPath.RelPath())),
 			targetStateEntry: &TargetStateFile{
 				contentsFunc:       eagerNoErr[[]byte](nil),
 				contentsSHA256Func: eagerNoErr(sha256.Sum256(nil)),
@@ -657,7 +755,8 @@ DEST_ABS_PATH:
 				entryState: &EntryState{
 					Type: EntryStateTypeRemove,
 				},
-				sourceRelPaths: []SourceRelPath{sourceRelPath},
+				sourceDirAbsPath: s.addSourceDirAbsPath(targetRelPath),
+				sourceRelPaths:   []SourceRelPath{sourceRelPath},
 			}
 			sourceUpdates = append(sourceUpdates, update)
 			return nil
@@ -670,11 +769,19 @@ DEST_ABS_PATH:
 
 	for _, sourceUpdate := range sourceUpdates {
 		for _, sourceRelPath := range sourceUpdate.sourceRelPaths {
+			// The parent directory might only exist in another source
+			// directory layer.
+			if len(s.sourceLayerAbsPaths) > 1 {
+				parentAbsPath := sourceUpdate.sourceDirAbsPath.Join(sourceRelPath.RelPath().Dir())
+				if err := MkdirAll(sourceSystem, parentAbsPath, fs.ModePerm); err != nil {
+					return err
+				}
+			}
 			err := targetSourceState.Apply(
 				sourceSystem,
 				sourceSystem,
 				NullPersistentState{},
-				s.sourceDirAbsPath,
+				sourceUpdate.sourceDirAbsPath,
 				sourceRelPath.RelPath(),
 				ApplyOptions{
 					Filter: options.Filter,
@@ -756,9 +863,13 @@ type PreApplyFunc func(targetRelPath RelPath, targetEntryState, lastWrittenEntry
 
 // ApplyOptions are options to SourceState.ApplyAll and SourceState.ApplyOne.
 type ApplyOptions struct {
-	Filter       *EntryTypeFilter
-	PreApplyFunc PreApplyFunc
-	Umask        fs.FileMode
+	ApplyContext   *ApplyContext
+	Filter         *EntryTypeFilter
+	HomeDirAbsPath AbsPath
+	PreApplyFunc   PreApplyFunc
+	ScriptEnv      []string
+	Umask          fs.FileMode
+	Validate       bool
 }
 
 // Apply updates targetRelPath in targetDirAbsPath in destSystem to match s.
@@ -807,6 +918,14 @@ func (s *SourceState) Apply(
 		return err
 	}
 
+	var actualEntryState *EntryState
+	if options.PreApplyFunc != nil || options.ApplyContext != nil {
+		actualEntryState, err = actualStateEntry.EntryState()
+		if err != nil {
+			return err
+		}
+	}
+
 	if options.PreApplyFunc != nil {
 		var lastWrittenEntryState *EntryState
 		var entryState EntryState
@@ -818,11 +937,6 @@ func (s *SourceState) Apply(
 			lastWrittenEntryState = &entryState
 		}
 
-		actualEntryState, err := actualStateEntry.EntryState()
-		if err != nil {
-			return err
-		}
-
 		// If the target entry state matches the actual entry state, but not the
 		// last written entry state then silently update the last written entry
 		// state. This handles the case where the user makes identical edits to
@@ -843,12 +957,21 @@ func (s *SourceState) Apply(
 		}
 	}
 
-	if changed, err := targetStateEntry.Apply(targetSystem, persistentState, actualStateEntry); err != nil {
+	if changed, err := targetStateEntry.Apply(targetSystem, persistentState, actualStateEntry, TargetStateApplyOptions{
+		ApplyContext:   options.ApplyContext,
+		HomeDirAbsPath: options.HomeDirAbsPath,
+		ScriptEnv:      options.ScriptEnv,
+		Validate:       options.Validate,
+	}); err != nil {
 		return err
 	} else if !changed {
 		return nil
 	}
 
+	if options.ApplyContext != nil {
+		options.ApplyContext.record(targetAbsPath, targetEntryState, actualEntryState)
+	}
+
 	return PersistentStateSet(persistentState, EntryStateBucket, targetAbsPath.Bytes(), targetEntryState)
 }
 
@@ -859,18 +982,24 @@ func (s *SourceState) Encryption() Encryption {
 
 // ExecuteTemplateDataOptions are options to SourceState.ExecuteTemplateData.
 type ExecuteTemplateDataOptions struct {
-	NameRelPath     RelPath
-	DestAbsPath     AbsPath
-	Data            []byte
-	TemplateOptions TemplateOptions
-	ExtraData       map[string]any
+	NameRelPath        RelPath
+	SourceLayerAbsPath AbsPath
+	DestAbsPath        AbsPath
+	Data               []byte
+	TemplateOptions    TemplateOptions
+	ExtraData          map[string]any
 }
 
 // ExecuteTemplateData returns the result of executing template data.
 func (s *SourceState) ExecuteTemplateData(options ExecuteTemplateDataOptions) ([]byte, error) {
 	templateOptions := options.TemplateOptions
-	templateOptions.Funcs = s.templateFuncs
+	templateOptions.Funcs = s.sourceLayerTemplateFuncs(options.SourceLayerAbsPath)
 	templateOptions.Options = slices.Clone(s.templateOptions)
+	templateOptions.Tracer = s.templateTracer
+
+	if err := s.validateTemplateDataFunc(); err != nil {
+		return nil, err
+	}
 
 	tmpl, err := ParseTemplate(options.NameRelPath.String(), options.Data, templateOptions)
 	if err != nil {
@@ -885,7 +1014,7 @@ func (s *SourceState) ExecuteTemplateData(options ExecuteTemplateDataOptions) ([
 	}
 
 	// Set .chezmoi.sourceFile to the name of the template.
-	templateData := s.TemplateData()
+	templateData := s.TemplateDataInDir(options.NameRelPath.Dir())
 	if chezmoiTemplateData, ok := templateData["chezmoi"].(map[string]any); ok {
 		chezmoiTemplateData["sourceFile"] = options.NameRelPath.String()
 		chezmoiTemplateData["targetFile"] = options.DestAbsPath.String()
@@ -899,6 +1028,17 @@ func (s *SourceState) ExecuteTemplateData(options ExecuteTemplateDataOptions) ([
 	return result, err
 }
 
+// sourceLayerTemplateFuncs returns the template functions for templates in the
+// source directory layer sourceLayerAbsPath.
+func (s *SourceState) sourceLayerTemplateFuncs(sourceLayerAbsPath AbsPath) template.FuncMap {
+	if s.sourceLayerTemplateFuncsFunc == nil || sourceLayerAbsPath.IsEmpty() {
+		return s.templateFuncs
+	}
+	templateFuncs := maps.Clone(s.templateFuncs)
+	maps.Copy(templateFuncs, s.sourceLayerTemplateFuncsFunc(sourceLayerAbsPath))
+	return templateFuncs
+}
+
 // ForEach calls f for each source state entry.
 func (s *SourceState) ForEach(f func(RelPath, SourceStateEntry) error) error {
 	return s.root.ForEach(EmptyRelPath, func(targetRelPath RelPath, entry SourceStateEntry) error {
@@ -996,18 +1136,12 @@ type ReadOptions struct {
 
 // Read reads the source state from the source directory.
 func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
-	switch fileInfo, err := s.system.Stat(s.sourceDirAbsPath); {
-	case errors.Is(err, fs.ErrNotExist):
-		return nil
-	case err != nil:
-		return err
-	case !fileInfo.IsDir():
-		return fmt.Errorf("%s: not a directory", s.sourceDirAbsPath)
-	}
-
-	// Read all source entries.
+	// Read all source entries. The entries in each layer replace the entries
+	// for the same targets in earlier layers.
 	var allSourceStateEntriesMu sync.Mutex
-	allSourceStateEntries := make(map[RelPath][]SourceStateEntry)
+	var allSourceStateEntries map[RelPath][]SourceStateEntry
+	var layerAbsPath AbsPath
+	var readingModule bool
 	addSourceStateEntries := func(relPath RelPath, sourceStateEntries ...SourceStateEntry) {
 		allSourceStateEntriesMu.Lock()
 		defer allSourceStateEntriesMu.Unlock()
@@ -1017,7 +1151,7 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 		if err != nil {
 			return err
 		}
-		if sourceAbsPath == s.sourceDirAbsPath {
+		if sourceAbsPath == layerAbsPath {
 			return nil
 		}
 
@@ -1036,17 +1170,45 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 		}
 
 		sourceRelPath := SourceRelPath{
-			relPath: sourceAbsPath.MustTrimDirPrefix(s.sourceDirAbsPath),
+			relPath: sourceAbsPath.MustTrimDirPrefix(layerAbsPath),
 			isDir:   fileInfo.IsDir(),
 		}
 		parentSourceRelPath, sourceName := sourceRelPath.Split()
 
+		// Modules are read after the rest of the source directory layer, once
+		// it is known which modules are enabled.
+		if fileInfo.IsDir() && !readingModule && sourceRelPath.RelPath().Dir() == DotRelPath &&
+			!strings.HasPrefix(fileInfo.Name(), ".") {
+			switch module, err := s.readModule(sourceAbsPath); {
+			case err != nil:
+				return err
+			case module != nil:
+				if err := s.addModule(module); err != nil {
+					return err
+				}
+				return fs.SkipDir
+			}
+		}
+
 		switch {
 		case fileInfo.Name() == dataName:
 			if !s.readTemplateData {
 				return nil
 			}
-			if err := s.addTemplateDataDir(sourceAbsPath, fileInfo); err != nil {
+			if err := s.addTemplateDataDir(sourceAbsPath, fileInfo, sourceRelPath.RelPath().Dir()); err != nil {
+				return err
+			}
+			return fs.SkipDir
+		case fileInfo.Name() == DataSchemaName:
+			if !s.readTemplateData {
+				return nil
+			}
+			return s.addTemplateDataSchema(sourceAbsPath, sourceRelPath.RelPath().Dir())
+		case fileInfo.Name() == dataGeneratorsDirName:
+			if !s.readTemplateData {
+				return fs.SkipDir
+			}
+			if err := s.addTemplateDataGeneratorsDir(sourceAbsPath, fileInfo, sourceRelPath.RelPath().Dir()); err != nil {
 				return err
 			}
 			return fs.SkipDir
@@ -1054,7 +1216,7 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 			if !s.readTemplateData {
 				return nil
 			}
-			return s.addTemplateData(sourceAbsPath)
+			return s.addTemplateData(sourceAbsPath, sourceRelPath.RelPath().Dir())
 		case fileInfo.Name() == TemplatesDirName:
 			if s.readTemplates {
 				if err := s.addTemplatesDir(ctx, sourceAbsPath); err != nil {
@@ -1065,9 +1227,15 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 		case s.templateDataOnly:
 			return nil
 		case isPrefixDotFormat(fileInfo.Name(), externalName) || isPrefixDotFormatDotTmpl(fileInfo.Name(), externalName):
+			if !s.readExternals {
+				return nil
+			}
 			parentAbsPath, _ := sourceAbsPath.Split()
 			return s.addExternal(sourceAbsPath, parentAbsPath)
 		case fileInfo.Name() == externalsDirName:
+			if !s.readExternals {
+				return fs.SkipDir
+			}
 			if err := s.addExternalDir(ctx, sourceAbsPath); err != nil {
 				return err
 			}
@@ -1076,6 +1244,10 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 			return s.addPatterns(s.ignore, sourceAbsPath, parentSourceRelPath)
 		case fileInfo.Name() == removeName || fileInfo.Name() == removeName+TemplateSuffix:
 			return s.addPatterns(s.remove, sourceAbsPath, parentSourceRelPath)
+		case fileInfo.Name() == reloadName || fileInfo.Name() == reloadName+TemplateSuffix:
+			return s.addReloads(sourceAbsPath, parentSourceRelPath)
+		case fileInfo.Name() == validateName || fileInfo.Name() == validateName+TemplateSuffix:
+			return s.addValidatorPatterns(sourceAbsPath, parentSourceRelPath)
 		case fileInfo.Name() == scriptsDirName:
 			scriptsDirSourceStateEntries, err := s.readScriptsDir(ctx, sourceAbsPath)
 			if err != nil {
@@ -1151,9 +1323,52 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 			}
 		}
 	}
-	if err := WalkSourceDir(s.system, s.sourceDirAbsPath, walkFunc); err != nil {
+	var layerAbsPaths []AbsPath
+	var layersSourceStateEntries []map[RelPath][]SourceStateEntry
+	for _, layerAbsPath = range s.sourceLayers() {
+		switch fileInfo, err := s.system.Stat(layerAbsPath); {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return err
+		case !fileInfo.IsDir():
+			return fmt.Errorf("%s: not a directory", layerAbsPath)
+		}
+		allSourceStateEntries = make(map[RelPath][]SourceStateEntry)
+		if err := WalkSourceDir(s.system, layerAbsPath, walkFunc); err != nil {
+			return err
+		}
+		layerAbsPaths = append(layerAbsPaths, layerAbsPath)
+		layersSourceStateEntries = append(layersSourceStateEntries, allSourceStateEntries)
+	}
+
+	// Read enabled modules as part of the layer that contains them. Modules
+	// may contribute entries to directories that are also in the layer. The
+	// module's entries are inserted first so that, if the directories are
+	// equivalent, the layer's directory is chosen as the canonical entry.
+	if err := s.resolveModules(); err != nil {
 		return err
 	}
+	readingModule = true
+	for _, module := range s.Modules() {
+		if !module.enabled {
+			continue
+		}
+		layerAbsPath = module.absPath
+		allSourceStateEntries = make(map[RelPath][]SourceStateEntry)
+		if err := WalkSourceDir(s.system, module.absPath, walkFunc); err != nil {
+			return err
+		}
+		layerSourceStateEntries := layersSourceStateEntries[slices.Index(layerAbsPaths, module.absPath.Dir())]
+		for targetRelPath, sourceStateEntries := range allSourceStateEntries {
+			layerSourceStateEntries[targetRelPath] = append(sourceStateEntries, layerSourceStateEntries[targetRelPath]...)
+		}
+	}
+
+	allSourceStateEntries = make(map[RelPath][]SourceStateEntry)
+	for _, layerSourceStateEntries := range layersSourceStateEntries {
+		maps.Copy(allSourceStateEntries, layerSourceStateEntries)
+	}
 
 	if s.templateDataOnly {
 		return nil
@@ -1376,9 +1591,41 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 		s.root.Set(targetRelPath, sourceEntries[0])
 	}
 
+	if s.restricted {
+		return s.checkRestricted()
+	}
+
 	return nil
 }
 
+// Reloads returns the reloads that match any of changedTargetRelPaths. Each
+// command is returned at most once, in the order in which it was first
+// declared.
+func (s *SourceState) Reloads(changedTargetRelPaths []RelPath) []*Reload {
+	var reloads []*Reload
+	commands := chezmoiset.New[string]()
+	for _, reload := range s.reloads {
+		if commands.Contains(reload.Command) {
+			continue
+		}
+		if slices.ContainsFunc(changedTargetRelPaths, reload.Match) {
+			reloads = append(reloads, reload)
+			commands.Add(reload.Command)
+		}
+	}
+	return reloads
+}
+
+// SourceAbsPath returns the absolute path of sourceStateEntry in the source
+// directory layer that contributed it.
+func (s *SourceState) SourceAbsPath(sourceStateEntry SourceStateEntry) AbsPath {
+	layerAbsPath := s.sourceDirAbsPath
+	if origin, ok := sourceStateEntry.Origin().(SourceStateOriginAbsPath); ok {
+		layerAbsPath = s.sourceLayer(AbsPath(origin))
+	}
+	return layerAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath())
+}
+
 // TargetRelPaths returns all of s's target relative paths in order.
 func (s *SourceState) TargetRelPaths() []RelPath {
 	entries := s.root.GetMap()
@@ -1399,27 +1646,84 @@ func (s *SourceState) TargetRelPaths() []RelPath {
 func (s *SourceState) TemplateData() map[string]any {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	s.computeTemplateData()
+	return copyTemplateData(s.templateData)
+}
 
-	if s.templateData == nil {
-		s.templateData = make(map[string]any)
-		if s.defaultTemplateDataFunc != nil {
-			s.defaultTemplateData = s.defaultTemplateDataFunc()
-			s.defaultTemplateDataFunc = nil
-		}
-		RecursiveMerge(s.templateData, s.defaultTemplateData)
-		RecursiveMerge(s.templateData, s.userTemplateData)
-		RecursiveMerge(s.templateData, s.priorityTemplateData)
+// computeTemplateData computes s's default template data and merged template
+// data if they have not already been computed. s.mutex must be held.
+func (s *SourceState) computeTemplateData() {
+	if s.templateData != nil {
+		return
+	}
+	s.templateData = make(map[string]any)
+	if s.defaultTemplateDataFunc != nil {
+		s.defaultTemplateData = s.defaultTemplateDataFunc()
+		s.defaultTemplateDataFunc = nil
 	}
-	templateData, err := copystructure.Copy(s.templateData)
+	RecursiveMerge(s.templateData, s.defaultTemplateData)
+	RecursiveMerge(s.templateData, s.userTemplateData)
+	RecursiveMerge(s.templateData, s.priorityTemplateData)
+}
+
+// copyTemplateData returns a deep copy of templateData.
+func copyTemplateData(templateData map[string]any) map[string]any {
+	templateDataCopy, err := copystructure.Copy(templateData)
 	if err != nil {
 		panic(err)
 	}
-	return templateData.(map[string]any) //nolint:forcetypeassert,revive
+	return templateDataCopy.(map[string]any) //nolint:forcetypeassert,revive
+}
+
+// LocalTemplateData returns the template data from .chezmoidata files in
+// dirRelPath and its parents, excluding the root of the source directory. Data
+// in deeper directories take precedence.
+func (s *SourceState) LocalTemplateData(dirRelPath RelPath) map[string]any {
+	var dirRelPaths []RelPath
+	for ; dirRelPath != DotRelPath && !dirRelPath.IsEmpty() && dirRelPath.String() != "/"; dirRelPath = dirRelPath.Dir() {
+		dirRelPaths = append(dirRelPaths, dirRelPath)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	localTemplateData := make(map[string]any)
+	for _, dirRelPath := range slices.Backward(dirRelPaths) {
+		if dirTemplateData, ok := s.localTemplateData[dirRelPath]; ok {
+			RecursiveMerge(localTemplateData, copyTemplateData(dirTemplateData))
+		}
+	}
+	return localTemplateData
+}
+
+// TemplateDataInDir returns the template data for templates in the source
+// directory dirRelPath. This is the template data overlaid with the template
+// data returned by LocalTemplateData, which are also available as
+// .chezmoi.localData. Data from the config file take precedence over all data
+// from .chezmoidata files.
+func (s *SourceState) TemplateDataInDir(dirRelPath RelPath) map[string]any {
+	localTemplateData := s.LocalTemplateData(dirRelPath)
+	var templateData map[string]any
+	if len(localTemplateData) == 0 {
+		templateData = s.TemplateData()
+	} else {
+		s.mutex.Lock()
+		s.computeTemplateData()
+		templateData = make(map[string]any)
+		RecursiveMerge(templateData, copyTemplateData(s.defaultTemplateData))
+		RecursiveMerge(templateData, copyTemplateData(s.userTemplateData))
+		RecursiveMerge(templateData, copyTemplateData(localTemplateData))
+		RecursiveMerge(templateData, copyTemplateData(s.priorityTemplateData))
+		s.mutex.Unlock()
+	}
+	if chezmoiTemplateData, ok := templateData["chezmoi"].(map[string]any); ok {
+		chezmoiTemplateData["localData"] = localTemplateData
+	}
+	return templateData
 }
 
 // addExternal adds external source entries to s.
 func (s *SourceState) addExternal(sourceAbsPath, parentAbsPath AbsPath) error {
-	parentRelPath, err := parentAbsPath.TrimDirPrefix(s.sourceDirAbsPath)
+	parentRelPath, err := parentAbsPath.TrimDirPrefix(s.sourceLayer(parentAbsPath))
 	if err != nil {
 		return err
 	}
@@ -1540,8 +1844,89 @@ func (s *SourceState) addPatterns(patternSet *PatternSet, sourceAbsPath AbsPath,
 	return nil
 }
 
-// addTemplateData adds all template data in sourceAbsPath to s.
-func (s *SourceState) addTemplateData(sourceAbsPath AbsPath) error {
+// addReloads executes the template at sourceAbsPath, interprets the result as
+// a list of patterns and commands, and adds all reloads found to s.
+func (s *SourceState) addReloads(sourceAbsPath AbsPath, sourceRelPath SourceRelPath) error {
+	data, err := s.executeTemplate(sourceAbsPath)
+	if err != nil {
+		return err
+	}
+
+	dir, err := sourceRelPath.Dir().TargetRelPath("")
+	if err != nil {
+		return err
+	}
+	var reloads []*Reload
+	lineNumber := 0
+	for line := range bytes.Lines(data) {
+		lineNumber++
+		// Only whole lines are comments as commands may contain #.
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := whitespaceRx.Split(string(line), 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: missing command", sourceAbsPath, lineNumber)
+		}
+		pattern := dir.JoinString(fields[0]).String()
+		reload, err := newReload(pattern, fields[1], sourceAbsPath, lineNumber)
+		if err != nil {
+			return err
+		}
+		reloads = append(reloads, reload)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reloads = append(s.reloads, reloads...)
+	return nil
+}
+
+// addValidatorPatterns adds the validators in the .chezmoivalidate file at
+// sourceAbsPath to s.
+func (s *SourceState) addValidatorPatterns(sourceAbsPath AbsPath, sourceRelPath SourceRelPath) error {
+	data, err := s.executeTemplate(sourceAbsPath)
+	if err != nil {
+		return err
+	}
+
+	dir, err := sourceRelPath.Dir().TargetRelPath("")
+	if err != nil {
+		return err
+	}
+	var validatorPatterns []*validatorPattern
+	lineNumber := 0
+	for line := range bytes.Lines(data) {
+		lineNumber++
+		// Only whole lines are comments as commands may contain #.
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := whitespaceRx.Split(string(line), 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: missing format or command", sourceAbsPath, lineNumber)
+		}
+		pattern := dir.JoinString(fields[0]).String()
+		validatorPattern, err := newValidatorPattern(pattern, fields[1], sourceAbsPath, lineNumber)
+		if err != nil {
+			return err
+		}
+		validatorPatterns = append(validatorPatterns, validatorPattern)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.validatorPatterns = append(s.validatorPatterns, validatorPatterns...)
+	return nil
+}
+
+// addTemplateData adds all template data in sourceAbsPath, which is in the
+// source directory dirRelPath, to s. Template data in subdirectories of the
+// source directory are only visible to templates in dirRelPath and its
+// subdirectories.
+func (s *SourceState) addTemplateData(sourceAbsPath AbsPath, dirRelPath RelPath) error {
 	format, err := FormatFromAbsPath(sourceAbsPath)
 	if err != nil {
 		return err
@@ -1554,18 +1939,31 @@ func (s *SourceState) addTemplateData(sourceAbsPath AbsPath) error {
 	if err := format.Unmarshal(data, &templateData); err != nil {
 		return fmt.Errorf("%s: %w", sourceAbsPath, err)
 	}
+	s.mergeTemplateData(templateData, dirRelPath)
+	return nil
+}
+
+// mergeTemplateData merges templateData, read from the source directory
+// dirRelPath, into s.
+func (s *SourceState) mergeTemplateData(templateData map[string]any, dirRelPath RelPath) {
 	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if dirRelPath != DotRelPath {
+		if s.localTemplateData[dirRelPath] == nil {
+			s.localTemplateData[dirRelPath] = make(map[string]any)
+		}
+		RecursiveMerge(s.localTemplateData[dirRelPath], templateData)
+		return
+	}
 	RecursiveMerge(s.userTemplateData, templateData)
 	// Clear the cached template data, as the change to the user template data
 	// means that the cached value is now invalid.
 	s.templateData = nil
-	s.mutex.Unlock()
-	return nil
 }
 
 // addTemplateDataDir adds all template data in the directory sourceAbsPath to
-// s.
-func (s *SourceState) addTemplateDataDir(sourceAbsPath AbsPath, fileInfo fs.FileInfo) error {
+// s, scoped to dirRelPath.
+func (s *SourceState) addTemplateDataDir(sourceAbsPath AbsPath, fileInfo fs.FileInfo, dirRelPath RelPath) error {
 	walkFunc := func(dataAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
 		if dataAbsPath == sourceAbsPath {
 			return nil
@@ -1584,7 +1982,7 @@ func (s *SourceState) addTemplateDataDir(sourceAbsPath AbsPath, fileInfo fs.File
 			}
 			return nil
 		case fileInfo.Mode().IsRegular():
-			return s.addTemplateData(dataAbsPath)
+			return s.addTemplateData(dataAbsPath, dirRelPath)
 		case fileInfo.IsDir():
 			return nil
 		default:
@@ -1627,6 +2025,7 @@ func (s *SourceState) addTemplatesDir(ctx context.Context, templatesDirAbsPath A
 			tmpl, err := ParseTemplate(name, contents, TemplateOptions{
 				Funcs:   s.templateFuncs,
 				Options: slices.Clone(s.templateOptions),
+				Tracer:  s.templateTracer,
 			})
 			if err != nil {
 				return err
@@ -1653,9 +2052,11 @@ func (s *SourceState) executeTemplate(templateAbsPath AbsPath) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	sourceLayerAbsPath := s.sourceLayer(templateAbsPath)
 	return s.ExecuteTemplateData(ExecuteTemplateDataOptions{
-		NameRelPath: templateAbsPath.MustTrimDirPrefix(s.sourceDirAbsPath),
-		Data:        data,
+		NameRelPath:        templateAbsPath.MustTrimDirPrefix(sourceLayerAbsPath),
+		SourceLayerAbsPath: sourceLayerAbsPath,
+		Data:               data,
 	})
 }
 
@@ -1907,6 +2308,7 @@ func (s *SourceState) newSourceStateDir(absPath AbsPath, sourceRelPath SourceRel
 // file with the value of sourceContentsFunc if the file does not already exist,
 // or returns the actual file's contents unchanged if the file already exists.
 func (s *SourceState) newCreateTargetStateEntryFunc(
+	sourceAbsPath AbsPath,
 	sourceRelPath SourceRelPath,
 	fileAttr FileAttr,
 	sourceContentsFunc func() ([]byte, error),
@@ -1924,9 +2326,10 @@ func (s *SourceState) newCreateTargetStateEntryFunc(
 				}
 				if fileAttr.Template {
 					contents, err = s.ExecuteTemplateData(ExecuteTemplateDataOptions{
-						NameRelPath: sourceRelPath.RelPath(),
-						Data:        contents,
-						DestAbsPath: destAbsPath,
+						NameRelPath:        sourceRelPath.RelPath(),
+						SourceLayerAbsPath: s.sourceLayer(sourceAbsPath),
+						Data:               contents,
+						DestAbsPath:        destAbsPath,
 					})
 					if err != nil {
 						return nil, err
@@ -1953,6 +2356,7 @@ func (s *SourceState) newCreateTargetStateEntryFunc(
 // newFileTargetStateEntryFunc returns a targetStateEntryFunc that returns a
 // file with the contents of the value of sourceContentsFunc.
 func (s *SourceState) newFileTargetStateEntryFunc(
+	sourceAbsPath AbsPath,
 	sourceRelPath SourceRelPath,
 	fileAttr FileAttr,
 	sourceContentsFunc func() ([]byte, error),
@@ -1965,7 +2369,7 @@ func (s *SourceState) newFileTargetStateEntryFunc(
 			case isEmpty(contents) && !fileAttr.Empty:
 				return &TargetStateRemove{}, nil
 			default:
-				linkname := normalizeLinkname(s.sourceDirAbsPath.Join(sourceRelPath.RelPath()).String())
+				linkname := normalizeLinkname(sourceAbsPath.String())
 				return &TargetStateSymlink{
 					linknameFunc: eagerNoErr(linkname),
 					sourceAttr: SourceAttr{
@@ -1974,21 +2378,33 @@ func (s *SourceState) newFileTargetStateEntryFunc(
 				}, nil
 			}
 		}
+		// validators is set when the contents are executed, as template
+		// directives can add validators.
+		var validators []Validator
 		executedContentsFunc := sync.OnceValues(func() ([]byte, error) {
 			contents, err := sourceContentsFunc()
 			if err != nil {
 				return nil, err
 			}
 			if fileAttr.Template {
+				var templateOptions TemplateOptions
+				if _, err := templateOptions.parseAndRemoveDirectives(contents); err != nil {
+					return nil, err
+				}
+				validators = templateOptions.Validators
 				contents, err = s.ExecuteTemplateData(ExecuteTemplateDataOptions{
-					NameRelPath: sourceRelPath.RelPath(),
-					Data:        contents,
-					DestAbsPath: destAbsPath,
+					NameRelPath:        sourceRelPath.RelPath(),
+					SourceLayerAbsPath: s.sourceLayer(sourceAbsPath),
+					Data:               contents,
+					DestAbsPath:        destAbsPath,
 				})
 				if err != nil {
 					return nil, err
 				}
 			}
+			if targetRelPath, err := destAbsPath.TrimDirPrefix(s.destDirAbsPath); err == nil {
+				validators = append(validators, s.matchingValidators(targetRelPath)...)
+			}
 			return contents, nil
 		})
 		return &TargetStateFile{
@@ -2000,6 +2416,9 @@ func (s *SourceState) newFileTargetStateEntryFunc(
 				Encrypted: fileAttr.Encrypted,
 				Template:  fileAttr.Template,
 			},
+			validateFunc: func(contents []byte) error {
+				return s.validate(destAbsPath, contents, validators)
+			},
 		}, nil
 	}
 }
@@ -2007,6 +2426,7 @@ func (s *SourceState) newFileTargetStateEntryFunc(
 // newModifyTargetStateEntryFunc returns a targetStateEntryFunc that returns a
 // file with the contents modified by running the sourceLazyContents script.
 func (s *SourceState) newModifyTargetStateEntryFunc(
+	sourceAbsPath AbsPath,
 	sourceRelPath SourceRelPath,
 	fileAttr FileAttr,
 	contentsFunc func() ([]byte, error),
@@ -2031,9 +2451,10 @@ func (s *SourceState) newModifyTargetStateEntryFunc(
 			}
 			if fileAttr.Template {
 				modifierContents, err = s.ExecuteTemplateData(ExecuteTemplateDataOptions{
-					NameRelPath: sourceRelPath.RelPath(),
-					Data:        modifierContents,
-					DestAbsPath: destAbsPath,
+					NameRelPath:        sourceRelPath.RelPath(),
+					SourceLayerAbsPath: s.sourceLayer(sourceAbsPath),
+					Data:               modifierContents,
+					DestAbsPath:        destAbsPath,
 				})
 				if err != nil {
 					return nil, err
@@ -2050,10 +2471,14 @@ func (s *SourceState) newModifyTargetStateEntryFunc(
 			if matches := modifyTemplateRx.FindAllSubmatchIndex(modifierContents, -1); matches != nil {
 				sourceFile := sourceRelPath.String()
 				templateContents := removeMatches(modifierContents, matches)
+				if err := s.validateTemplateDataFunc(); err != nil {
+					return nil, err
+				}
 				var tmpl *Template
 				tmpl, err = ParseTemplate(sourceFile, templateContents, TemplateOptions{
-					Funcs:   s.templateFuncs,
+					Funcs:   s.sourceLayerTemplateFuncs(s.sourceLayer(sourceAbsPath)),
 					Options: slices.Clone(s.templateOptions),
+					Tracer:  s.templateTracer,
 				})
 				if err != nil {
 					return nil, err
@@ -2061,7 +2486,7 @@ func (s *SourceState) newModifyTargetStateEntryFunc(
 
 				// Temporarily set .chezmoi.stdin to the current contents and
 				// .chezmoi.sourceFile to the name of the template.
-				templateData := s.TemplateData()
+				templateData := s.TemplateDataInDir(sourceRelPath.RelPath().Dir())
 				if chezmoiTemplateData, ok := templateData["chezmoi"].(map[string]any); ok {
 					chezmoiTemplateData["stdin"] = string(currentContents)
 					chezmoiTemplateData["sourceFile"] = sourceRelPath.RelPath().String()
@@ -2128,6 +2553,7 @@ func (s *SourceState) newRemoveTargetStateEntryFunc() TargetStateEntryFunc {
 // newScriptTargetStateEntryFunc returns a targetStateEntryFunc that returns a
 // script with sourceLazyContents.
 func (s *SourceState) newScriptTargetStateEntryFunc(
+	sourceAbsPath AbsPath,
 	sourceRelPath SourceRelPath,
 	fileAttr FileAttr,
 	targetRelPath RelPath,
@@ -2142,9 +2568,10 @@ func (s *SourceState) newScriptTargetStateEntryFunc(
 			}
 			if fileAttr.Template {
 				contents, err = s.ExecuteTemplateData(ExecuteTemplateDataOptions{
-					NameRelPath: sourceRelPath.RelPath(),
-					Data:        contents,
-					DestAbsPath: destAbsPath,
+					NameRelPath:        sourceRelPath.RelPath(),
+					SourceLayerAbsPath: s.sourceLayer(sourceAbsPath),
+					Data:               contents,
+					DestAbsPath:        destAbsPath,
 				})
 				if err != nil {
 					return nil, err
@@ -2169,6 +2596,7 @@ func (s *SourceState) newScriptTargetStateEntryFunc(
 // newSymlinkTargetStateEntryFunc returns a targetStateEntryFunc that returns a
 // symlink with the linkname sourceLazyContents.
 func (s *SourceState) newSymlinkTargetStateEntryFunc(
+	sourceAbsPath AbsPath,
 	sourceRelPath SourceRelPath,
 	fileAttr FileAttr,
 	contentsFunc func() ([]byte, error),
@@ -2181,9 +2609,10 @@ func (s *SourceState) newSymlinkTargetStateEntryFunc(
 			}
 			if fileAttr.Template {
 				linknameBytes, err = s.ExecuteTemplateData(ExecuteTemplateDataOptions{
-					NameRelPath: sourceRelPath.RelPath(),
-					Data:        linknameBytes,
-					DestAbsPath: destAbsPath,
+					NameRelPath:        sourceRelPath.RelPath(),
+					SourceLayerAbsPath: s.sourceLayer(sourceAbsPath),
+					Data:               linknameBytes,
+					DestAbsPath:        destAbsPath,
 				})
 				if err != nil {
 					return "", err
@@ -2207,7 +2636,7 @@ func (s *SourceState) newSourceStateFile(
 	targetRelPath RelPath,
 ) (RelPath, *SourceStateFile) {
 	contentsFunc := sync.OnceValues(func() ([]byte, error) {
-		contents, err := s.system.ReadFile(s.sourceDirAbsPath.Join(sourceRelPath.RelPath()))
+		contents, err := s.system.ReadFile(absPath)
 		if err != nil {
 			return nil, err
 		}
@@ -2223,9 +2652,9 @@ func (s *SourceState) newSourceStateFile(
 	var targetStateEntryFunc TargetStateEntryFunc
 	switch fileAttr.Type {
 	case SourceFileTypeCreate:
-		targetStateEntryFunc = s.newCreateTargetStateEntryFunc(sourceRelPath, fileAttr, contentsFunc)
+		targetStateEntryFunc = s.newCreateTargetStateEntryFunc(absPath, sourceRelPath, fileAttr, contentsFunc)
 	case SourceFileTypeFile:
-		targetStateEntryFunc = s.newFileTargetStateEntryFunc(sourceRelPath, fileAttr, contentsFunc)
+		targetStateEntryFunc = s.newFileTargetStateEntryFunc(absPath, sourceRelPath, fileAttr, contentsFunc)
 	case SourceFileTypeModify:
 		// If the target has an extension, determine if it indicates an
 		// interpreter to use.
@@ -2234,9 +2663,9 @@ func (s *SourceState) newSourceStateFile(
 			// For modify scripts, the script extension is not considered part
 			// of the target name, so remove it.
 			targetRelPath = targetRelPath.Slice(0, targetRelPath.Len()-len(extension)-1)
-			targetStateEntryFunc = s.newModifyTargetStateEntryFunc(sourceRelPath, fileAttr, contentsFunc, &interpreter)
+			targetStateEntryFunc = s.newModifyTargetStateEntryFunc(absPath, sourceRelPath, fileAttr, contentsFunc, &interpreter)
 		} else {
-			targetStateEntryFunc = s.newModifyTargetStateEntryFunc(sourceRelPath, fileAttr, contentsFunc, nil)
+			targetStateEntryFunc = s.newModifyTargetStateEntryFunc(absPath, sourceRelPath, fileAttr, contentsFunc, nil)
 		}
 	case SourceFileTypeRemove:
 		targetStateEntryFunc = s.newRemoveTargetStateEntryFunc()
@@ -2246,6 +2675,7 @@ func (s *SourceState) newSourceStateFile(
 		extension := strings.ToLower(strings.TrimPrefix(targetRelPath.Ext(), "."))
 		if interpreter, ok := s.interpreters[extension]; ok {
 			targetStateEntryFunc = s.newScriptTargetStateEntryFunc(
+				absPath,
 				sourceRelPath,
 				fileAttr,
 				targetRelPath,
@@ -2253,10 +2683,10 @@ func (s *SourceState) newSourceStateFile(
 				&interpreter,
 			)
 		} else {
-			targetStateEntryFunc = s.newScriptTargetStateEntryFunc(sourceRelPath, fileAttr, targetRelPath, contentsFunc, nil)
+			targetStateEntryFunc = s.newScriptTargetStateEntryFunc(absPath, sourceRelPath, fileAttr, targetRelPath, contentsFunc, nil)
 		}
 	case SourceFileTypeSymlink:
-		targetStateEntryFunc = s.newSymlinkTargetStateEntryFunc(sourceRelPath, fileAttr, contentsFunc)
+		targetStateEntryFunc = s.newSymlinkTargetStateEntryFunc(absPath, sourceRelPath, fileAttr, contentsFunc)
 	default:
 		panic(fmt.Sprintf("%d: unsupported type", fileAttr.Type))
 	}
@@ -2953,7 +3383,7 @@ func (s *SourceState) readScriptsDir(ctx context.Context, scriptsDirAbsPath AbsP
 		}
 
 		sourceRelPath := SourceRelPath{
-			relPath: sourceAbsPath.MustTrimDirPrefix(s.sourceDirAbsPath),
+			relPath: sourceAbsPath.MustTrimDirPrefix(s.sourceLayer(sourceAbsPath)),
 			isDir:   fileInfo.IsDir(),
 		}
 		parentSourceRelPath, sourceName := sourceRelPath.Split()
@@ -3024,6 +3454,54 @@ func (s *SourceState) readVersionFile(sourceAbsPath AbsPath) error {
 	return nil
 }
 
+// addSourceDirAbsPath returns the directory in which the source state entry
+// for targetRelPath is added. This is the enabled module in the source
+// directory that contains the entry of targetRelPath or of its closest managed
+// parent directory, or the source directory.
+func (s *SourceState) addSourceDirAbsPath(targetRelPath RelPath) AbsPath {
+	for relPath := targetRelPath; relPath != DotRelPath; relPath = relPath.Dir() {
+		sourceStateEntry := s.root.Get(relPath)
+		if sourceStateEntry == nil {
+			continue
+		}
+		if layerAbsPath := s.sourceLayer(s.SourceAbsPath(sourceStateEntry)); layerAbsPath.HasDirPrefix(s.sourceDirAbsPath) {
+			return layerAbsPath
+		}
+		break
+	}
+	return s.sourceDirAbsPath
+}
+
+// sourceLayer returns the source directory layer or enabled module that
+// contains absPath. If neither contains absPath then it returns the source
+// directory.
+func (s *SourceState) sourceLayer(absPath AbsPath) AbsPath {
+	var result AbsPath
+	for _, layerAbsPath := range s.sourceLayers() {
+		if absPath.HasDirPrefix(layerAbsPath) && layerAbsPath.Len() > result.Len() {
+			result = layerAbsPath
+		}
+	}
+	for _, module := range s.modules {
+		if module.enabled && absPath.HasDirPrefix(module.absPath) && module.absPath.Len() > result.Len() {
+			result = module.absPath
+		}
+	}
+	if result.IsEmpty() {
+		return s.sourceDirAbsPath
+	}
+	return result
+}
+
+// sourceLayers returns the source directory layers in the order in which they
+// are read.
+func (s *SourceState) sourceLayers() []AbsPath {
+	if len(s.sourceLayerAbsPaths) == 0 {
+		return []AbsPath{s.sourceDirAbsPath}
+	}
+	return s.sourceLayerAbsPaths
+}
+
 // sourceStateEntry returns a new SourceStateEntry based on actualStateEntry.
 func (s *SourceState) sourceStateEntry(
 	actualStateEntry ActualStateEntry,
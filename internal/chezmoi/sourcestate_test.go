@@ -969,6 +969,7 @@ func TestSourceStateRead(t *testing.T) {
 							contentsFunc:       eagerNoErr([]byte("# contents of .file\n")),
 							contentsSHA256Func: eagerNoErr(sha256.Sum256([]byte("# contents of .file\n"))),
 							perm:               0o666 &^ chezmoitest.Umask,
+							validateFunc:       noValidate,
 						},
 					},
 				}),
@@ -1032,6 +1033,7 @@ func TestSourceStateRead(t *testing.T) {
 							contentsFunc:       eagerNoErr([]byte("# contents of .file\n")),
 							contentsSHA256Func: eagerNoErr(sha256.Sum256([]byte("# contents of .file\n"))),
 							perm:               fs.ModePerm &^ chezmoitest.Umask,
+							validateFunc:       noValidate,
 						},
 					},
 				}),
@@ -1163,6 +1165,7 @@ func TestSourceStateRead(t *testing.T) {
 							contentsFunc:       eagerNoErr([]byte("# contents of .dir/file\n")),
 							contentsSHA256Func: eagerNoErr(sha256.Sum256([]byte("# contents of .dir/file\n"))),
 							perm:               0o666 &^ chezmoitest.Umask,
+							validateFunc:       noValidate,
 						},
 					},
 				}),
@@ -1262,6 +1265,7 @@ func TestSourceStateRead(t *testing.T) {
 							contentsFunc:       eagerNoErr([]byte("# contents of dir/file1\n")),
 							contentsSHA256Func: eagerNoErr(sha256.Sum256([]byte("# contents of dir/file1\n"))),
 							perm:               0o666 &^ chezmoitest.Umask,
+							validateFunc:       noValidate,
 						},
 					},
 					NewRelPath("dir/file2"): &SourceStateRemove{
@@ -1784,6 +1788,58 @@ func TestSourceStateReadExternalCache(t *testing.T) {
 	})
 }
 
+func TestSourceStateReadLayers(t *testing.T) {
+	chezmoitest.WithTestFS(t, map[string]any{
+		"/home/user/base": map[string]any{
+			".chezmoidata.yaml": chezmoitest.JoinLines(
+				"email: base@example.com",
+				"name: base",
+			),
+			"dot_dir/file": "# base\n",
+			"dot_file_a":   "# base\n",
+			"dot_file_b":   "# base\n",
+			"dot_file_c":   "# base\n",
+		},
+		"/home/user/.local/share/chezmoi": map[string]any{
+			".chezmoidata.yaml":  "email: me@example.com\n",
+			".chezmoiignore":     ".file_c\n",
+			"private_dot_file_b": "# overlay\n",
+		},
+	}, func(fileSystem vfs.FS) {
+		system := NewRealSystem(fileSystem)
+		s := NewSourceState(
+			WithBaseSystem(system),
+			WithDestDir(NewAbsPath("/home/user")),
+			WithSourceDir(NewAbsPath("/home/user/.local/share/chezmoi")),
+			WithSourceLayers([]AbsPath{
+				NewAbsPath("/home/user/base"),
+				NewAbsPath("/home/user/.local/share/chezmoi"),
+			}),
+			WithSystem(system),
+		)
+		assert.NoError(t, s.Read(t.Context(), nil))
+
+		assert.Equal(t, []RelPath{
+			NewRelPath(".dir"),
+			NewRelPath(".dir/file"),
+			NewRelPath(".file_a"),
+			NewRelPath(".file_b"),
+		}, s.TargetRelPaths())
+		for targetRelPath, expectedSourceAbsPath := range map[string]string{
+			".dir":      "/home/user/base/dot_dir",
+			".dir/file": "/home/user/base/dot_dir/file",
+			".file_a":   "/home/user/base/dot_file_a",
+			".file_b":   "/home/user/.local/share/chezmoi/private_dot_file_b",
+		} {
+			assert.Equal(t, NewAbsPath(expectedSourceAbsPath), s.SourceAbsPath(s.MustEntry(NewRelPath(targetRelPath))))
+		}
+		assert.Equal(t, map[string]any{
+			"email": "me@example.com",
+			"name":  "base",
+		}, s.userTemplateData)
+	})
+}
+
 func TestSourceStateTargetRelPaths(t *testing.T) {
 	for _, tc := range []struct {
 		name                   string
@@ -2196,6 +2252,11 @@ func requireEvaluateAll(t *testing.T, s *SourceState, destSystem System) {
 	assert.NoError(t, err)
 }
 
+// noValidate is a TargetStateFile validateFunc that accepts all contents.
+func noValidate([]byte) error {
+	return nil
+}
+
 func withEntries(sourceEntries map[RelPath]SourceStateEntry) SourceStateOption {
 	return func(s *SourceState) {
 		s.root = SourceStateEntryTreeNode{}
@@ -15,8 +15,13 @@ import (
 )
 
 type RunScriptOptions struct {
+	ApplyContext  *ApplyContext
 	Interpreter   *Interpreter
 	Condition     ScriptCondition
+	DryRun        ScriptDryRun
+	Env           []string
+	Args          []string
+	WorkDir       AbsPath
 	SourceRelPath SourceRelPath
 }
 
@@ -191,11 +196,13 @@ func WalkSourceDir(system System, sourceDirAbsPath AbsPath, walkFunc WalkFunc) e
 // source directory. More negative values are visited first. Entries with the
 // same order are visited alphabetically. The default order is zero.
 var sourceDirEntryOrder = map[string]int{
-	VersionName:        -3,
-	dataName + ".json": -2,
-	dataName + ".toml": -2,
-	dataName + ".yaml": -2,
-	TemplatesDirName:   -1,
+	VersionName:           -3,
+	DataSchemaName:        -2,
+	dataName + ".json":    -2,
+	dataName + ".toml":    -2,
+	dataName + ".yaml":    -2,
+	dataGeneratorsDirName: -1,
+	TemplatesDirName:      -1,
 }
 
 // walkSourceDirHelper is a helper function for WalkSourceDir.
@@ -8,15 +8,25 @@ import (
 	"io/fs"
 	"os/exec"
 	"runtime"
+	"slices"
 	"time"
 )
 
+// TargetStateApplyOptions are per-call options to TargetStateEntry.Apply.
+type TargetStateApplyOptions struct {
+	ApplyContext   *ApplyContext
+	HomeDirAbsPath AbsPath
+	ScriptEnv      []string
+	Validate       bool
+}
+
 // A TargetStateEntry represents the state of an entry in the target state.
 type TargetStateEntry interface {
 	Apply(
 		system System,
 		persistentState PersistentState,
 		actualStateEntry ActualStateEntry,
+		options TargetStateApplyOptions,
 	) (bool, error)
 	EntryState(umask fs.FileMode) (*EntryState, error)
 	Evaluate() error
@@ -47,6 +57,7 @@ type TargetStateFile struct {
 	overwrite          bool
 	perm               fs.FileMode
 	sourceAttr         SourceAttr
+	validateFunc       func(contents []byte) error
 }
 
 // A TargetStateRemove represents the absence of an entry in the target state.
@@ -87,6 +98,7 @@ func (t *TargetStateModifyDirWithCmd) Apply(
 	system System,
 	persistentState PersistentState,
 	actualStateEntry ActualStateEntry,
+	options TargetStateApplyOptions,
 ) (bool, error) {
 	if _, ok := actualStateEntry.(*ActualStateDir); !ok {
 		if err := actualStateEntry.Remove(system); err != nil {
@@ -157,6 +169,7 @@ func (t *TargetStateDir) Apply(
 	system System,
 	persistentState PersistentState,
 	actualStateEntry ActualStateEntry,
+	options TargetStateApplyOptions,
 ) (bool, error) {
 	if actualStateDir, ok := actualStateEntry.(*ActualStateDir); ok {
 		if runtime.GOOS == "windows" || actualStateDir.perm == t.perm {
@@ -198,6 +211,7 @@ func (t *TargetStateFile) Apply(
 	system System,
 	persistentState PersistentState,
 	actualStateEntry ActualStateEntry,
+	options TargetStateApplyOptions,
 ) (bool, error) {
 	contents, err := t.Contents()
 	if err != nil {
@@ -228,8 +242,16 @@ func (t *TargetStateFile) Apply(
 			}
 			return true, system.Chmod(actualStateFile.Path(), t.perm)
 		}
-	} else if err := actualStateEntry.Remove(system); err != nil {
-		return false, err
+	}
+	if options.Validate && t.validateFunc != nil {
+		if err := t.validateFunc(contents); err != nil {
+			return false, err
+		}
+	}
+	if _, ok := actualStateEntry.(*ActualStateFile); !ok {
+		if err := actualStateEntry.Remove(system); err != nil {
+			return false, err
+		}
 	}
 	return true, system.WriteFile(actualStateEntry.Path(), contents, t.perm)
 }
@@ -299,6 +321,7 @@ func (t *TargetStateRemove) Apply(
 	system System,
 	persistentState PersistentState,
 	actualStateEntry ActualStateEntry,
+	options TargetStateApplyOptions,
 ) (bool, error) {
 	if _, ok := actualStateEntry.(*ActualStateAbsent); ok {
 		return false, nil
@@ -333,6 +356,7 @@ func (t *TargetStateScript) Apply(
 	system System,
 	persistentState PersistentState,
 	actualStateEntry ActualStateEntry,
+	options TargetStateApplyOptions,
 ) (bool, error) {
 	skipApply, err := t.SkipApply(persistentState, actualStateEntry.Path())
 	if err != nil {
@@ -353,10 +377,24 @@ func (t *TargetStateScript) Apply(
 	}
 	runAt := time.Now().UTC()
 	if !isEmpty(contents) {
-		if err := system.RunScript(t.name, actualStateEntry.Path().Dir(), contents, RunScriptOptions{
+		scriptDirectives, err := parseScriptDirectives(contents)
+		if err != nil {
+			return false, err
+		}
+		dirAbsPath := actualStateEntry.Path().Dir()
+		workDirAbsPath, err := scriptDirectives.workDirAbsPath(dirAbsPath, options.HomeDirAbsPath)
+		if err != nil {
+			return false, err
+		}
+		if err := system.RunScript(t.name, dirAbsPath, contents, RunScriptOptions{
+			ApplyContext:  options.ApplyContext,
+			Args:          scriptDirectives.Args,
 			Condition:     t.condition,
+			DryRun:        scriptDirectives.DryRun,
+			Env:           slices.Concat(scriptDirectives.Env, options.ScriptEnv),
 			Interpreter:   t.interpreter,
 			SourceRelPath: t.sourceRelPath,
+			WorkDir:       workDirAbsPath,
 		}); err != nil {
 			return false, err
 		}
@@ -464,6 +502,7 @@ func (t *TargetStateSymlink) Apply(
 	system System,
 	persistentState PersistentState,
 	actualStateEntry ActualStateEntry,
+	options TargetStateApplyOptions,
 ) (bool, error) {
 	linkname, err := t.Linkname()
 	if err != nil {
@@ -100,7 +100,7 @@ func TestTargetStateEntryApply(t *testing.T) {
 				assert.NoError(t, err)
 
 				// Apply the target state entry.
-				_, err = targetState.Apply(system, nil, actualStateEntry)
+				_, err = targetState.Apply(system, nil, actualStateEntry, TargetStateApplyOptions{})
 				assert.NoError(t, err)
 
 				// Verify that the actual state entry matches the desired
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"text/template"
@@ -19,9 +20,11 @@ import (
 
 // A Template extends [text/template.Template] with support for directives.
 type Template struct {
-	name     string
-	template *template.Template
-	options  TemplateOptions
+	name           string
+	template       *template.Template
+	funcs          template.FuncMap
+	options        TemplateOptions
+	directiveLines map[string][]int
 }
 
 // TemplateOptions are template options that can be set with directives.
@@ -33,6 +36,8 @@ type TemplateOptions struct {
 	LineEnding     string
 	RightDelimiter string
 	Options        []string
+	Tracer         *TemplateTracer
+	Validators     []Validator
 }
 
 // ParseTemplate parses a template named name from data with the given funcs and
@@ -82,17 +87,24 @@ func ParseTemplate(name string, data []byte, options TemplateOptions) (*Template
 	if err != nil {
 		return nil, err
 	}
-	return &Template{
-		name:     name,
-		template: tmpl,
-		options:  options,
-	}, nil
+	t := &Template{
+		name:           name,
+		template:       tmpl,
+		funcs:          funcs,
+		options:        options,
+		directiveLines: make(map[string][]int),
+	}
+	if options.Tracer != nil {
+		t.directiveLines[name] = directiveLines(data)
+	}
+	return t, nil
 }
 
 // AddParseTree adds tmpl's parse tree to t.
 func (t *Template) AddParseTree(tmpl *Template) (*Template, error) {
 	var err error
 	t.template, err = t.template.AddParseTree(tmpl.name, tmpl.template.Tree)
+	maps.Copy(t.directiveLines, tmpl.directiveLines)
 	return t, err
 }
 
@@ -107,9 +119,18 @@ func (t *Template) Execute(data any) ([]byte, error) {
 		}
 	}
 
+	tmpl := t.template
+	restoreFuncNames := func(err error) error { return err }
+	if t.options.Tracer != nil {
+		var err error
+		if tmpl, restoreFuncNames, err = t.options.Tracer.traceTemplate(t); err != nil {
+			return nil, err
+		}
+	}
+
 	var builder strings.Builder
-	if err := t.template.ExecuteTemplate(&builder, t.name, data); err != nil {
-		return nil, err
+	if err := tmpl.ExecuteTemplate(&builder, t.name, data); err != nil {
+		return nil, restoreFuncNames(err)
 	}
 
 	result := []byte(replaceLineEndings(builder.String(), t.options.LineEnding))
@@ -186,6 +207,17 @@ func (o *TemplateOptions) parseAndRemoveDirectives(data []byte) ([]byte, error)
 				o.RightDelimiter = value
 			case "missing-key":
 				o.Options = append(o.Options, "missingkey="+value)
+			case "validate":
+				if !slices.Contains(validateFormats, value) {
+					return nil, fmt.Errorf("%s: unknown validate format", value)
+				}
+				o.Validators = append(o.Validators, Validator{
+					Format: value,
+				})
+			case "validate-command":
+				o.Validators = append(o.Validators, Validator{
+					Command: value,
+				})
 			}
 		}
 	}
@@ -193,6 +225,16 @@ func (o *TemplateOptions) parseAndRemoveDirectives(data []byte) ([]byte, error)
 	return removeMatches(data, directiveMatches), nil
 }
 
+// directiveLines returns the one-based line numbers of the lines in data that
+// contain directives.
+func directiveLines(data []byte) []int {
+	var lines []int
+	for _, directiveMatch := range templateDirectiveRx.FindAllIndex(data, -1) {
+		lines = append(lines, bytes.Count(data[:directiveMatch[0]], []byte{'\n'})+1)
+	}
+	return lines
+}
+
 // removeMatches returns data with matchesIndexes removed.
 func removeMatches(data []byte, matchesIndexes [][]int) []byte {
 	slices := make([][]byte, len(matchesIndexes)+1)
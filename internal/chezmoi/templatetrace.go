@@ -0,0 +1,497 @@
+package chezmoi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoiset"
+)
+
+// templateTraceMaskedValue replaces secret values in traces.
+const templateTraceMaskedValue = "********"
+
+// templateTraceMaxValueLen is the maximum length of a value in a trace.
+const templateTraceMaxValueLen = 80
+
+// templateTraceMinSecretLen is the minimum length of a string returned by a
+// secret template function for it to be masked in other values. Shorter strings
+// are too likely to occur by chance.
+const templateTraceMinSecretLen = 6
+
+var templateTraceFuncNameRx = regexp.MustCompile(`chezmoiTrace\d+ ?`)
+
+// A TemplateTraceEventType is the type of a template trace event.
+type TemplateTraceEventType string
+
+// Template trace event types.
+const (
+	TemplateTraceEventTypeCall     TemplateTraceEventType = "call"
+	TemplateTraceEventTypeLookup   TemplateTraceEventType = "lookup"
+	TemplateTraceEventTypeTemplate TemplateTraceEventType = "template"
+)
+
+// A TemplateTraceEvent is an event recorded while executing a template.
+type TemplateTraceEvent struct {
+	Type     TemplateTraceEventType
+	Location string
+	Depth    int
+	Name     string
+	Args     []any
+	Value    any
+	Err      error
+	Duration time.Duration
+	secret   bool
+}
+
+// A TemplateTracer records the included templates, data lookups, and function
+// calls made while executing templates.
+type TemplateTracer struct {
+	mutex           sync.Mutex
+	secretFuncNames chezmoiset.Set[string]
+	secrets         chezmoiset.Set[string]
+	events          []*TemplateTraceEvent
+	depth           int
+}
+
+// NewTemplateTracer returns a new TemplateTracer. The results of the template
+// functions in secretFuncNames are masked.
+func NewTemplateTracer(secretFuncNames []string) *TemplateTracer {
+	return &TemplateTracer{
+		secretFuncNames: chezmoiset.New(secretFuncNames...),
+		secrets:         chezmoiset.New[string](),
+	}
+}
+
+// Events returns the events recorded by t.
+func (t *TemplateTracer) Events() []*TemplateTraceEvent {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return slices.Clone(t.events)
+}
+
+// WriteTo writes the events recorded by t to w, one per line.
+func (t *TemplateTracer) WriteTo(w io.Writer) (int64, error) {
+	var builder strings.Builder
+	for _, event := range t.Events() {
+		builder.WriteString(strings.Repeat("  ", event.Depth))
+		builder.WriteString(event.Location)
+		builder.WriteString(": ")
+		builder.WriteString(string(event.Type))
+		builder.WriteByte(' ')
+		switch event.Type {
+		case TemplateTraceEventTypeCall:
+			builder.WriteString(event.Name)
+			for _, arg := range event.Args {
+				builder.WriteByte(' ')
+				builder.WriteString(t.formatValue(arg))
+			}
+			if event.Err != nil {
+				builder.WriteString(" error: ")
+				builder.WriteString(event.Err.Error())
+			} else {
+				builder.WriteString(" = ")
+				if event.secret {
+					builder.WriteString(templateTraceMaskedValue)
+				} else {
+					builder.WriteString(t.formatValue(event.Value))
+				}
+			}
+			builder.WriteString(" (")
+			builder.WriteString(event.Duration.String())
+			builder.WriteByte(')')
+		case TemplateTraceEventTypeLookup:
+			builder.WriteString(event.Name)
+			builder.WriteString(" = ")
+			builder.WriteString(t.formatValue(event.Value))
+		case TemplateTraceEventTypeTemplate:
+			builder.WriteString(strconv.Quote(event.Name))
+		}
+		builder.WriteByte('\n')
+	}
+	n, err := io.WriteString(w, builder.String())
+	return int64(n), err
+}
+
+// addSecrets records all strings in value as secrets.
+func (t *TemplateTracer) addSecrets(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if !value.IsNil() {
+			t.addSecrets(value.Elem())
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			t.addSecrets(value.MapIndex(key))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			t.addSecrets(value.Index(i))
+		}
+	case reflect.String:
+		if s := value.String(); len(s) >= templateTraceMinSecretLen {
+			t.secrets.Add(s)
+		}
+	}
+}
+
+// beginCall records the start of a call to the template function name.
+func (t *TemplateTracer) beginCall(location, name string, args []any) *TemplateTraceEvent {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	event := &TemplateTraceEvent{
+		Type:     TemplateTraceEventTypeCall,
+		Location: location,
+		Depth:    t.depth,
+		Name:     name,
+		Args:     args,
+		secret:   t.secretFuncNames.Contains(name),
+	}
+	t.events = append(t.events, event)
+	t.depth++
+	return event
+}
+
+// endCall records the end of the call recorded in event.
+func (t *TemplateTracer) endCall(event *TemplateTraceEvent, results []reflect.Value, err error, duration time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.depth--
+	event.Duration = duration
+	event.Err = err
+	if len(results) > 0 {
+		event.Value = results[0].Interface()
+		if event.secret {
+			t.addSecrets(results[0])
+		}
+	}
+	if len(results) > 1 && event.Err == nil {
+		event.Err, _ = results[1].Interface().(error)
+	}
+}
+
+// formatValue returns value formatted for a trace, with any secrets masked.
+func (t *TemplateTracer) formatValue(value any) string {
+	var s string
+	switch value := value.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		s = value
+	case []byte:
+		s = string(value)
+	default:
+		if data, err := json.Marshal(value); err == nil {
+			s = string(data)
+		} else {
+			s = fmt.Sprint(value)
+		}
+	}
+
+	t.mutex.Lock()
+	for secret := range t.secrets.Elements() {
+		s = strings.ReplaceAll(s, secret, templateTraceMaskedValue)
+	}
+	t.mutex.Unlock()
+
+	if runes := []rune(s); len(runes) > templateTraceMaxValueLen {
+		s = string(runes[:templateTraceMaxValueLen-3]) + "..."
+	}
+	switch value.(type) {
+	case string, []byte:
+		return strconv.Quote(s)
+	default:
+		return s
+	}
+}
+
+// record records event.
+func (t *TemplateTracer) record(event *TemplateTraceEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	event.Depth = t.depth
+	t.events = append(t.events, event)
+}
+
+// A templateTraceError is an error from executing a traced template, with the
+// names of trace functions replaced by the names of the functions they trace.
+type templateTraceError struct {
+	err     error
+	message string
+}
+
+func (e *templateTraceError) Error() string {
+	return e.message
+}
+
+func (e *templateTraceError) Unwrap() error {
+	return e.err
+}
+
+// traceTemplate returns a copy of tmpl, with parse trees rewritten so that
+// executing it records events in t, and a function that restores the names of
+// traced functions in errors.
+func (t *TemplateTracer) traceTemplate(tmpl *Template) (*template.Template, func(error) error, error) {
+	clone, err := tmpl.template.Clone()
+	if err != nil {
+		return nil, nil, err
+	}
+	traceFuncs := make(template.FuncMap)
+	tracedFuncNames := make(map[string]string)
+	for _, associatedTemplate := range tmpl.template.Templates() {
+		if associatedTemplate.Tree == nil {
+			continue
+		}
+		tree := associatedTemplate.Tree.Copy()
+		rewriter := &templateTraceRewriter{
+			tracer:          t,
+			tree:            tree,
+			funcs:           tmpl.funcs,
+			traceFuncs:      traceFuncs,
+			tracedFuncNames: tracedFuncNames,
+			directiveLines:  tmpl.directiveLines[tree.ParseName],
+		}
+		rewriter.rewriteList(tree.Root)
+		if _, err := clone.AddParseTree(associatedTemplate.Name(), tree); err != nil {
+			return nil, nil, err
+		}
+	}
+	restoreFuncNames := func(err error) error {
+		if err == nil {
+			return nil
+		}
+		message := templateTraceFuncNameRx.ReplaceAllStringFunc(err.Error(), func(s string) string {
+			tracedFuncName := tracedFuncNames[strings.TrimSuffix(s, " ")]
+			if tracedFuncName == "" {
+				return ""
+			}
+			return tracedFuncName + strings.TrimPrefix(s, strings.TrimSuffix(s, " "))
+		})
+		return &templateTraceError{
+			err:     err,
+			message: message,
+		}
+	}
+	return clone.Funcs(traceFuncs), restoreFuncNames, nil
+}
+
+// A templateTraceRewriter rewrites a parse tree to record data lookups,
+// function calls, and template invocations in a TemplateTracer.
+type templateTraceRewriter struct {
+	tracer          *TemplateTracer
+	tree            *parse.Tree
+	funcs           template.FuncMap
+	traceFuncs      template.FuncMap
+	tracedFuncNames map[string]string
+	directiveLines  []int
+}
+
+// addTraceFunc adds f as a new template function that traces the template
+// function tracedFuncName, if any, and returns its name.
+func (r *templateTraceRewriter) addTraceFunc(f any, tracedFuncName string) string {
+	name := "chezmoiTrace" + strconv.Itoa(len(r.traceFuncs))
+	r.traceFuncs[name] = f
+	r.tracedFuncNames[name] = tracedFuncName
+	return name
+}
+
+// location returns the location of node in its source template. Line numbers
+// are adjusted for the lines containing directives that were removed before the
+// template was parsed.
+func (r *templateTraceRewriter) location(node parse.Node) string {
+	location, _ := r.tree.ErrorContext(node)
+	name, lineColumn, ok := cutLast(location, ':')
+	if !ok {
+		return location
+	}
+	name, lineStr, ok := cutLast(name, ':')
+	if !ok {
+		return location
+	}
+	line, err := strconv.Atoi(lineStr)
+	if err != nil {
+		return location
+	}
+	for _, directiveLine := range r.directiveLines {
+		if directiveLine <= line {
+			line++
+		}
+	}
+	return name + ":" + strconv.Itoa(line) + ":" + lineColumn
+}
+
+// newTraceCall returns a new pipeline that calls the template function
+// funcName with args.
+func (r *templateTraceRewriter) newTraceCall(pos parse.Pos, funcName string, args ...parse.Node) *parse.PipeNode {
+	return &parse.PipeNode{
+		NodeType: parse.NodePipe,
+		Pos:      pos,
+		Cmds: []*parse.CommandNode{
+			{
+				NodeType: parse.NodeCommand,
+				Pos:      pos,
+				Args:     append([]parse.Node{parse.NewIdentifier(funcName).SetTree(r.tree).SetPos(pos)}, args...),
+			},
+		},
+	}
+}
+
+func (r *templateTraceRewriter) rewriteList(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		r.rewriteNode(node)
+	}
+}
+
+func (r *templateTraceRewriter) rewriteNode(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ActionNode:
+		r.rewritePipe(node.Pipe)
+	case *parse.IfNode:
+		r.rewriteBranch(&node.BranchNode)
+	case *parse.ListNode:
+		r.rewriteList(node)
+	case *parse.RangeNode:
+		r.rewriteBranch(&node.BranchNode)
+	case *parse.TemplateNode:
+		r.rewriteTemplate(node)
+	case *parse.WithNode:
+		r.rewriteBranch(&node.BranchNode)
+	}
+}
+
+func (r *templateTraceRewriter) rewriteBranch(node *parse.BranchNode) {
+	r.rewritePipe(node.Pipe)
+	r.rewriteList(node.List)
+	r.rewriteList(node.ElseList)
+}
+
+// rewritePipe rewrites the data lookups and function calls in pipe.
+func (r *templateTraceRewriter) rewritePipe(pipe *parse.PipeNode) {
+	if pipe == nil {
+		return
+	}
+	for i, cmd := range pipe.Cmds {
+		for j, arg := range cmd.Args {
+			switch arg := arg.(type) {
+			case *parse.ChainNode:
+				if pipeNode, ok := arg.Node.(*parse.PipeNode); ok {
+					r.rewritePipe(pipeNode)
+				}
+				if r.isLookup(i, j, len(cmd.Args)) {
+					cmd.Args[j] = r.traceLookup(arg)
+				}
+			case *parse.FieldNode, *parse.VariableNode:
+				if r.isLookup(i, j, len(cmd.Args)) {
+					cmd.Args[j] = r.traceLookup(arg)
+				}
+			case *parse.IdentifierNode:
+				r.traceCall(arg)
+			case *parse.PipeNode:
+				r.rewritePipe(arg)
+			}
+		}
+	}
+}
+
+// isLookup returns whether a field, variable, or chain node at argument index j
+// of the command at index i in a pipeline, with numArgs arguments, is a data
+// lookup. Otherwise, it is a method call which receives arguments.
+func (r *templateTraceRewriter) isLookup(i, j, numArgs int) bool {
+	return j > 0 || i == 0 && numArgs == 1
+}
+
+// rewriteTemplate rewrites node to record the invocation of its template.
+func (r *templateTraceRewriter) rewriteTemplate(node *parse.TemplateNode) {
+	r.rewritePipe(node.Pipe)
+	location := r.location(node)
+	name := node.Name
+	funcName := r.addTraceFunc(func(args ...any) any {
+		r.tracer.record(&TemplateTraceEvent{
+			Type:     TemplateTraceEventTypeTemplate,
+			Location: location,
+			Name:     name,
+		})
+		if len(args) == 0 {
+			return nil
+		}
+		return args[0]
+	}, "")
+	var args []parse.Node
+	if node.Pipe != nil {
+		args = append(args, node.Pipe)
+	}
+	node.Pipe = r.newTraceCall(node.Pos, funcName, args...)
+}
+
+// traceCall rewrites node to record calls to its template function.
+func (r *templateTraceRewriter) traceCall(node *parse.IdentifierNode) {
+	name := node.Ident
+	funcValue := reflect.ValueOf(r.funcs[name])
+	if funcValue.Kind() != reflect.Func {
+		return
+	}
+	funcType := funcValue.Type()
+	location := r.location(node)
+	traceFunc := reflect.MakeFunc(funcType, func(args []reflect.Value) (results []reflect.Value) {
+		var argValues []any
+		for i, arg := range args {
+			if funcType.IsVariadic() && i == len(args)-1 {
+				for k := range arg.Len() {
+					argValues = append(argValues, arg.Index(k).Interface())
+				}
+			} else {
+				argValues = append(argValues, arg.Interface())
+			}
+		}
+		event := r.tracer.beginCall(location, name, argValues)
+		start := time.Now()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				r.tracer.endCall(event, nil, fmt.Errorf("%v", recovered), time.Since(start))
+				panic(recovered)
+			}
+			r.tracer.endCall(event, results, nil, time.Since(start))
+		}()
+		if funcType.IsVariadic() {
+			return funcValue.CallSlice(args)
+		}
+		return funcValue.Call(args)
+	})
+	node.Ident = r.addTraceFunc(traceFunc.Interface(), name)
+}
+
+// traceLookup returns a new pipeline that records the value of node.
+func (r *templateTraceRewriter) traceLookup(node parse.Node) parse.Node {
+	location := r.location(node)
+	name := node.String()
+	funcName := r.addTraceFunc(func(value any) any {
+		r.tracer.record(&TemplateTraceEvent{
+			Type:     TemplateTraceEventTypeLookup,
+			Location: location,
+			Name:     name,
+			Value:    value,
+		})
+		return value
+	}, "")
+	return r.newTraceCall(node.Position(), funcName, node)
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s string, sep byte) (before, after string, found bool) {
+	if i := strings.LastIndexByte(s, sep); i != -1 {
+		return s[:i], s[i+1:], true
+	}
+	return s, "", false
+}
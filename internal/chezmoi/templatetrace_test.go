@@ -0,0 +1,56 @@
+package chezmoi
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/alecthomas/assert/v2"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestTemplateTracer(t *testing.T) {
+	tracer := NewTemplateTracer([]string{"secret"})
+	tmpl, err := ParseTemplate("name", []byte(chezmoitest.JoinLines(
+		"{{/* chezmoi:template:missing-key=error */}}",
+		`{{ $s := secret }}{{ .key | upper }} {{ template "other" $s }}`,
+		`{{ define "other" }}{{ printf "%s!" . }}{{ end }}`,
+	)), TemplateOptions{
+		Funcs: template.FuncMap{
+			"secret": func() string { return "password" },
+			"upper":  strings.ToUpper,
+		},
+		Tracer: tracer,
+	})
+	assert.NoError(t, err)
+
+	actual, err := tmpl.ExecuteString(map[string]any{
+		"key": "value",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "VALUE password!\n\n", actual)
+
+	events := tracer.Events()
+	assert.Equal(t, 5, len(events))
+	for i, expected := range []struct {
+		eventType TemplateTraceEventType
+		location  string
+		name      string
+	}{
+		{TemplateTraceEventTypeCall, "name:2:9", "secret"},
+		{TemplateTraceEventTypeLookup, "name:2:21", ".key"},
+		{TemplateTraceEventTypeCall, "name:2:28", "upper"},
+		{TemplateTraceEventTypeLookup, "name:2:57", "$s"},
+		{TemplateTraceEventTypeTemplate, "name:2:49", "other"},
+	} {
+		assert.Equal(t, expected.eventType, events[i].Type)
+		assert.Equal(t, expected.location, events[i].Location)
+		assert.Equal(t, expected.name, events[i].Name)
+	}
+
+	var builder strings.Builder
+	_, err = tracer.WriteTo(&builder)
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(builder.String(), "password"))
+}
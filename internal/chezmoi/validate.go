@@ -0,0 +1,207 @@
+package chezmoi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/goccy/go-yaml"
+	"github.com/tailscale/hujson"
+	"gopkg.in/ini.v1"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoierrors"
+	"chezmoi.io/chezmoi/v2/internal/chezmoilog"
+)
+
+// validateFormats are the formats that the contents of targets can be
+// validated against.
+var validateFormats = []string{
+	"ini",
+	"json",
+	"jsonc",
+	"toml",
+	"yaml",
+}
+
+// A Validator validates the contents of a target, either by parsing them in a
+// format or by running a command on a temporary copy.
+type Validator struct {
+	Format  string
+	Command string
+}
+
+// A validatorPattern is a Validator for all targets that match a pattern.
+type validatorPattern struct {
+	pattern   string
+	validator Validator
+}
+
+// newValidatorPattern returns a new validatorPattern. If value is a format
+// then targets are validated against the format, otherwise value is a command.
+func newValidatorPattern(pattern, value string, sourceAbsPath AbsPath, lineNumber int) (*validatorPattern, error) {
+	if !doublestar.ValidatePattern(pattern) {
+		return nil, fmt.Errorf("%s:%d: %s: invalid pattern", sourceAbsPath, lineNumber, pattern)
+	}
+	validator := Validator{
+		Command: value,
+	}
+	if slices.Contains(validateFormats, value) {
+		validator = Validator{
+			Format: value,
+		}
+	}
+	return &validatorPattern{
+		pattern:   pattern,
+		validator: validator,
+	}, nil
+}
+
+// match returns if p matches targetRelPath.
+func (p *validatorPattern) match(targetRelPath RelPath) bool {
+	match, _ := doublestar.Match(p.pattern, targetRelPath.String())
+	return match
+}
+
+// matchingValidators returns the validators in .chezmoivalidate files that
+// match targetRelPath.
+func (s *SourceState) matchingValidators(targetRelPath RelPath) []Validator {
+	var validators []Validator
+	for _, validatorPattern := range s.validatorPatterns {
+		if validatorPattern.match(targetRelPath) {
+			validators = append(validators, validatorPattern.validator)
+		}
+	}
+	return validators
+}
+
+// validate validates contents, the contents of the target at destAbsPath, with
+// validators.
+func (s *SourceState) validate(destAbsPath AbsPath, contents []byte, validators []Validator) error {
+	for _, validator := range validators {
+		var err error
+		if validator.Format != "" {
+			err = validateFormat(validator.Format, contents)
+		} else {
+			err = s.validateCommand(validator.Command, destAbsPath, contents)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCommand runs command on a temporary copy of contents, the contents of
+// the target at destAbsPath, and returns an error if command fails.
+func (s *SourceState) validateCommand(command string, destAbsPath AbsPath, contents []byte) (err error) {
+	if strings.TrimSpace(command) == "" {
+		return errors.New("empty validate command")
+	}
+	name, args, err := ParseCommand(command, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+
+	// Create the script temporary directory, if needed.
+	s.createScriptTempDirOnce.Do(func() {
+		if !s.scriptTempDirAbsPath.IsEmpty() {
+			err = os.MkdirAll(s.scriptTempDirAbsPath.String(), 0o700)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write the contents to a temporary file with the same extension as the
+	// target, as some commands use the extension to determine the format.
+	var tempFile *os.File
+	if tempFile, err = os.CreateTemp(s.scriptTempDirAbsPath.String(), "*."+destAbsPath.Base()); err != nil {
+		return err
+	}
+	defer chezmoierrors.CombineFunc(&err, func() error {
+		return os.RemoveAll(tempFile.Name())
+	})
+	_, err = tempFile.Write(contents)
+	err = chezmoierrors.Combine(err, tempFile.Close())
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(name, append(args, tempFile.Name())...) //nolint:gosec
+	if output, err := chezmoilog.LogCmdCombinedOutput(s.logger, cmd); err != nil {
+		return fmt.Errorf("%s: %w\n%s", command, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// validateFormat returns an error, including the line and column if known, if
+// data is not valid in format.
+func validateFormat(format string, data []byte) error {
+	var err error
+	switch format {
+	case "ini":
+		// Some ini errors include the offending line, including its newline.
+		if _, err = ini.Load(data); err != nil {
+			err = errors.New(strings.TrimSpace(err.Error()))
+		}
+	case "json":
+		err = validateJSON(data)
+	case "jsonc":
+		// hujson.Standardize replaces comments and trailing commas with
+		// whitespace, so offsets in the standardized data are offsets in data.
+		var standardizedData []byte
+		if standardizedData, err = hujson.Standardize(slices.Clone(data)); err == nil {
+			err = validateJSON(standardizedData)
+		}
+	case "toml":
+		var value any
+		var parseError toml.ParseError
+		if _, err = toml.Decode(string(data), &value); errors.As(err, &parseError) {
+			err = fmt.Errorf("%d:%d: %s", parseError.Position.Line, parseError.Position.Col, parseError.Message)
+		}
+	case "yaml":
+		var value any
+		var yamlError yaml.Error
+		if err = yaml.Unmarshal(data, &value); errors.As(err, &yamlError) {
+			if token := yamlError.GetToken(); token != nil {
+				err = fmt.Errorf("%d:%d: %s", token.Position.Line, token.Position.Column, yamlError.GetMessage())
+			}
+		}
+	default:
+		return fmt.Errorf("%s: unknown format", format)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", format, err)
+	}
+	return nil
+}
+
+// validateJSON returns an error, including the line and column, if data is not
+// valid JSON.
+func validateJSON(data []byte) error {
+	var value any
+	var syntaxError *json.SyntaxError
+	err := json.Unmarshal(data, &value)
+	if errors.As(err, &syntaxError) {
+		// syntaxError.Offset is the offset after the offending byte.
+		line, column := lineAndColumn(data, max(syntaxError.Offset-1, 0))
+		return fmt.Errorf("%d:%d: %w", line, column, err)
+	}
+	return err
+}
+
+// lineAndColumn returns the one-based line and column of the byte at offset in
+// data.
+func lineAndColumn(data []byte, offset int64) (line, column int) {
+	before := data[:min(offset, int64(len(data)))]
+	line = bytes.Count(before, []byte{'\n'}) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -0,0 +1,87 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestValidateFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		format        string
+		data          string
+		expectedError string
+	}{
+		{
+			name:   "ini",
+			format: "ini",
+			data:   "[section]\nkey = value\n",
+		},
+		{
+			name:          "ini_invalid",
+			format:        "ini",
+			data:          "[section\n",
+			expectedError: "invalid ini: unclosed section: [section",
+		},
+		{
+			name:   "json",
+			format: "json",
+			data:   "{\n  \"key\": \"value\"\n}\n",
+		},
+		{
+			name:          "json_invalid",
+			format:        "json",
+			data:          "{\n  \"key\": \n}\n",
+			expectedError: "invalid json: 3:1: invalid character '}' looking for beginning of value",
+		},
+		{
+			name:          "json_truncated",
+			format:        "json",
+			data:          "{\n  \"key\": \"value\"\n",
+			expectedError: "invalid json: 2:17: unexpected end of JSON input",
+		},
+		{
+			name:   "jsonc",
+			format: "jsonc",
+			data:   "{\n  // comment\n  \"key\": \"value\",\n}\n",
+		},
+		{
+			name:          "jsonc_invalid",
+			format:        "jsonc",
+			data:          "{\n  // comment\n  \"key\": }\n",
+			expectedError: "invalid jsonc: hujson: line 3, column 10: invalid character '}' at start of value",
+		},
+		{
+			name:   "toml",
+			format: "toml",
+			data:   "key = \"value\"\n",
+		},
+		{
+			name:          "toml_invalid",
+			format:        "toml",
+			data:          "key = \"value\"\nother = \n",
+			expectedError: "invalid toml: 2:9: expected value but found '\\n' instead",
+		},
+		{
+			name:   "yaml",
+			format: "yaml",
+			data:   "key: value\n",
+		},
+		{
+			name:          "yaml_invalid",
+			format:        "yaml",
+			data:          "key: value\n other: value\n",
+			expectedError: "invalid yaml: 1:6: mapping value is not allowed in this context",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateFormat(tc.format, []byte(tc.data))
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
@@ -45,7 +45,9 @@ func (c *Config) runApplyCmd(cmd *cobra.Command, args []string) error {
 		init:         c.apply.init,
 		parentDirs:   c.apply.parentDirs,
 		recursive:    c.apply.recursive,
+		reload:       true,
 		umask:        c.Umask,
+		validate:     true,
 		preApplyFunc: c.defaultPreApplyFunc,
 	})
 }
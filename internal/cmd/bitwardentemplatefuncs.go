@@ -7,13 +7,15 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"chezmoi.io/chezmoi/v2/internal/chezmoilog"
 )
 
 type bitwardenConfig struct {
-	Command     string   `json:"command" mapstructure:"command" yaml:"command"`
-	Unlock      autoBool `json:"unlock"  mapstructure:"unlock"  yaml:"unlock"`
+	Command     string        `json:"command"  mapstructure:"command"  yaml:"command"`
+	Unlock      autoBool      `json:"unlock"   mapstructure:"unlock"   yaml:"unlock"`
+	CacheTTL    time.Duration `json:"cacheTTL" mapstructure:"cacheTTL" yaml:"cacheTTL"`
 	session     string
 	outputCache map[string][]byte
 }
@@ -90,7 +92,9 @@ func (c *Config) bitwardenOutput(args []string) ([]byte, error) {
 		return data, nil
 	}
 
-	output, err := c.bitwardenUncachedOutput(args)
+	output, err := c.secretCacheOutput("bitwarden", c.Bitwarden.CacheTTL, args, func() ([]byte, error) {
+		return c.bitwardenUncachedOutput(args)
+	})
 	if err != nil {
 		return nil, err
 	}
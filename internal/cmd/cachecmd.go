@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+type cacheCmdConfig struct {
+	clear cacheClearCmdConfig
+}
+
+type cacheClearCmdConfig struct {
+	data     bool
+	gitHub   bool
+	template bool
+}
+
+func (c *Config) newCacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		GroupID: groupIDAdvanced,
+		Use:     "cache",
+		Args:    cobra.NoArgs,
+		Short:   "Manipulate cached template function results",
+		Long:    mustLongHelp("cache"),
+		Example: example("cache"),
+		Annotations: newAnnotations(
+			persistentStateModeNone,
+		),
+	}
+
+	cacheClearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Clear cached template function results",
+		Args:  cobra.NoArgs,
+		RunE:  c.runCacheClearCmd,
+		Annotations: newAnnotations(
+			persistentStateModeReadWrite,
+		),
+	}
+	cacheClearCmd.Flags().BoolVar(&c.cache.clear.data, "data", c.cache.clear.data, "Clear cached data generator output")
+	cacheClearCmd.Flags().BoolVar(&c.cache.clear.gitHub, "github", c.cache.clear.gitHub, "Clear cached GitHub API results")
+	cacheClearCmd.Flags().BoolVar(&c.cache.clear.template, "template", c.cache.clear.template, "Clear cached command results")
+	cacheCmd.AddCommand(cacheClearCmd)
+
+	return cacheCmd
+}
+
+func (c *Config) runCacheClearCmd(cmd *cobra.Command, args []string) error {
+	all := !c.cache.clear.data && !c.cache.clear.gitHub && !c.cache.clear.template
+	if all || c.cache.clear.data {
+		dataGeneratorsCacheDirAbsPath := c.CacheDirAbsPath.JoinString(chezmoi.DataGeneratorsCacheDirName)
+		if err := c.baseSystem.RemoveAll(dataGeneratorsCacheDirAbsPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	var buckets [][]byte
+	if all || c.cache.clear.gitHub {
+		buckets = append(buckets,
+			gitHubKeysStateBucket,
+			gitHubLatestReleaseStateBucket,
+			gitHubReleasesStateBucket,
+			gitHubTagsStateBucket,
+			gitHubVersionReleaseStateBucket,
+		)
+	}
+	if all || c.cache.clear.template {
+		buckets = append(buckets, templateFuncCacheStateBucket)
+	}
+	for _, bucket := range buckets {
+		if err := c.persistentState.DeleteBucket(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
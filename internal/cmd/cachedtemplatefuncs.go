@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+// A templateFuncCacheState is a cached result of a template function.
+type templateFuncCacheState[T any] struct {
+	RequestedAt time.Time `json:"requestedAt" yaml:"requestedAt"`
+	Value       T         `json:"value"       yaml:"value"`
+}
+
+var templateFuncCacheStateBucket = []byte("templateFuncCacheState")
+
+// templateFuncCacheKeyEnvVars are the environment variables that are always
+// included in cache keys because they change the result of commands.
+var templateFuncCacheKeyEnvVars = []string{
+	"HOME",
+	"PATH",
+}
+
+func (c *Config) cachedExecTemplateFunc(ttl, name string, args ...string) bool {
+	return cachedTemplateFuncValue(c, ttl, "exec", name, args, func() bool {
+		return c.execTemplateFunc(name, args...)
+	})
+}
+
+func (c *Config) cachedLookPathTemplateFunc(ttl, file string) string {
+	return cachedTemplateFuncValue(c, ttl, "lookPath", file, nil, func() string {
+		return c.lookPathTemplateFunc(file)
+	})
+}
+
+func (c *Config) cachedOutputTemplateFunc(ttl, name string, args ...string) string {
+	return cachedTemplateFuncValue(c, ttl, "output", name, args, func() string {
+		return c.outputTemplateFunc(name, args...)
+	})
+}
+
+// cachedTemplateFuncValue returns the cached result of the template function
+// funcName called with name and args if it is younger than ttl, otherwise it
+// calls f and caches its result.
+func cachedTemplateFuncValue[T any](c *Config, ttl, funcName, name string, args []string, f func() T) T {
+	ttlDuration := mustValue(time.ParseDuration(ttl))
+	key := c.templateFuncCacheKey(funcName, name, args)
+
+	now := time.Now()
+	var value templateFuncCacheState[T]
+	ok := mustValue(chezmoi.PersistentStateGet(c.persistentState, templateFuncCacheStateBucket, key, &value))
+	if ok && now.Before(value.RequestedAt.Add(ttlDuration)) {
+		return value.Value
+	}
+
+	result := f()
+	must(chezmoi.PersistentStateSet(c.persistentState, templateFuncCacheStateBucket, key, &templateFuncCacheState[T]{
+		RequestedAt: now,
+		Value:       result,
+	}))
+	return result
+}
+
+// templateFuncCacheKey returns the cache key for the template function
+// funcName called with name and args with the current values of
+// templateFuncCacheKeyEnvVars and the environment variables in
+// template.cacheEnv.
+func (c *Config) templateFuncCacheKey(funcName, name string, args []string) []byte {
+	envVars := slices.Concat(templateFuncCacheKeyEnvVars, c.Template.CacheEnv)
+	slices.Sort(envVars)
+	envVars = slices.Compact(envVars)
+	env := make([]string, 0, len(envVars))
+	for _, key := range envVars {
+		env = append(env, key+"="+os.Getenv(key))
+	}
+	data := mustValue(json.Marshal(struct {
+		Func string   `json:"func"`
+		Name string   `json:"name"`
+		Args []string `json:"args"`
+		Env  []string `json:"env"`
+	}{
+		Func: funcName,
+		Name: name,
+		Args: args,
+		Env:  env,
+	}))
+	sha256Sum := sha256.Sum256(data)
+	return []byte(hex.EncodeToString(sha256Sum[:]))
+}
@@ -7,8 +7,13 @@ import (
 	"github.com/spf13/cobra"
 
 	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+	"chezmoi.io/chezmoi/v2/internal/chezmoierrors"
 )
 
+type catCmdConfig struct {
+	trace bool
+}
+
 func (c *Config) newCatCmd() *cobra.Command {
 	catCmd := &cobra.Command{
 		GroupID:           groupIDTemplate,
@@ -18,17 +23,26 @@ func (c *Config) newCatCmd() *cobra.Command {
 		Example:           example("cat"),
 		ValidArgsFunction: c.targetValidArgs,
 		Args:              cobra.MinimumNArgs(1),
-		RunE:              c.makeRunEWithSourceState(c.runCatCmd),
+		RunE:              c.runCatCmd,
 		Annotations: newAnnotations(
 			persistentStateModeReadWrite,
 			requiresSourceDirectory,
 		),
 	}
 
+	catCmd.Flags().BoolVar(&c.cat.trace, "trace", c.cat.trace, "Trace template execution")
+
 	return catCmd
 }
 
-func (c *Config) runCatCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+func (c *Config) runCatCmd(cmd *cobra.Command, args []string) (err error) {
+	if c.cat.trace {
+		defer chezmoierrors.CombineFunc(&err, c.traceTemplates())
+	}
+	return c.makeRunEWithSourceState(c.runCatCmdWithSourceState)(cmd, args)
+}
+
+func (c *Config) runCatCmdWithSourceState(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
 	targetRelPaths, err := c.targetRelPaths(sourceState, args, targetRelPathsOptions{})
 	if err != nil {
 		return err
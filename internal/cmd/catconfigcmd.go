@@ -1,6 +1,19 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+type catConfigCmdConfig struct {
+	sources bool
+}
 
 func (c *Config) newCatConfigCmd() *cobra.Command {
 	catConfigCmd := &cobra.Command{
@@ -19,6 +32,8 @@ func (c *Config) newCatConfigCmd() *cobra.Command {
 		),
 	}
 
+	catConfigCmd.Flags().BoolVar(&c.catConfig.sources, "sources", c.catConfig.sources, "Print the source of each key")
+
 	return catConfigCmd
 }
 
@@ -27,6 +42,38 @@ func (c *Config) runCatConfigCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+
+	if c.catConfig.sources {
+		configSources, err := c.readConfigSources(configFileAbsPath)
+		if err != nil {
+			return err
+		}
+		keySources := make(map[string]string)
+		for key, absPath := range configKeySources(configSources) {
+			keySources[key] = absPath.String()
+		}
+		configEnvVars, err := configEnvVars(os.Environ())
+		if err != nil {
+			return err
+		}
+		for _, configEnvVar := range configEnvVars {
+			envKey := strings.Join(configEnvVar.key, ".")
+			// Config file keys are case insensitive.
+			for key := range keySources {
+				if strings.EqualFold(key, envKey) {
+					delete(keySources, key)
+				}
+			}
+			keySources[envKey] = "$" + configEnvVar.name
+		}
+		resultWriter := tabwriter.NewWriter(c.stdout, 3, 0, 3, ' ', 0)
+		fmt.Fprint(resultWriter, "KEY\tSOURCE\n")
+		for _, key := range slices.Sorted(maps.Keys(keySources)) {
+			fmt.Fprintf(resultWriter, "%s\t%s\n", key, keySources[key])
+		}
+		return resultWriter.Flush()
+	}
+
 	data, err := c.baseSystem.ReadFile(configFileAbsPath)
 	if err != nil {
 		return err
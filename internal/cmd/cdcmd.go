@@ -87,5 +87,5 @@ func (c *Config) cdCommand() (string, []string, error) {
 	}
 
 	cdCommand, _ = shell.CurrentUserShell()
-	return parseCommand(cdCommand, cdArgs)
+	return chezmoi.ParseCommand(cdCommand, cdArgs)
 }
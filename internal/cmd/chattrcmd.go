@@ -40,6 +40,8 @@ const (
 	orderModifierLeaveUnchanged orderModifier = 0
 	orderModifierClearAfter     orderModifier = 1
 	orderModifierSetAfter       orderModifier = 2
+	orderModifierClearFinally   orderModifier = 3
+	orderModifierSetFinally     orderModifier = 4
 )
 
 type sourceFileTypeModifier int
@@ -109,6 +111,7 @@ func (c *Config) chattrCmdValidArgs(cmd *cobra.Command, args []string, toComplet
 			"exact",
 			"executable",
 			"external",
+			"finally",
 			"modify",
 			"once",
 			"onchange",
@@ -165,16 +168,16 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string, sourceState *ch
 	encryptedSuffix := sourceState.Encryption().EncryptedSuffix()
 	for _, targetRelPath := range slices.Backward(targetRelPaths) {
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
-		sourceRelPath := sourceStateEntry.SourceRelPath()
-		parentSourceRelPath, fileSourceRelPath := sourceRelPath.Split()
-		parentRelPath := parentSourceRelPath.RelPath()
+		_, fileSourceRelPath := sourceStateEntry.SourceRelPath().Split()
 		fileRelPath := fileSourceRelPath.RelPath()
+		// Rename the entry within the source directory layer that contains it.
+		oldSourceAbsPath := sourceState.SourceAbsPath(sourceStateEntry)
+		parentAbsPath := oldSourceAbsPath.Dir()
 		switch sourceStateEntry := sourceStateEntry.(type) {
 		case *chezmoi.SourceStateDir:
 			relPath := m.modifyDirAttr(sourceStateEntry.Attr()).SourceName()
 			if newBaseNameRelPath := chezmoi.NewRelPath(relPath); newBaseNameRelPath != fileRelPath {
-				oldSourceAbsPath := c.SourceDirAbsPath.Join(parentRelPath, fileRelPath)
-				newSourceAbsPath := c.SourceDirAbsPath.Join(parentRelPath, newBaseNameRelPath)
+				newSourceAbsPath := parentAbsPath.Join(newBaseNameRelPath)
 				if err := c.sourceSystem.Rename(oldSourceAbsPath, newSourceAbsPath); err != nil {
 					return err
 				}
@@ -182,8 +185,7 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string, sourceState *ch
 		case *chezmoi.SourceStateFile:
 			newAttr := m.modifyFileAttr(sourceStateEntry.Attr())
 			newBaseNameRelPath := chezmoi.NewRelPath(newAttr.SourceName(encryptedSuffix))
-			oldSourceAbsPath := c.SourceDirAbsPath.Join(parentRelPath, fileRelPath)
-			newSourceAbsPath := c.SourceDirAbsPath.Join(parentRelPath, newBaseNameRelPath)
+			newSourceAbsPath := parentAbsPath.Join(newBaseNameRelPath)
 			switch encryptedBefore, encryptedAfter := sourceStateEntry.Attr().Encrypted, newAttr.Encrypted; {
 			case encryptedBefore && !encryptedAfter:
 				// Write the plaintext and then remove the ciphertext.
@@ -282,6 +284,13 @@ func (m orderModifier) modify(order chezmoi.ScriptOrder) chezmoi.ScriptOrder {
 		return order
 	case orderModifierSetAfter:
 		return chezmoi.ScriptOrderAfter
+	case orderModifierClearFinally:
+		if order == chezmoi.ScriptOrderFinally {
+			return chezmoi.ScriptOrderDuring
+		}
+		return order
+	case orderModifierSetFinally:
+		return chezmoi.ScriptOrderFinally
 	default:
 		panic(fmt.Sprintf("%d: unknown order modifier", m))
 	}
@@ -392,6 +401,15 @@ func parseModifier(s string) (*modifier, error) {
 			m.executable = bm
 		case "external":
 			m.external = bm
+		case "finally":
+			switch bm {
+			case boolModifierClear:
+				m.order = orderModifierClearFinally
+			case boolModifierLeaveUnchanged:
+				m.order = orderModifierLeaveUnchanged
+			case boolModifierSet:
+				m.order = orderModifierSetFinally
+			}
 		case "modify":
 			switch bm {
 			case boolModifierClear:
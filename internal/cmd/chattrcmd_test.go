@@ -134,6 +134,18 @@ func TestParseAttrModifier(t *testing.T) {
 				order: orderModifierClearAfter,
 			},
 		},
+		{
+			s: "finally",
+			expected: &modifier{
+				order: orderModifierSetFinally,
+			},
+		},
+		{
+			s: "-finally",
+			expected: &modifier{
+				order: orderModifierClearFinally,
+			},
+		},
 		{
 			s: "once",
 			expected: &modifier{
@@ -25,6 +25,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -43,8 +44,6 @@ import (
 	"github.com/twpayne/go-vfs/v5"
 	"github.com/twpayne/go-xdg/v6"
 	"golang.org/x/term"
-	"mvdan.cc/sh/v3/expand"
-	"mvdan.cc/sh/v3/syntax"
 
 	"chezmoi.io/chezmoi/v2/assets/templates"
 	"chezmoi.io/chezmoi/v2/internal/chezmoi"
@@ -104,16 +103,33 @@ type commandConfig struct {
 	Args    []string `json:"args"    mapstructure:"args"    yaml:"args"`
 }
 
+type dataGeneratorsConfig struct {
+	CacheTTL time.Duration `json:"cacheTTL" mapstructure:"cacheTTL" yaml:"cacheTTL"`
+}
+
 type hookConfig struct {
 	Pre  commandConfig `json:"pre"  mapstructure:"pre"  yaml:"pre"`
 	Post commandConfig `json:"post" mapstructure:"post" yaml:"post"`
 }
 
+// A profileConfig contains the configuration of a named profile, which
+// overrides the top-level configuration when the profile is selected.
+type profileConfig struct {
+	Data             map[string]any  `json:"data"       mapstructure:"data"       yaml:"data"`
+	DestDirAbsPath   chezmoi.AbsPath `json:"destDir"    mapstructure:"destDir"    yaml:"destDir"`
+	Encryption       string          `json:"encryption" mapstructure:"encryption" yaml:"encryption"`
+	Externals        *bool           `json:"externals"  mapstructure:"externals"  yaml:"externals"`
+	SourceDirAbsPath chezmoi.AbsPath `json:"sourceDir"  mapstructure:"sourceDir"  yaml:"sourceDir"`
+}
+
 type templateConfig struct {
-	Options []string `json:"options" mapstructure:"options" yaml:"options"`
+	CacheEnv   []string `json:"cacheEnv"   mapstructure:"cacheEnv"   yaml:"cacheEnv"`
+	Options    []string `json:"options"    mapstructure:"options"    yaml:"options"`
+	Restricted bool     `json:"restricted" mapstructure:"restricted" yaml:"restricted"`
 }
 
 type warningsConfig struct {
+	ConfigFileSchema             bool `json:"configFileSchema"             mapstructure:"configFileSchema"             yaml:"configFileSchema"`
 	ConfigFileTemplateHasChanged bool `json:"configFileTemplateHasChanged" mapstructure:"configFileTemplateHasChanged" yaml:"configFileTemplateHasChanged"`
 }
 
@@ -123,6 +139,7 @@ type ConfigFile struct {
 	CacheDirAbsPath        chezmoi.AbsPath                `json:"cacheDir"        mapstructure:"cacheDir"        yaml:"cacheDir"`
 	Color                  autoBool                       `json:"color"           mapstructure:"color"           yaml:"color"`
 	Data                   map[string]any                 `json:"data"            mapstructure:"data"            yaml:"data"`
+	DataGenerators         dataGeneratorsConfig           `json:"dataGenerators"  mapstructure:"dataGenerators"  yaml:"dataGenerators"`
 	Env                    map[string]string              `json:"env"             mapstructure:"env"             yaml:"env"`
 	Format                 *choiceFlag                    `json:"format"          mapstructure:"format"          yaml:"format"`
 	DestDirAbsPath         chezmoi.AbsPath                `json:"destDir"         mapstructure:"destDir"         yaml:"destDir"`
@@ -132,15 +149,19 @@ type ConfigFile struct {
 	Interpreters           map[string]chezmoi.Interpreter `json:"interpreters"    mapstructure:"interpreters"    yaml:"interpreters"`
 	LessInteractive        bool                           `json:"lessInteractive" mapstructure:"lessInteractive" yaml:"lessInteractive"`
 	Mode                   chezmoi.Mode                   `json:"mode"            mapstructure:"mode"            yaml:"mode"`
+	Modules                map[string]bool                `json:"modules"         mapstructure:"modules"         yaml:"modules"`
 	Pager                  string                         `json:"pager"           mapstructure:"pager"           yaml:"pager"`
 	PagerArgs              []string                       `json:"pagerArgs"       mapstructure:"pagerArgs"       yaml:"pagerArgs"`
 	PersistentStateAbsPath chezmoi.AbsPath                `json:"persistentState" mapstructure:"persistentState" yaml:"persistentState"`
 	PINEntry               pinEntryConfig                 `json:"pinentry"        mapstructure:"pinentry"        yaml:"pinentry"`
+	Profiles               map[string]profileConfig       `json:"profiles"        mapstructure:"profiles"        yaml:"profiles"`
 	Progress               autoBool                       `json:"progress"        mapstructure:"progress"        yaml:"progress"`
 	Safe                   bool                           `json:"safe"            mapstructure:"safe"            yaml:"safe"`
 	ScriptEnv              map[string]string              `json:"scriptEnv"       mapstructure:"scriptEnv"       yaml:"scriptEnv"`
 	ScriptTempDir          chezmoi.AbsPath                `json:"scriptTempDir"   mapstructure:"scriptTempDir"   yaml:"scriptTempDir"`
 	SourceDirAbsPath       chezmoi.AbsPath                `json:"sourceDir"       mapstructure:"sourceDir"       yaml:"sourceDir"`
+	SourceLayerAbsPaths    []chezmoi.AbsPath              `json:"-"               mapstructure:"-"               yaml:"-"`
+	SourceWriteDirAbsPath  chezmoi.AbsPath                `json:"sourceWriteDir"  mapstructure:"sourceWriteDir"  yaml:"sourceWriteDir"`
 	TempDir                chezmoi.AbsPath                `json:"tempDir"         mapstructure:"tempDir"         yaml:"tempDir"`
 	Template               templateConfig                 `json:"template"        mapstructure:"template"        yaml:"template"`
 	TextConv               textConv                       `json:"textConv"        mapstructure:"textConv"        yaml:"textConv"`
@@ -177,23 +198,28 @@ type ConfigFile struct {
 	GPG        chezmoi.GPGEncryption `json:"gpg"        mapstructure:"gpg"        yaml:"gpg"`
 
 	// Command configurations.
-	Add        addCmdConfig        `json:"add"        mapstructure:"add"        yaml:"add"`
-	CD         cdCmdConfig         `json:"cd"         mapstructure:"cd"         yaml:"cd"`
-	Completion completionCmdConfig `json:"completion" mapstructure:"completion" yaml:"completion"`
-	Docker     dockerCmdConfig     `json:"docker"     mapstructure:"docker"     yaml:"docker"`
-	Diff       diffCmdConfig       `json:"diff"       mapstructure:"diff"       yaml:"diff"`
-	Edit       editCmdConfig       `json:"edit"       mapstructure:"edit"       yaml:"edit"`
-	Git        gitCmdConfig        `json:"git"        mapstructure:"git"        yaml:"git"`
-	Merge      mergeCmdConfig      `json:"merge"      mapstructure:"merge"      yaml:"merge"`
-	Status     statusCmdConfig     `json:"status"     mapstructure:"status"     yaml:"status"`
-	Update     updateCmdConfig     `json:"update"     mapstructure:"update"     yaml:"update"`
-	Verify     verifyCmdConfig     `json:"verify"     mapstructure:"verify"     yaml:"verify"`
+	Add          addCmdConfig          `json:"add"          mapstructure:"add"          yaml:"add"`
+	CD           cdCmdConfig           `json:"cd"           mapstructure:"cd"           yaml:"cd"`
+	Completion   completionCmdConfig   `json:"completion"   mapstructure:"completion"   yaml:"completion"`
+	Docker       dockerCmdConfig       `json:"docker"       mapstructure:"docker"       yaml:"docker"`
+	Diff         diffCmdConfig         `json:"diff"         mapstructure:"diff"         yaml:"diff"`
+	Edit         editCmdConfig         `json:"edit"         mapstructure:"edit"         yaml:"edit"`
+	Git          gitCmdConfig          `json:"git"          mapstructure:"git"          yaml:"git"`
+	Merge        mergeCmdConfig        `json:"merge"        mapstructure:"merge"        yaml:"merge"`
+	RenderMatrix renderMatrixCmdConfig `json:"renderMatrix" mapstructure:"renderMatrix" yaml:"renderMatrix"`
+	Status       statusCmdConfig       `json:"status"       mapstructure:"status"       yaml:"status"`
+	Update       updateCmdConfig       `json:"update"       mapstructure:"update"       yaml:"update"`
+	Verify       verifyCmdConfig       `json:"verify"       mapstructure:"verify"       yaml:"verify"`
 }
 
 // A Config represents a configuration.
 type Config struct {
 	ConfigFile
 
+	// ConfigEnv maps config file keys to the environment variables that
+	// override them.
+	ConfigEnv map[string]string `json:"configEnv,omitempty" yaml:"configEnv,omitempty"`
+
 	// Global configuration.
 	ageRecipient     string
 	ageRecipientFile string
@@ -206,6 +232,8 @@ type Config struct {
 	noPager          bool
 	noTTY            bool
 	outputAbsPath    chezmoi.AbsPath
+	profile          string
+	readExternals    bool
 	refreshExternals chezmoi.RefreshExternals
 	sourcePath       bool
 	templateFuncs    template.FuncMap
@@ -220,17 +248,23 @@ type Config struct {
 	ageKeygen       ageKeygenCmdConfig
 	apply           applyCmdConfig
 	archive         archiveCmdConfig
+	cache           cacheCmdConfig
+	cat             catCmdConfig
+	catConfig       catConfigCmdConfig
 	chattr          chattrCmdConfig
+	config          configCmdConfig
 	data            dataCmdConfig
 	destroy         destroyCmdConfig
 	doctor          doctorCmdConfig
 	dump            dumpCmdConfig
 	dumpConfig      dumpConfigCmdConfig
 	executeTemplate executeTemplateCmdConfig
+	explain         explainCmdConfig
 	generate        generateCmdConfig
 	ignored         ignoredCmdConfig
 	_import         importCmdConfig
 	init            initCmdConfig
+	lint            lintCmdConfig
 	managed         managedCmdConfig
 	mergeAll        mergeAllCmdConfig
 	ssh             sshCmdConfig
@@ -257,6 +291,7 @@ type Config struct {
 	defaultConfigFileAbsPath    chezmoi.AbsPath
 	defaultConfigFileAbsPathErr error
 	customConfigFileAbsPath     chezmoi.AbsPath
+	systemConfigDirAbsPath      chezmoi.AbsPath
 	baseSystem                  chezmoi.System
 	sourceSystem                chezmoi.System
 	destSystem                  chezmoi.System
@@ -268,6 +303,8 @@ type Config struct {
 	commandDirAbsPath      chezmoi.AbsPath
 	homeDirAbsPath         chezmoi.AbsPath
 	encryption             chezmoi.Encryption
+	secretCacheEnabled     bool
+	templateTracer         *chezmoi.TemplateTracer
 	sourceDirAbsPath       chezmoi.AbsPath
 	sourceDirAbsPathErr    error
 	sourceState            *chezmoi.SourceState
@@ -308,6 +345,7 @@ type templateData struct {
 	configFile        string
 	destDir           string
 	executable        string
+	facts             func() map[string]any
 	flags             templateDataFlags
 	fqdnHostname      string
 	gid               string
@@ -319,6 +357,7 @@ type templateData struct {
 	osRelease         map[string]any
 	pathListSeparator string
 	pathSeparator     string
+	profile           string
 	rawHomeDir        string
 	sourceDir         string
 	uid               string
@@ -383,6 +422,8 @@ func newConfig(options ...configOption) (*Config, error) {
 		// Global configuration.
 		configFormat:  newChoiceFlag("", readDataFormatValues),
 		homeDir:       userHomeDir,
+		profile:       os.Getenv("CHEZMOI_PROFILE"),
+		readExternals: true,
 		templateFuncs: sprig.TxtFuncMap(),
 
 		// Command configurations.
@@ -395,6 +436,11 @@ func newConfig(options ...configOption) (*Config, error) {
 			format:    newChoiceFlag("tar.gz", archiveFormatValues),
 			recursive: true,
 		},
+		config: configCmdConfig{
+			get: configGetCmdConfig{
+				format: newChoiceFlag("", writeDataFormatValues),
+			},
+		},
 		data: dataCmdConfig{
 			format: newChoiceFlag("", writeDataFormatValues),
 		},
@@ -409,6 +455,9 @@ func newConfig(options ...configOption) (*Config, error) {
 		executeTemplate: executeTemplateCmdConfig{
 			stdinIsATTY: true,
 		},
+		explain: explainCmdConfig{
+			format: newChoiceFlag("", writeDataFormatValues),
+		},
 		generate: generateCmdConfig{
 			installInitShellSh: generateInstallInitShellShCmdConfig{
 				interactive: true,
@@ -425,6 +474,9 @@ func newConfig(options ...configOption) (*Config, error) {
 			guessRepoURL:      true,
 			recurseSubmodules: true,
 		},
+		lint: lintCmdConfig{
+			format: newChoiceFlag("", writeDataFormatValues),
+		},
 		managed: managedCmdConfig{
 			filter:    chezmoi.NewEntryTypeFilter(chezmoi.EntryTypesAll, chezmoi.EntryTypesNone),
 			format:    newChoiceFlag(formatJSON, writeDataFormatValues),
@@ -500,6 +552,9 @@ func newConfig(options ...configOption) (*Config, error) {
 		"bitwardenAttachmentByRef":    c.bitwardenAttachmentByRefTemplateFunc,
 		"bitwardenFields":             c.bitwardenFieldsTemplateFunc,
 		"bitwardenSecrets":            c.bitwardenSecretsTemplateFunc,
+		"cachedExec":                  c.cachedExecTemplateFunc,
+		"cachedLookPath":              c.cachedLookPathTemplateFunc,
+		"cachedOutput":                c.cachedOutputTemplateFunc,
 		"comment":                     c.commentTemplateFunc,
 		"dashlaneNote":                c.dashlaneNoteTemplateFunc,
 		"dashlanePassword":            c.dashlanePasswordTemplateFunc,
@@ -610,6 +665,16 @@ func newConfig(options ...configOption) (*Config, error) {
 		return nil, err
 	}
 	c.defaultConfigFileAbsPath, c.defaultConfigFileAbsPathErr = c.defaultConfigFile(c.fileSystem, c.bds)
+	systemConfigDir := defaultSystemConfigDir()
+	if value, ok := os.LookupEnv("CHEZMOI_SYSTEM_CONFIG_DIR"); ok {
+		systemConfigDir = value
+	}
+	if systemConfigDir != "" {
+		c.systemConfigDirAbsPath, err = chezmoi.NormalizePath(systemConfigDir)
+		if err != nil {
+			return nil, err
+		}
+	}
 	c.SourceDirAbsPath, err = c.defaultSourceDir(c.fileSystem, c.bds)
 	if err != nil {
 		return nil, err
@@ -649,10 +714,55 @@ type applyArgsOptions struct {
 	init         bool
 	parentDirs   bool
 	recursive    bool
+	reload       bool
 	umask        fs.FileMode
+	validate     bool
 	preApplyFunc chezmoi.PreApplyFunc
 }
 
+// applyProfile applies the configuration of the selected profile, if any, on
+// top of the configuration read from the config file. Unknown profiles are
+// reported by validateProfile.
+func (c *Config) applyProfile() {
+	profile, ok := c.Profiles[c.profile]
+	if !ok {
+		return
+	}
+	if c.Data == nil {
+		c.Data = make(map[string]any)
+	}
+	chezmoi.RecursiveMerge(c.Data, profile.Data)
+	if !profile.DestDirAbsPath.IsEmpty() {
+		c.DestDirAbsPath = profile.DestDirAbsPath
+	}
+	if profile.Encryption != "" {
+		c.Encryption = profile.Encryption
+	}
+	if profile.Externals != nil {
+		c.readExternals = *profile.Externals
+	}
+	if !profile.SourceDirAbsPath.IsEmpty() {
+		c.SourceDirAbsPath = profile.SourceDirAbsPath
+		c.SourceLayerAbsPaths = nil
+	}
+}
+
+// validateProfile returns an error if the selected profile has an invalid name
+// or is not defined.
+func (c *Config) validateProfile() error {
+	switch {
+	case c.profile == "":
+		return nil
+	case strings.ContainsAny(c.profile, `/\`) || c.profile == "." || c.profile == "..":
+		return fmt.Errorf("%s: invalid profile name", c.profile)
+	default:
+		if _, ok := c.Profiles[c.profile]; !ok {
+			return fmt.Errorf("%s: unknown profile", c.profile)
+		}
+		return nil
+	}
+}
+
 // applyArgs is the core of all commands that make changes to a target system.
 // It checks config file freshness, reads the source state, and then applies the
 // source state for each target entry in args. If args is empty then the source
@@ -740,40 +850,101 @@ func (c *Config) applyArgs(
 		targetRelPaths = prependParentRelPaths(targetRelPaths)
 	}
 
+	// Finally scripts are run after all other targets, whether or not applying
+	// them succeeded.
+	var finallyTargetRelPaths []chezmoi.RelPath
+	targetRelPaths = slices.DeleteFunc(targetRelPaths, func(targetRelPath chezmoi.RelPath) bool {
+		sourceStateEntry := sourceState.Get(targetRelPath)
+		if sourceStateEntry != nil && sourceStateEntry.Order() == chezmoi.ScriptOrderFinally {
+			finallyTargetRelPaths = append(finallyTargetRelPaths, targetRelPath)
+			return true
+		}
+		return false
+	})
+
+	applyContext := chezmoi.NewApplyContext(c.dryRun, options.filter)
 	applyOptions := chezmoi.ApplyOptions{
-		Filter:       options.filter,
-		PreApplyFunc: options.preApplyFunc,
-		Umask:        options.umask,
+		ApplyContext:   applyContext,
+		Filter:         options.filter,
+		HomeDirAbsPath: c.homeDirAbsPath,
+		PreApplyFunc:   options.preApplyFunc,
+		Umask:          options.umask,
+		Validate:       options.validate,
+	}
+
+	// keepGoing returns err if the user did not request --keep-going, otherwise
+	// it reports err and returns nil.
+	var keptGoingErrs []error
+	keepGoing := func(err error) error {
+		if !c.keepGoing {
+			return err
+		}
+		c.errorf("%v\n", err)
+		keptGoingErrs = append(keptGoingErrs, err)
+		return nil
 	}
 
-	keptGoingAfterErr := false
-	for _, targetRelPath := range targetRelPaths {
-		switch err := sourceState.Apply(targetSystem, c.destSystem, c.persistentState, targetDirAbsPath, targetRelPath, applyOptions); {
-		case errors.Is(err, fs.SkipDir):
-			continue
-		case err != nil:
-			err = fmt.Errorf("%s: %w", targetRelPath, err)
-			if !c.keepGoing {
+	applyErr := func() error {
+		for _, targetRelPath := range targetRelPaths {
+			switch err := sourceState.Apply(targetSystem, c.destSystem, c.persistentState, targetDirAbsPath, targetRelPath, applyOptions); {
+			case errors.Is(err, fs.SkipDir):
+				continue
+			case err != nil:
+				if err := keepGoing(fmt.Errorf("%s: %w", targetRelPath, err)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := sourceState.PostApply(targetSystem, c.persistentState, targetDirAbsPath, targetRelPaths); err != nil {
+			if err := keepGoing(err); err != nil {
 				return err
 			}
-			c.errorf("%v\n", err)
-			keptGoingAfterErr = true
 		}
-	}
 
-	switch err := sourceState.PostApply(targetSystem, c.persistentState, targetDirAbsPath, targetRelPaths); {
-	case err != nil && c.keepGoing:
-		c.errorf("%v\n", err)
-		keptGoingAfterErr = true
-	case err != nil:
-		return err
+		if options.reload {
+			if err := c.runReloads(targetSystem, sourceState, targetDirAbsPath, options.filter, applyContext); err != nil {
+				if err := keepGoing(err); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}()
+
+	if len(finallyTargetRelPaths) != 0 {
+		applyStatus := "success"
+		var applyErrStr string
+		if err := chezmoierrors.Combine(append(slices.Clone(keptGoingErrs), applyErr)...); err != nil {
+			applyStatus = "failure"
+			applyErrStr = err.Error()
+		}
+		finallyApplyOptions := applyOptions
+		finallyApplyOptions.ScriptEnv = []string{
+			"CHEZMOI_APPLY_STATUS=" + applyStatus,
+			"CHEZMOI_APPLY_ERROR=" + applyErrStr,
+		}
+		for _, targetRelPath := range finallyTargetRelPaths {
+			switch err := sourceState.Apply(targetSystem, c.destSystem, c.persistentState, targetDirAbsPath, targetRelPath, finallyApplyOptions); {
+			case errors.Is(err, fs.SkipDir):
+				continue
+			case err != nil:
+				if err := keepGoing(fmt.Errorf("%s: %w", targetRelPath, err)); err != nil {
+					return chezmoierrors.Combine(applyErr, err)
+				}
+			}
+		}
 	}
 
-	if keptGoingAfterErr {
+	switch {
+	case applyErr != nil:
+		return applyErr
+	case len(keptGoingErrs) != 0:
 		return chezmoi.ExitCodeError(1)
+	default:
+		return nil
 	}
-
-	return nil
 }
 
 // builtinDiffFile outputs the diff between fromData and fromMode and toData and
@@ -886,14 +1057,37 @@ func (c *Config) createAndReloadConfigFile(cmd *cobra.Command) error {
 		return c.persistentState.Delete(chezmoi.ConfigStateBucket, configStateKey)
 	}
 
+	// Use the template data schema, if any, for the defaults and allowed values
+	// of prompts.
+	dataSchemaAbsPath := sourceDirAbsPath.JoinString(chezmoi.DataSchemaName)
+	switch data, err := c.baseSystem.ReadFile(dataSchemaAbsPath); {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if c.interactiveTemplateFuncs.dataSchema, err = chezmoi.ParseTemplateDataSchema(dataSchemaAbsPath, data); err != nil {
+			return err
+		}
+	}
+
 	configFileContents, err := c.createConfigFileContents(configTemplate.targetRelPath, configTemplate.contents, cmd)
 	if err != nil {
 		return err
 	}
 
 	// Validate the config file.
-	var configFile ConfigFile
-	if err := c.decodeConfigContents(configTemplate.format, configFileContents, &configFile); err != nil {
+	var configMap map[string]any
+	if err := configTemplate.format.Unmarshal(configFileContents, &configMap); err != nil {
+		return fmt.Errorf("%s: %w", configTemplate.sourceAbsPath, err)
+	}
+	violations, err := configFileSchemaViolations(configMap)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configTemplate.sourceAbsPath, err)
+	}
+	if c.configFileSchemaWarningsEnabled(configMap) {
+		c.warnConfigFileSchemaViolations(configTemplate.sourceAbsPath, violations)
+	}
+	if err := c.decodeConfigMap(configMap, &ConfigFile{}); err != nil {
 		return fmt.Errorf("%s: %w", configTemplate.sourceAbsPath, err)
 	}
 
@@ -923,9 +1117,23 @@ func (c *Config) createAndReloadConfigFile(cmd *cobra.Command) error {
 		return err
 	}
 
-	// Reload the config.
-	if err := c.decodeConfigContents(configTemplate.format, configFileContents, &c.ConfigFile); err != nil {
-		return fmt.Errorf("%s: %w", configTemplate.sourceAbsPath, err)
+	// Reload the config from all config sources, in the same way as readConfig.
+	// The generated contents are used for the config file as it is not written
+	// in dry-run mode.
+	configSources, err := c.readConfigSourcesWithConfigFileData(configPath, configTemplate.format, configFileContents)
+	if err != nil {
+		c.warnConfigSourceDecodeErrorViolations(err)
+		return err
+	}
+	if err := c.decodeConfigMap(mergeConfigSources(configSources), &c.ConfigFile); err != nil {
+		return err
+	}
+	c.applyProfile()
+	if err := c.applyConfigEnv(); err != nil {
+		return err
+	}
+	if err := c.setSourceWriteDir(cmd); err != nil {
+		return err
 	}
 
 	if err := c.setEncryption(); err != nil {
@@ -962,6 +1170,9 @@ func (c *Config) createConfigFileContents(filename chezmoi.RelPath, data []byte,
 		"writeToStdout":         c.writeToStdout,
 	}
 	chezmoi.RecursiveMerge(c.templateFuncs, initTemplateFuncs)
+	if c.Template.Restricted {
+		c.restrictTemplateFuncs()
+	}
 
 	tmpl, err := chezmoi.ParseTemplate(filename.String(), data, chezmoi.TemplateOptions{
 		Funcs:   c.templateFuncs,
@@ -982,46 +1193,16 @@ func (c *Config) createConfigFileContents(filename chezmoi.RelPath, data []byte,
 // Directory Specification.
 func (c *Config) defaultConfigFile(fileSystem vfs.FS, bds *xdg.BaseDirectorySpecification) (chezmoi.AbsPath, error) {
 	// Search XDG Base Directory Specification config directories first.
-CONFIG_DIR:
 	for _, configDir := range bds.ConfigDirs {
 		configDirAbsPath, err := chezmoi.NewAbsPathFromExtPath(configDir, c.homeDirAbsPath)
 		if err != nil {
 			return chezmoi.EmptyAbsPath, err
 		}
-
-		dirEntries, err := fileSystem.ReadDir(configDirAbsPath.JoinString("chezmoi").String())
-		switch {
-		case errors.Is(err, fs.ErrNotExist):
-			continue CONFIG_DIR
+		switch configFileAbsPath, err := findConfigFile(fileSystem, configDirAbsPath.JoinString("chezmoi")); {
 		case err != nil:
 			return chezmoi.EmptyAbsPath, err
-		}
-
-		dirEntryNames := chezmoiset.NewWithCapacity[string](len(dirEntries))
-		for _, dirEntry := range dirEntries {
-			dirEntryNames.Add(dirEntry.Name())
-		}
-
-		var names []string
-		for _, extension := range chezmoi.FormatExtensions {
-			name := "chezmoi." + extension
-			if dirEntryNames.Contains(name) {
-				names = append(names, name)
-			}
-		}
-
-		switch len(names) {
-		case 0:
-			// Do nothing.
-		case 1:
-			return configDirAbsPath.JoinString("chezmoi", names[0]), nil
-		default:
-			configFileAbsPathStrs := make([]string, 0, len(names))
-			for _, name := range names {
-				configFileAbsPathStr := configDirAbsPath.JoinString("chezmoi", name)
-				configFileAbsPathStrs = append(configFileAbsPathStrs, configFileAbsPathStr.String())
-			}
-			return chezmoi.EmptyAbsPath, fmt.Errorf("multiple config files: %s", englishList(configFileAbsPathStrs))
+		case !configFileAbsPath.IsEmpty():
+			return configFileAbsPath, nil
 		}
 	}
 
@@ -1042,25 +1223,28 @@ func (c *Config) decodeConfigContents(format chezmoi.Format, contents []byte, co
 	return c.decodeConfigMap(configMap, configFile)
 }
 
-// decodeConfigFile decodes the config file at configFileAbsPath into
-// configFile.
-func (c *Config) decodeConfigFile(configFileAbsPath chezmoi.AbsPath, configFile *ConfigFile) error {
-	var format chezmoi.Format
+// configFileFormat returns the format of the config file at configFileAbsPath.
+func (c *Config) configFileFormat(configFileAbsPath chezmoi.AbsPath) (chezmoi.Format, error) {
 	switch formatStr := c.configFormat.String(); formatStr {
 	case "":
-		var err error
-		format, err = chezmoi.FormatFromAbsPath(configFileAbsPath)
-		if err != nil {
-			return err
-		}
+		return chezmoi.FormatFromAbsPath(configFileAbsPath)
 	case formatJSON:
-		format = chezmoi.FormatJSON
+		return chezmoi.FormatJSON, nil
 	case formatTOML:
-		format = chezmoi.FormatTOML
+		return chezmoi.FormatTOML, nil
 	case formatYAML:
-		format = chezmoi.FormatYAML
+		return chezmoi.FormatYAML, nil
 	default:
-		return fmt.Errorf("%s: invalid format", formatStr)
+		return nil, fmt.Errorf("%s: invalid format", formatStr)
+	}
+}
+
+// decodeConfigFile decodes the config file at configFileAbsPath into
+// configFile.
+func (c *Config) decodeConfigFile(configFileAbsPath chezmoi.AbsPath, configFile *ConfigFile) error {
+	format, err := c.configFileFormat(configFileAbsPath)
+	if err != nil {
+		return err
 	}
 
 	configFileContents, err := c.fileSystem.ReadFile(configFileAbsPath.String())
@@ -1092,6 +1276,7 @@ func (c *Config) decodeConfigMap(configMap map[string]any, configFile *ConfigFil
 			chezmoi.StringToAbsPathHookFunc(),
 			StringOrBoolToAutoBoolHookFunc(),
 			StringToChoiceFlagHookFunc(),
+			sourceDirLayersHookFunc(&configFile.SourceLayerAbsPaths),
 		),
 		Result: configFile,
 	})
@@ -1101,6 +1286,46 @@ func (c *Config) decodeConfigMap(configMap map[string]any, configFile *ConfigFil
 	return decoder.Decode(configMap)
 }
 
+// sourceDirLayersHookFunc returns a
+// github.com/go-viper/mapstructure/v2.DecodeHookFunc that decodes a list-valued
+// sourceDir into *sourceLayerAbsPaths. A string-valued sourceDir clears
+// *sourceLayerAbsPaths.
+func sourceDirLayersHookFunc(sourceLayerAbsPaths *[]chezmoi.AbsPath) mapstructure.DecodeHookFunc {
+	return func(from, to reflect.Type, data any) (any, error) {
+		if to != reflect.TypeFor[ConfigFile]() {
+			return data, nil
+		}
+		configMap, ok := data.(map[string]any)
+		if !ok {
+			return data, nil
+		}
+		switch sourceDir := configMap["sourceDir"].(type) {
+		case string:
+			*sourceLayerAbsPaths = nil
+			return data, nil
+		case []any:
+			layerAbsPaths := make([]chezmoi.AbsPath, 0, len(sourceDir))
+			for _, layer := range sourceDir {
+				layerStr, ok := layer.(string)
+				if !ok {
+					return nil, fmt.Errorf("sourceDir: expected a string, got a %T", layer)
+				}
+				var layerAbsPath chezmoi.AbsPath
+				if err := layerAbsPath.Set(layerStr); err != nil {
+					return nil, fmt.Errorf("sourceDir: %w", err)
+				}
+				layerAbsPaths = append(layerAbsPaths, layerAbsPath)
+			}
+			*sourceLayerAbsPaths = layerAbsPaths
+			configMap = maps.Clone(configMap)
+			delete(configMap, "sourceDir")
+			return configMap, nil
+		default:
+			return data, nil
+		}
+	}
+}
+
 // defaultPreApplyFunc is the default pre-apply function. If the target entry
 // has changed since chezmoi last wrote it then it prompts the user for the
 // action to take.
@@ -1366,6 +1591,55 @@ func (c *Config) diffFile(
 	)
 }
 
+// editConfigFile reads the config file, calls edit with its format and
+// contents, and writes the edited contents back to the config file after
+// checking that they are valid. It refuses to edit a config file that is
+// generated from a config file template unless --force is set.
+func (c *Config) editConfigFile(edit func(format chezmoi.Format, data []byte) ([]byte, error)) error {
+	configFileAbsPath, err := c.getConfigFileAbsPath()
+	if err != nil {
+		return err
+	}
+	if !c.force {
+		switch configTemplate, err := c.findConfigTemplate(); {
+		case err != nil:
+			return err
+		case configTemplate != nil:
+			return fmt.Errorf(
+				"%s: generated from %s, edit the template or use --force",
+				configFileAbsPath,
+				configTemplate.sourceAbsPath,
+			)
+		}
+	}
+	format, err := c.configFileFormat(configFileAbsPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.baseSystem.ReadFile(configFileAbsPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	data, err = edit(format, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configFileAbsPath, err)
+	}
+
+	var configMap map[string]any
+	if err := format.Unmarshal(data, &configMap); err != nil {
+		return fmt.Errorf("%s: %w", configFileAbsPath, err)
+	}
+	if err := c.decodeConfigMap(configMap, &ConfigFile{}); err != nil {
+		return fmt.Errorf("%s: %w", configFileAbsPath, err)
+	}
+
+	if err := chezmoi.MkdirAll(c.baseSystem, configFileAbsPath.Dir(), fs.ModePerm); err != nil {
+		return err
+	}
+	return c.baseSystem.WriteFile(configFileAbsPath, data, 0o600)
+}
+
 // editor returns the path to the user's editor and any extra arguments.
 func (c *Config) editor(args []string) (string, []string, error) {
 	editCommand := c.Edit.Command
@@ -1379,7 +1653,7 @@ func (c *Config) editor(args []string) (string, []string, error) {
 	// Prefer $VISUAL over $EDITOR and fallback to the OS's default editor.
 	editCommand = cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), defaultEditor)
 
-	return parseCommand(editCommand, append(editArgs, args...))
+	return chezmoi.ParseCommand(editCommand, append(editArgs, args...))
 }
 
 // errorf writes an error to stderr.
@@ -1570,7 +1844,7 @@ func (c *Config) getDiffPagerCmd() (*exec.Cmd, error) {
 	var pagerCmd *exec.Cmd
 	if runtime.GOOS != "windows" && whitespaceRx.MatchString(pager) {
 		shellCommand, _ := shell.CurrentUserShell()
-		shellCommand, shellArgs, err := parseCommand(shellCommand, []string{"-c", pager})
+		shellCommand, shellArgs, err := chezmoi.ParseCommand(shellCommand, []string{"-c", pager})
 		if err != nil {
 			return nil, err
 		}
@@ -1676,6 +1950,42 @@ func (c *Config) getSourceDirAbsPath(options *getSourceDirAbsPathOptions) (chezm
 	return c.sourceDirAbsPath, c.sourceDirAbsPathErr
 }
 
+// sourceDirAbsPaths returns the source directory layers, or only the source
+// directory if sourceDir is not a list of layers.
+func (c *Config) sourceDirAbsPaths() ([]chezmoi.AbsPath, error) {
+	switch sourceLayerAbsPaths, err := c.getSourceLayerAbsPaths(); {
+	case err != nil:
+		return nil, err
+	case len(sourceLayerAbsPaths) > 0:
+		return sourceLayerAbsPaths, nil
+	}
+	sourceDirAbsPath, err := c.getSourceDirAbsPath(nil)
+	if err != nil {
+		return nil, err
+	}
+	return []chezmoi.AbsPath{sourceDirAbsPath}, nil
+}
+
+// getSourceLayerAbsPaths returns the source directory layers, using
+// .chezmoiroot in each layer if it exists.
+func (c *Config) getSourceLayerAbsPaths() ([]chezmoi.AbsPath, error) {
+	if len(c.SourceLayerAbsPaths) == 0 {
+		return nil, nil
+	}
+	sourceLayerAbsPaths := make([]chezmoi.AbsPath, 0, len(c.SourceLayerAbsPaths))
+	for _, sourceLayerAbsPath := range c.SourceLayerAbsPaths {
+		switch data, err := c.sourceSystem.ReadFile(sourceLayerAbsPath.JoinString(chezmoi.RootName)); {
+		case errors.Is(err, fs.ErrNotExist):
+			sourceLayerAbsPaths = append(sourceLayerAbsPaths, sourceLayerAbsPath)
+		case err != nil:
+			return nil, err
+		default:
+			sourceLayerAbsPaths = append(sourceLayerAbsPaths, sourceLayerAbsPath.JoinString(string(bytes.TrimSpace(data))))
+		}
+	}
+	return sourceLayerAbsPaths, nil
+}
+
 func (c *Config) getSourceState(ctx context.Context, cmd *cobra.Command) (*chezmoi.SourceState, error) {
 	if c.sourceState != nil || c.sourceStateErr != nil {
 		return c.sourceState, c.sourceStateErr
@@ -1707,6 +2017,7 @@ func (c *Config) getTemplateDataMap(cmd *cobra.Command) map[string]any {
 			"configFile": templateData.configFile,
 			"destDir":    templateData.destDir,
 			"executable": templateData.executable,
+			"facts":      templateData.facts(),
 			"flags": map[string]any{
 				"dryRun":    templateData.flags.dryRun,
 				"force":     templateData.flags.force,
@@ -1724,6 +2035,7 @@ func (c *Config) getTemplateDataMap(cmd *cobra.Command) map[string]any {
 			"osRelease":         templateData.osRelease,
 			"pathListSeparator": templateData.pathListSeparator,
 			"pathSeparator":     templateData.pathSeparator,
+			"profile":           templateData.profile,
 			"rawHomeDir":        templateData.rawHomeDir,
 			"sourceDir":         templateData.sourceDir,
 			"uid":               templateData.uid,
@@ -1904,6 +2216,12 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 	persistentFlags.Var(&c.Mode, "mode", "Mode")
 	persistentFlags.Var(&c.PersistentStateAbsPath, "persistent-state", "Set persistent state file")
 	persistentFlags.Var(&c.Progress, "progress", "Display progress bars")
+	persistentFlags.BoolVar(
+		&c.Template.Restricted,
+		"restricted-templates",
+		c.Template.Restricted,
+		"Block scripts and template functions with side effects",
+	)
 	persistentFlags.BoolVar(&c.Safe, "safe", c.Safe, "Safely replace files and symlinks")
 	persistentFlags.VarP(&c.SourceDirAbsPath, "source", "S", "Set source directory")
 	persistentFlags.Var(&c.UseBuiltinAge, "use-builtin-age", "Use builtin age")
@@ -1922,6 +2240,7 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 	persistentFlags.VarP(&c.outputAbsPath, "output", "o", "Write output to path instead of stdout")
 	persistentFlags.StringVar(&c.overrideData, "override-data", c.overrideData, "Override data")
 	persistentFlags.Var(&c.overrideDataFileAbsPath, "override-data-file", "Override data with file")
+	persistentFlags.StringVar(&c.profile, "profile", c.profile, "Select profile")
 	persistentFlags.VarP(&c.refreshExternals, "refresh-externals", "R", "Refresh external cache")
 	persistentFlags.Lookup("refresh-externals").NoOptDefVal = chezmoi.RefreshExternalsAlways.String()
 	persistentFlags.BoolVar(&c.sourcePath, "source-path", c.sourcePath, "Specify targets by source path")
@@ -1952,11 +2271,13 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 		c.newAgeKeygenCmd(),
 		c.newApplyCmd(),
 		c.newArchiveCmd(),
+		c.newCacheCmd(),
 		c.newCatCmd(),
 		c.newCatConfigCmd(),
 		c.newCDCmd(),
 		c.newChattrCmd(),
 		c.newCompletionCmd(),
+		c.newConfigCmd(),
 		c.newDataCmd(),
 		c.newDecryptCommand(),
 		c.newDestroyCmd(),
@@ -1971,6 +2292,7 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 		c.newEditEncryptedCmd(),
 		c.newEncryptCmd(),
 		c.newExecuteTemplateCmd(),
+		c.newExplainCmd(),
 		c.newForgetCmd(),
 		c.newGenerateCmd(),
 		c.newGitCmd(),
@@ -1979,19 +2301,23 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 		c.newInitCmd(),
 		c.newInternalTestCmd(),
 		c.newLicenseCmd(),
+		c.newLintCmd(),
 		c.newMackupCmd(),
 		c.newManagedCmd(),
 		c.newMergeCmd(),
 		c.newMergeAllCmd(),
+		c.newModulesCmd(),
 		c.newPurgeCmd(),
 		c.newReAddCmd(),
 		c.newRemoveCmd(),
+		c.newRenderMatrixCmd(),
 		c.newSSHCmd(),
 		c.newSecretCmd(),
 		c.newSourcePathCmd(),
 		c.newStateCmd(),
 		c.newStatusCmd(),
 		c.newTargetPathCmd(),
+		c.newTestCmd(),
 		c.newUnmanagedCmd(),
 		c.newUpdateCmd(),
 		c.newUpgradeCmd(),
@@ -2063,6 +2389,10 @@ func (c *Config) newSourceState(
 	if err != nil {
 		return nil, err
 	}
+	sourceLayerAbsPaths, err := c.getSourceLayerAbsPaths()
+	if err != nil {
+		return nil, err
+	}
 
 	if err := c.runHookPre(readSourceStateHookName); err != nil {
 		return nil, err
@@ -2088,9 +2418,14 @@ func (c *Config) newSourceState(
 		chezmoi.RecursiveMerge(priorityTemplateData, overrideData)
 	}
 
+	if c.Template.Restricted {
+		options = append([]chezmoi.SourceStateOption{chezmoi.WithRestricted(restrictedTemplateFuncNames)}, options...)
+	}
+
 	sourceState := chezmoi.NewSourceState(append([]chezmoi.SourceStateOption{
 		chezmoi.WithBaseSystem(c.baseSystem),
 		chezmoi.WithCacheDir(c.CacheDirAbsPath),
+		chezmoi.WithDataGeneratorCacheTTL(c.DataGenerators.CacheTTL),
 		chezmoi.WithDefaultTemplateDataFunc(func() map[string]any {
 			return c.getTemplateDataMap(cmd)
 		}),
@@ -2100,12 +2435,17 @@ func (c *Config) newSourceState(
 		chezmoi.WithInterpreters(c.Interpreters),
 		chezmoi.WithLogger(sourceStateLogger),
 		chezmoi.WithMode(c.Mode),
+		chezmoi.WithModules(c.Modules),
 		chezmoi.WithPriorityTemplateData(priorityTemplateData),
+		chezmoi.WithReadExternals(c.readExternals),
 		chezmoi.WithScriptTempDir(c.ScriptTempDir),
 		chezmoi.WithSourceDir(c.SourceDirAbsPath),
+		chezmoi.WithSourceLayers(sourceLayerAbsPaths),
+		chezmoi.WithSourceLayerTemplateFuncs(c.sourceLayerTemplateFuncs),
 		chezmoi.WithSystem(c.sourceSystem),
 		chezmoi.WithTemplateFuncs(c.templateFuncs),
 		chezmoi.WithTemplateOptions(c.Template.Options),
+		chezmoi.WithTemplateTracer(c.templateTracer),
 		chezmoi.WithUmask(c.Umask),
 		chezmoi.WithVersion(c.version),
 		chezmoi.WithWarnFunc(c.errorf),
@@ -2148,7 +2488,13 @@ func (c *Config) persistentPostRunRootE(cmd *cobra.Command, args []string) error
 				if err = format.Unmarshal(configFileContents, &config); err != nil {
 					// err is already set, do nothing.
 				} else {
-					err = c.decodeConfigMap(config, &ConfigFile{})
+					var violations []string
+					if violations, err = configFileSchemaViolations(config); err == nil {
+						if c.Warnings.ConfigFileSchema {
+							c.warnConfigFileSchemaViolations(configFileAbsPath, violations)
+						}
+						err = c.decodeConfigMap(config, &ConfigFile{})
+					}
 				}
 			}
 		}
@@ -2274,7 +2620,12 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		if err != nil {
 			return err
 		}
-		if err := c.readConfig(configFileAbsPath); err != nil {
+		// Errors in environment variables are not reported with the config
+		// file path.
+		switch err := c.readConfig(configFileAbsPath); {
+		case errors.As(err, new(*configEnvVarError)):
+			return fmt.Errorf("invalid config: %w", err)
+		case err != nil:
 			return fmt.Errorf("invalid config: %s: %w", configFileAbsPath, err)
 		}
 	}
@@ -2286,10 +2637,24 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		}
 	}
 
+	// The profile is validated after reading the config file as some commands
+	// ignore errors reading the config file.
+	if err := c.validateProfile(); err != nil {
+		return err
+	}
+
+	if err := c.setSourceWriteDir(cmd); err != nil {
+		return err
+	}
+
 	if c.force && c.Interactive {
 		return errors.New("the --force and --interactive flags are mutually exclusive")
 	}
 
+	if c.Template.Restricted {
+		c.restrictTemplateFuncs()
+	}
+
 	// Configure the logger.
 	var handler slog.Handler
 	if c.debug {
@@ -2386,10 +2751,7 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 	if !annotations.hasTag(modifiesSourceDirectory) {
 		c.sourceSystem = chezmoi.NewReadOnlySystem(c.sourceSystem)
 	}
-	if c.dryRun || annotations.hasTag(dryRun) {
-		c.sourceSystem = chezmoi.NewDryRunSystem(c.sourceSystem)
-		c.destSystem = chezmoi.NewDryRunSystem(c.destSystem)
-	}
+	var diffWriter io.Writer
 	if annotations.hasTag(outputsDiff) ||
 		c.Verbose && (annotations.hasTag(modifiesDestinationDirectory) || annotations.hasTag(modifiesSourceDirectory)) {
 		// If the user has configured a diff pager, then start it as a process.
@@ -2413,8 +2775,25 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 			c.diffPagerCmd = pagerCmd
 			c.diffPagerCmdStdin = lazyWriter
 		}
-		c.sourceSystem = c.newDiffSystem(c.sourceSystem, writer, c.SourceDirAbsPath)
-		c.destSystem = c.newDiffSystem(c.destSystem, writer, c.DestDirAbsPath)
+		diffWriter = writer
+	}
+	if c.dryRun || annotations.hasTag(dryRun) {
+		// Only run scripts that support dry-run mode if the user explicitly
+		// requested a dry run, not for commands that are always dry runs.
+		dryRunSystemOptions := []chezmoi.DryRunSystemOption{
+			chezmoi.DryRunSystemWithRunScripts(c.dryRun),
+		}
+		// Report which scripts are run only if scripts are included in diffs.
+		diffFilter := chezmoi.NewEntryTypeFilter(c.Diff.include.Bits(), c.Diff.Exclude.Bits())
+		if c.dryRun && c.Verbose && diffFilter.IncludeEntryTypeBits(chezmoi.EntryTypeScripts) {
+			dryRunSystemOptions = append(dryRunSystemOptions, chezmoi.DryRunSystemWithScriptWriter(diffWriter))
+		}
+		c.sourceSystem = chezmoi.NewDryRunSystem(c.sourceSystem)
+		c.destSystem = chezmoi.NewDryRunSystem(c.destSystem, dryRunSystemOptions...)
+	}
+	if diffWriter != nil {
+		c.sourceSystem = c.newDiffSystem(c.sourceSystem, diffWriter, c.SourceDirAbsPath)
+		c.destSystem = c.newDiffSystem(c.destSystem, diffWriter, c.DestDirAbsPath)
 	}
 
 	if err := c.setEncryption(); err != nil && !annotations.hasTag(doesNotRequireValidConfig) {
@@ -2511,6 +2890,7 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		"HOME_DIR":      templateData.homeDir,
 		"HOSTNAME":      templateData.hostname,
 		"OS":            templateData.os,
+		"PROFILE":       templateData.profile,
 		"RAW_HOME_DIR":  templateData.rawHomeDir,
 		"SOURCE_DIR":    templateData.sourceDir,
 		"UID":           templateData.uid,
@@ -2555,12 +2935,20 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 // none are found.
 func (c *Config) persistentStateFile() (chezmoi.AbsPath, error) {
 	if !c.PersistentStateAbsPath.IsEmpty() {
+		if c.profile != "" {
+			c.errorf("warning: %s: persistent state is shared by all profiles\n", c.PersistentStateAbsPath)
+		}
 		return c.PersistentStateAbsPath, nil
 	}
 	configFileAbsPath, err := c.getConfigFileAbsPath()
 	if err != nil {
 		return chezmoi.EmptyAbsPath, err
 	}
+	// Each profile has its own persistent state so that the state written by
+	// one profile does not cause spurious changes in another.
+	if c.profile != "" {
+		return configFileAbsPath.Dir().JoinString("chezmoistate-" + c.profile + ".boltdb"), nil
+	}
 	return configFileAbsPath.Dir().Join(persistentStateFileRelPath), nil
 }
 
@@ -2637,6 +3025,19 @@ func (c *Config) newTemplateData(cmd *cobra.Command) *templateData {
 		c.logger.Info("chezmoi.Kernel", slog.Any("err", err))
 	}
 
+	// Facts are only available on Linux. They are computed the first time
+	// that the template data are needed as they require reading many files.
+	facts := func() map[string]any { return nil }
+	if runtime.GOOS == "linux" {
+		facts = sync.OnceValue(func() map[string]any {
+			facts, err := chezmoi.Facts(c.fileSystem, os.LookupEnv, username)
+			if err != nil {
+				c.logger.Info("chezmoi.Facts", slog.Any("err", err))
+			}
+			return facts
+		})
+	}
+
 	var osRelease map[string]any
 	switch runtime.GOOS {
 	case "openbsd", "windows":
@@ -2666,6 +3067,7 @@ func (c *Config) newTemplateData(cmd *cobra.Command) *templateData {
 		configFile: configFileAbsPath.String(),
 		destDir:    c.DestDirAbsPath.String(),
 		executable: executable,
+		facts:      facts,
 		flags: templateDataFlags{
 			dryRun:    c.dryRun,
 			force:     c.force,
@@ -2683,6 +3085,7 @@ func (c *Config) newTemplateData(cmd *cobra.Command) *templateData {
 		osRelease:         osRelease,
 		pathListSeparator: string(os.PathListSeparator),
 		pathSeparator:     string(os.PathSeparator),
+		profile:           c.profile,
 		rawHomeDir:        rawHomeDir,
 		sourceDir:         sourceDirAbsPath.String(),
 		uid:               uid,
@@ -2698,14 +3101,31 @@ func (c *Config) newTemplateData(cmd *cobra.Command) *templateData {
 	}
 }
 
-// readConfig reads the config file, if it exists.
+// readConfig reads and merges the system config file, the config file, and the
+// config files in the chezmoi.d directory, if they exist, and applies the
+// selected profile.
 func (c *Config) readConfig(configFileAbsPath chezmoi.AbsPath) error {
-	switch err := c.decodeConfigFile(configFileAbsPath, &c.ConfigFile); {
-	case errors.Is(err, fs.ErrNotExist):
-		return nil
-	default:
+	configSources, err := c.readConfigSources(configFileAbsPath)
+	if err != nil {
+		c.warnConfigSourceDecodeErrorViolations(err)
+		return err
+	}
+	if err := c.decodeConfigMap(mergeConfigSources(configSources), &c.ConfigFile); err != nil {
+		return err
+	}
+	if c.Git.CommitMessageTemplate != "" && c.Git.CommitMessageTemplateFile != "" {
+		return errors.New("cannot specify both git.commitMessageTemplate and git.commitMessageTemplateFile")
+	}
+	c.applyProfile()
+	if err := c.applyConfigEnv(); err != nil {
 		return err
 	}
+	if c.Warnings.ConfigFileSchema {
+		for _, configSource := range configSources {
+			c.warnConfigFileSchemaViolations(configSource.absPath, configSource.violations)
+		}
+	}
+	return nil
 }
 
 // resetSourceState clears the cached source state, if any.
@@ -2733,6 +3153,54 @@ func (c *Config) run(dir chezmoi.AbsPath, name string, args []string) error {
 	return nil
 }
 
+// runReloads runs the commands in .chezmoireload files whose patterns match any
+// target changed during the apply recorded in applyContext. Reloads are treated
+// as scripts by filter.
+func (c *Config) runReloads(
+	targetSystem chezmoi.System,
+	sourceState *chezmoi.SourceState,
+	targetDirAbsPath chezmoi.AbsPath,
+	filter *chezmoi.EntryTypeFilter,
+	applyContext *chezmoi.ApplyContext,
+) error {
+	if !filter.IncludeEntryTypeBits(chezmoi.EntryTypeScripts) {
+		return nil
+	}
+
+	changedTargetRelPaths := make([]chezmoi.RelPath, 0, len(applyContext.Changed()))
+	for _, changedAbsPath := range applyContext.Changed() {
+		changedTargetRelPath, err := changedAbsPath.TrimDirPrefix(targetDirAbsPath)
+		if err != nil {
+			return err
+		}
+		changedTargetRelPaths = append(changedTargetRelPaths, changedTargetRelPath)
+	}
+
+	for _, reload := range sourceState.Reloads(changedTargetRelPaths) {
+		if c.dryRun || c.Verbose {
+			fmt.Fprintf(c.stdout, "reload %s\n", reload.Command)
+		}
+		name, args, err := chezmoi.ParseCommand(reload.Command, nil)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", reload.SourceAbsPath, reload.LineNumber, err)
+		}
+		cmd := exec.Command(name, args...)
+		dirRawAbsPath, err := c.baseSystem.RawPath(targetDirAbsPath)
+		if err != nil {
+			return err
+		}
+		cmd.Dir = dirRawAbsPath.String()
+		cmd.Stdin = c.stdin
+		cmd.Stdout = c.stdout
+		cmd.Stderr = c.stderr
+		if err := targetSystem.RunCmd(cmd); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", reload.SourceAbsPath, reload.LineNumber, reload.Command, err)
+		}
+	}
+
+	return nil
+}
+
 // runEditor runs the configured editor with args.
 func (c *Config) runEditor(args []string) error {
 	if err := c.persistentState.Close(); err != nil {
@@ -2877,6 +3345,12 @@ func (c *Config) setEncryption() error {
 		return fmt.Errorf("%s: unknown encryption", c.Encryption)
 	}
 
+	// Only cache secrets on disk if they are encrypted.
+	switch c.encryption.(type) {
+	case *chezmoi.AgeEncryption, *chezmoi.GPGEncryption:
+		c.secretCacheEnabled = true
+	}
+
 	if c.debug {
 		encryptionLogger := c.logger.With(slog.String(logComponentKey, logComponentValueEncryption))
 		c.encryption = chezmoi.NewDebugEncryption(c.encryption, encryptionLogger)
@@ -2907,6 +3381,25 @@ func (c *Config) setEnvironmentVariables() error {
 	return nil
 }
 
+// setSourceWriteDir sets the source directory to the layer that chezmoi
+// writes to if sourceDir is a list of layers. The --source flag overrides all
+// layers.
+func (c *Config) setSourceWriteDir(cmd *cobra.Command) error {
+	switch {
+	case len(c.SourceLayerAbsPaths) == 0:
+		return nil
+	case cmd.Flags().Changed("source"):
+		c.SourceLayerAbsPaths = nil
+	case c.SourceWriteDirAbsPath.IsEmpty():
+		c.SourceDirAbsPath = c.SourceLayerAbsPaths[len(c.SourceLayerAbsPaths)-1]
+	case !slices.Contains(c.SourceLayerAbsPaths, c.SourceWriteDirAbsPath):
+		return fmt.Errorf("%s: not a source directory layer", c.SourceWriteDirAbsPath)
+	default:
+		c.SourceDirAbsPath = c.SourceWriteDirAbsPath
+	}
+	return nil
+}
+
 // sourceAbsPaths returns the source absolute paths for each target path in
 // args.
 func (c *Config) sourceAbsPaths(sourceState *chezmoi.SourceState, args []string) ([]chezmoi.AbsPath, error) {
@@ -2918,8 +3411,7 @@ func (c *Config) sourceAbsPaths(sourceState *chezmoi.SourceState, args []string)
 	}
 	sourceAbsPaths := make([]chezmoi.AbsPath, 0, len(targetRelPaths))
 	for _, targetRelPath := range targetRelPaths {
-		sourceAbsPath := c.SourceDirAbsPath.Join(sourceState.MustEntry(targetRelPath).SourceRelPath().RelPath())
-		sourceAbsPaths = append(sourceAbsPaths, sourceAbsPath)
+		sourceAbsPaths = append(sourceAbsPaths, sourceState.SourceAbsPath(sourceState.MustEntry(targetRelPath)))
 	}
 	return sourceAbsPaths, nil
 }
@@ -3143,6 +3635,16 @@ func (c *Config) writePaths(paths []string, options writePathsOptions) error {
 	return c.writeOutputString(builder.String(), 0o666)
 }
 
+// traceTemplates starts tracing template execution and returns a function that
+// writes the trace to the standard error.
+func (c *Config) traceTemplates() func() error {
+	c.templateTracer = chezmoi.NewTemplateTracer(secretTemplateFuncNames)
+	return func() error {
+		_, err := c.templateTracer.WriteTo(c.stderr)
+		return err
+	}
+}
+
 // writeOutputString writes data to the configured output.
 func (c *Config) writeOutputString(data string, perm fs.FileMode) error {
 	return c.writeOutput([]byte(data), perm)
@@ -3178,6 +3680,7 @@ func newConfigFile(bds *xdg.BaseDirectorySpecification) ConfigFile {
 			auto: true,
 		},
 		Warnings: warningsConfig{
+			ConfigFileSchema:             true,
 			ConfigFileTemplateHasChanged: true,
 		},
 
@@ -3312,37 +3815,6 @@ func (f *ConfigFile) toMap() map[string]any {
 	return result
 }
 
-func parseCommand(command string, args []string) (string, []string, error) {
-	// If command is found, then return it.
-	if commandPath, err := chezmoi.LookPath(command); err == nil {
-		return commandPath, args, nil
-	}
-
-	// Otherwise, if the command contains spaces, parse it as a shell command.
-	if whitespaceRx.MatchString(command) {
-		var words []*syntax.Word
-		for word, err := range syntax.NewParser().WordsSeq(strings.NewReader(command)) {
-			if err != nil {
-				return "", nil, err
-			}
-			words = append(words, word)
-		}
-		switch fields, err := expand.Fields(&expand.Config{
-			Env: expand.FuncEnviron(os.Getenv),
-		}, words...); {
-		case err != nil:
-			return "", nil, err
-		case len(fields) > 1:
-			return fields[0], append(fields[1:], args...), nil
-		case len(fields) == 1:
-			return fields[0], args, nil
-		}
-	}
-
-	// Fallback to the command only.
-	return command, args, nil
-}
-
 // prependParentRelPaths returns a new slice of RelPaths where the parents of
 // each RelPath appear before each RelPath.
 func prependParentRelPaths(relPaths []chezmoi.RelPath) []chezmoi.RelPath {
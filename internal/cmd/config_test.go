@@ -9,7 +9,6 @@ import (
 	"reflect"
 	"runtime"
 	"slices"
-	"strconv"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
@@ -117,6 +116,10 @@ func TestConfigFileFormatRoundTrip(t *testing.T) {
 				Merge: mergeCmdConfig{
 					Args: []string{},
 				},
+				RenderMatrix: renderMatrixCmdConfig{
+					Profiles: []renderMatrixProfile{},
+					Stubs:    map[string]any{},
+				},
 				Update: updateCmdConfig{
 					Args: []string{},
 				},
@@ -130,43 +133,129 @@ func TestConfigFileFormatRoundTrip(t *testing.T) {
 	}
 }
 
-func TestParseCommand(t *testing.T) {
-	for i, tc := range []struct {
-		command         string
-		args            []string
-		expectedCommand string
-		expectedArgs    []string
-		expectedErr     bool
+func TestConfigEnvVars(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		environ               []string
+		expectedConfigEnvVars []configEnvVar
+		expectedErr           string
 	}{
 		{
-			command:         "chezmoi-editor",
-			expectedCommand: "chezmoi-editor",
+			name: "empty",
+			environ: []string{
+				"CHEZMOI_CONFIG_FILE=/home/user/.config/chezmoi/chezmoi.toml",
+				"CHEZMOI_CONFIG_GIT_AUTOCOMMIT=",
+				"HOME=/home/user",
+			},
+		},
+		{
+			name: "types",
+			environ: []string{
+				"CHEZMOI_CONFIG_UMASK=0o22",
+				"CHEZMOI_CONFIG_GIT_AUTOCOMMIT=false",
+				"CHEZMOI_CONFIG_AGE_IDENTITY=~/key.txt",
+				"CHEZMOI_CONFIG_DIFF_PAGER=less -R",
+			},
+			expectedConfigEnvVars: []configEnvVar{
+				{
+					name:  "CHEZMOI_CONFIG_AGE_IDENTITY",
+					key:   []string{"age", "identity"},
+					value: "~/key.txt",
+				},
+				{
+					name:  "CHEZMOI_CONFIG_DIFF_PAGER",
+					key:   []string{"diff", "pager"},
+					value: "less -R",
+				},
+				{
+					name:  "CHEZMOI_CONFIG_GIT_AUTOCOMMIT",
+					key:   []string{"git", "autocommit"},
+					value: false,
+				},
+				{
+					name:  "CHEZMOI_CONFIG_UMASK",
+					key:   []string{"umask"},
+					value: uint64(0o22),
+				},
+			},
+		},
+		{
+			name: "auto_bool",
+			environ: []string{
+				"CHEZMOI_CONFIG_COLOR=auto",
+				"CHEZMOI_CONFIG_PROGRESS=true",
+			},
+			expectedConfigEnvVars: []configEnvVar{
+				{
+					name:  "CHEZMOI_CONFIG_COLOR",
+					key:   []string{"color"},
+					value: "auto",
+				},
+				{
+					name:  "CHEZMOI_CONFIG_PROGRESS",
+					key:   []string{"progress"},
+					value: true,
+				},
+			},
+		},
+		{
+			name: "entry_type_set",
+			environ: []string{
+				"CHEZMOI_CONFIG_DIFF_EXCLUDE=scripts,externals",
+			},
+			expectedConfigEnvVars: []configEnvVar{
+				{
+					name:  "CHEZMOI_CONFIG_DIFF_EXCLUDE",
+					key:   []string{"diff", "exclude"},
+					value: []any{"scripts", "externals"},
+				},
+			},
+		},
+		{
+			name: "slice",
+			environ: []string{
+				"CHEZMOI_CONFIG_PAGERARGS=-R,-F",
+			},
+			expectedConfigEnvVars: []configEnvVar{
+				{
+					name:  "CHEZMOI_CONFIG_PAGERARGS",
+					key:   []string{"pagerArgs"},
+					value: []any{"-R", "-F"},
+				},
+			},
+		},
+		{
+			name:        "invalid_bool",
+			environ:     []string{"CHEZMOI_CONFIG_GIT_AUTOCOMMIT=maybe"},
+			expectedErr: `CHEZMOI_CONFIG_GIT_AUTOCOMMIT: strconv.ParseBool: parsing "maybe": invalid syntax`,
 		},
 		{
-			command:         `chezmoi-editor -f --nomru -c "au VimLeave * !open -a Terminal"`,
-			expectedCommand: "chezmoi-editor",
-			expectedArgs:    []string{"-f", "--nomru", "-c", "au VimLeave * !open -a Terminal"},
+			name:        "unknown_key",
+			environ:     []string{"CHEZMOI_CONFIG_GIT_UNKNOWN=true"},
+			expectedErr: "CHEZMOI_CONFIG_GIT_UNKNOWN: unknown config key",
 		},
 		{
-			command:         `"chezmoi editor" $CHEZMOI_TEST_VAR`,
-			args:            []string{"extra-arg"},
-			expectedCommand: "chezmoi editor",
-			expectedArgs:    []string{"chezmoi-test-value", "extra-arg"},
+			name:        "not_a_leaf",
+			environ:     []string{"CHEZMOI_CONFIG_GIT=true"},
+			expectedErr: "CHEZMOI_CONFIG_GIT: unknown config key",
 		},
 		{
-			command:     `"chezmoi editor`,
-			expectedErr: true,
+			name:        "map",
+			environ:     []string{"CHEZMOI_CONFIG_DATA=true"},
+			expectedErr: "CHEZMOI_CONFIG_DATA: cannot be set by an environment variable",
 		},
 	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			t.Setenv("CHEZMOI_TEST_VAR", "chezmoi-test-value")
-			actualCommand, actualArgs, err := parseCommand(tc.command, tc.args)
-			if tc.expectedErr {
-				assert.Error(t, err)
-			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tc.expectedCommand, actualCommand)
-				assert.Equal(t, tc.expectedArgs, actualArgs)
+		t.Run(tc.name, func(t *testing.T) {
+			actualConfigEnvVars, err := configEnvVars(tc.environ)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedConfigEnvVars, actualConfigEnvVars)
+			c := &Config{}
+			for _, configEnvVar := range actualConfigEnvVars {
+				assert.NoError(t, c.decodeConfigMap(nestedConfigMap(configEnvVar.key, configEnvVar.value), &c.ConfigFile))
 			}
 		})
 	}
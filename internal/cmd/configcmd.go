@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+type configCmdConfig struct {
+	get configGetCmdConfig
+}
+
+type configGetCmdConfig struct {
+	format *choiceFlag
+}
+
+func (c *Config) newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		GroupID: groupIDAdvanced,
+		Use:     "config",
+		Args:    cobra.NoArgs,
+		Short:   "Get and set values in the config file",
+		Long:    mustLongHelp("config"),
+		Example: example("config"),
+		Annotations: newAnnotations(
+			persistentStateModeNone,
+		),
+	}
+
+	configGetCmd := &cobra.Command{
+		Use:               "get key",
+		Short:             "Print the value of a config key",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE:              c.runConfigGetCmd,
+		Annotations: newAnnotations(
+			persistentStateModeNone,
+		),
+	}
+	configGetCmd.Flags().VarP(c.config.get.format, "format", "f", "Output format")
+	must(configGetCmd.RegisterFlagCompletionFunc("format", c.config.get.format.FlagCompletionFunc()))
+	configCmd.AddCommand(configGetCmd)
+
+	configSetCmd := &cobra.Command{
+		Use:               "set key value",
+		Short:             "Set the value of a config key in the config file",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE:              c.runConfigSetCmd,
+		Annotations: newAnnotations(
+			doesNotRequireValidConfig,
+			modifiesConfigFile,
+			persistentStateModeNone,
+			requiresConfigDirectory,
+		),
+	}
+	configCmd.AddCommand(configSetCmd)
+
+	configUnsetCmd := &cobra.Command{
+		Use:               "unset key",
+		Short:             "Remove a config key from the config file",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE:              c.runConfigUnsetCmd,
+		Annotations: newAnnotations(
+			doesNotRequireValidConfig,
+			modifiesConfigFile,
+			persistentStateModeNone,
+			requiresConfigDirectory,
+		),
+	}
+	configCmd.AddCommand(configUnsetCmd)
+
+	return configCmd
+}
+
+func (c *Config) runConfigGetCmd(cmd *cobra.Command, args []string) error {
+	key, _, err := parseConfigKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Round trip the config through JSON to get the values that would be
+	// written to a config file.
+	data, err := chezmoi.FormatJSON.Marshal(c.ConfigFile)
+	if err != nil {
+		return err
+	}
+	var value any
+	if err := chezmoi.FormatJSON.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	for _, segment := range key {
+		configMap, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: not set", args[0])
+		}
+		if value, ok = configMap[segment]; !ok {
+			return fmt.Errorf("%s: not set", args[0])
+		}
+	}
+
+	if valueStr, ok := value.(string); ok {
+		return c.writeOutputString(valueStr+"\n", 0o666)
+	}
+	return c.marshal(cmp.Or(c.config.get.format.String(), c.Format.String()), value)
+}
+
+func (c *Config) runConfigSetCmd(cmd *cobra.Command, args []string) error {
+	key, fieldType, err := parseConfigKey(args[0])
+	if err != nil {
+		return err
+	}
+	if fieldType.Kind() == reflect.Map || hasFieldsWithMapstructureTags(fieldType) {
+		return fmt.Errorf("%s: cannot set a table", args[0])
+	}
+	value, err := parseConfigValue(fieldType, args[1])
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return c.editConfigFile(func(format chezmoi.Format, data []byte) ([]byte, error) {
+		return setConfigFileValue(format, data, key, value)
+	})
+}
+
+func (c *Config) runConfigUnsetCmd(cmd *cobra.Command, args []string) error {
+	key, _, err := parseConfigKey(args[0])
+	if err != nil {
+		return err
+	}
+	return c.editConfigFile(func(format chezmoi.Format, data []byte) ([]byte, error) {
+		return unsetConfigFileValue(format, data, key)
+	})
+}
+
+// parseConfigKey parses key, a dot-separated config file key, and returns its
+// canonical segments and the type of its value. Keys of struct fields are
+// matched case insensitively, keys of maps are used as is.
+func parseConfigKey(key string) ([]string, reflect.Type, error) {
+	segments := strings.Split(key, ".")
+	canonicalKey := make([]string, 0, len(segments))
+	fieldType := reflect.TypeFor[ConfigFile]()
+SEGMENT:
+	for _, segment := range segments {
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		switch {
+		case segment == "":
+		case hasFieldsWithMapstructureTags(fieldType):
+			for field := range fieldsWithMapstructureTags(fieldType) {
+				if fieldKey := mapstructureKey(field); strings.EqualFold(fieldKey, segment) {
+					canonicalKey = append(canonicalKey, fieldKey)
+					fieldType = field.Type
+					continue SEGMENT
+				}
+			}
+		case fieldType.Kind() == reflect.Map && fieldType.Key().Kind() == reflect.String:
+			canonicalKey = append(canonicalKey, segment)
+			fieldType = fieldType.Elem()
+			continue SEGMENT
+		case fieldType.Kind() == reflect.Interface:
+			canonicalKey = append(canonicalKey, segment)
+			continue SEGMENT
+		}
+		return nil, nil, fmt.Errorf("%s: unknown config key", key)
+	}
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	return canonicalKey, fieldType, nil
+}
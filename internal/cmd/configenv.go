@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"errors"
+	"iter"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+// configEnvVarPrefix is the prefix of environment variables that override
+// config file keys.
+const configEnvVarPrefix = "CHEZMOI_CONFIG_"
+
+// configFileEnvVar is the environment variable that chezmoi sets to the path of
+// the config file. It shares configEnvVarPrefix but is not an override.
+const configFileEnvVar = "CHEZMOI_CONFIG_FILE"
+
+// A configEnvVar is an environment variable that overrides a config file key.
+type configEnvVar struct {
+	name  string
+	key   []string
+	value any
+}
+
+// A configEnvVarError is an error in an environment variable that overrides a
+// config file key.
+type configEnvVarError struct {
+	name string
+	err  error
+}
+
+func (e *configEnvVarError) Error() string {
+	return e.name + ": " + e.err.Error()
+}
+
+func (e *configEnvVarError) Unwrap() error {
+	return e.err
+}
+
+// applyConfigEnv applies the config file overrides in the environment to c.
+func (c *Config) applyConfigEnv() error {
+	configEnvVars, err := configEnvVars(os.Environ())
+	if err != nil {
+		return err
+	}
+	for _, configEnvVar := range configEnvVars {
+		if err := c.decodeConfigMap(nestedConfigMap(configEnvVar.key, configEnvVar.value), &c.ConfigFile); err != nil {
+			return &configEnvVarError{name: configEnvVar.name, err: err}
+		}
+		if c.ConfigEnv == nil {
+			c.ConfigEnv = make(map[string]string)
+		}
+		c.ConfigEnv[strings.Join(configEnvVar.key, ".")] = configEnvVar.name
+	}
+	return nil
+}
+
+// configEnvVars returns the config file overrides in environ, sorted by name.
+// Variables with empty values are ignored.
+func configEnvVars(environ []string) ([]configEnvVar, error) {
+	var configEnvVars []configEnvVar
+	for _, keyValue := range environ {
+		name, value, ok := strings.Cut(keyValue, "=")
+		if !ok || value == "" || !strings.HasPrefix(name, configEnvVarPrefix) || name == configFileEnvVar {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(name, configEnvVarPrefix), "_")
+		key, fieldType, ok := configFileKey(reflect.TypeFor[ConfigFile](), segments)
+		if !ok {
+			return nil, &configEnvVarError{name: name, err: errors.New("unknown config key")}
+		}
+		if fieldType.Kind() == reflect.Map {
+			return nil, &configEnvVarError{name: name, err: errors.New("cannot be set by an environment variable")}
+		}
+		typedValue, err := parseConfigValue(fieldType, value)
+		if err != nil {
+			return nil, &configEnvVarError{name: name, err: err}
+		}
+		configEnvVars = append(configEnvVars, configEnvVar{
+			name:  name,
+			key:   key,
+			value: typedValue,
+		})
+	}
+	slices.SortFunc(configEnvVars, func(a, b configEnvVar) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return configEnvVars, nil
+}
+
+// configFileKey returns the config file key and type of the field in
+// structType identified by segments, where each segment is the name of a
+// field's mapstructure tag, in any case.
+func configFileKey(structType reflect.Type, segments []string) ([]string, reflect.Type, bool) {
+	for field := range fieldsWithMapstructureTags(structType) {
+		key := mapstructureKey(field)
+		if !strings.EqualFold(key, segments[0]) {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		hasNestedKeys := hasFieldsWithMapstructureTags(fieldType)
+		switch {
+		case len(segments) == 1 && !hasNestedKeys:
+			return []string{key}, fieldType, true
+		case len(segments) > 1 && hasNestedKeys:
+			if nestedKey, nestedFieldType, ok := configFileKey(fieldType, segments[1:]); ok {
+				return append([]string{key}, nestedKey...), nestedFieldType, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// fieldsWithMapstructureTags yields the fields of structType that have
+// mapstructure tags.
+func fieldsWithMapstructureTags(structType reflect.Type) iter.Seq[reflect.StructField] {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range structType.NumField() {
+			field := structType.Field(i)
+			if tag := field.Tag.Get("mapstructure"); tag == "" || tag == "-" {
+				continue
+			}
+			if !yield(field) {
+				return
+			}
+		}
+	}
+}
+
+// hasFieldsWithMapstructureTags returns whether t is a struct with any fields
+// with mapstructure tags.
+func hasFieldsWithMapstructureTags(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for range fieldsWithMapstructureTags(t) {
+		return true
+	}
+	return false
+}
+
+// mapstructureKey returns the key of field in a config map.
+func mapstructureKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	return key
+}
+
+// nestedConfigMap returns a config map with value at key.
+func nestedConfigMap(key []string, value any) map[string]any {
+	configMap := map[string]any{
+		key[len(key)-1]: value,
+	}
+	for i := len(key) - 2; i >= 0; i-- {
+		configMap = map[string]any{
+			key[i]: configMap,
+		}
+	}
+	return configMap
+}
+
+// parseConfigValue parses value as a value of fieldType. Values of types that
+// are handled by decode hooks are returned as strings.
+func parseConfigValue(fieldType reflect.Type, value string) (any, error) {
+	switch fieldType {
+	case reflect.TypeFor[autoBool]():
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b, nil
+		}
+		return value, nil
+	case reflect.TypeFor[chezmoi.EntryTypeSet]():
+		return parseConfigList(value), nil
+	case reflect.TypeFor[time.Duration]():
+		return value, nil
+	}
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(value, 0, fieldType.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(value, 0, fieldType.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(value, fieldType.Bits())
+	case reflect.Slice:
+		return parseConfigList(value), nil
+	default:
+		return value, nil
+	}
+}
+
+// parseConfigList parses value as a comma-separated list.
+func parseConfigList(value string) []any {
+	if value == "" {
+		return []any{}
+	}
+	elems := strings.Split(value, ",")
+	list := make([]any, 0, len(elems))
+	for _, elem := range elems {
+		list = append(list, elem)
+	}
+	return list
+}
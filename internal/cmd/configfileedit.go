@@ -0,0 +1,608 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/tailscale/hujson"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+// defaultConfigFileIndent is the indent used for new keys when the config file
+// does not already contain indented keys.
+const defaultConfigFileIndent = "    "
+
+var tomlBareKeyRx = regexp.MustCompile(`\A[A-Za-z0-9_-]+\z`)
+
+// A tomlTable is a table in a TOML document.
+type tomlTable struct {
+	key        []string
+	start      int // Offset of the table header.
+	end        int // Offset of the end of the last expression in the table.
+	arrayTable bool
+}
+
+// A tomlKeyValue is a key/value pair in a TOML document.
+type tomlKeyValue struct {
+	key        []string // Full key, including the key of the enclosing table.
+	start      int
+	valueStart int
+	end        int
+	table      *tomlTable // Enclosing table, nil for the root table.
+}
+
+// A tomlDocument is the location of the tables and key/value pairs in a TOML
+// document.
+type tomlDocument struct {
+	data      []byte
+	tables    []*tomlTable
+	keyValues []*tomlKeyValue
+}
+
+// setConfigFileValue returns data, the contents of a config file in format,
+// with the value of key set to value. Comments and the order of keys are
+// preserved.
+func setConfigFileValue(format chezmoi.Format, data []byte, key []string, value any) ([]byte, error) {
+	switch format {
+	case chezmoi.FormatJSON, chezmoi.FormatJSONC:
+		return setJSONValue(data, key, value)
+	case chezmoi.FormatTOML:
+		return setTOMLValue(data, key, value)
+	case chezmoi.FormatYAML:
+		return setYAMLValue(data, key, value)
+	default:
+		return nil, fmt.Errorf("%s: unsupported format", format.Name())
+	}
+}
+
+// unsetConfigFileValue returns data, the contents of a config file in format,
+// with key and any keys nested under it removed. Comments and the order of the
+// remaining keys are preserved.
+func unsetConfigFileValue(format chezmoi.Format, data []byte, key []string) ([]byte, error) {
+	switch format {
+	case chezmoi.FormatJSON, chezmoi.FormatJSONC:
+		return unsetJSONValue(data, key)
+	case chezmoi.FormatTOML:
+		return unsetTOMLValue(data, key)
+	case chezmoi.FormatYAML:
+		return unsetYAMLValue(data, key)
+	default:
+		return nil, fmt.Errorf("%s: unsupported format", format.Name())
+	}
+}
+
+// hasConfigKeyPrefix returns whether key starts with prefix. Config file keys
+// are case insensitive.
+func hasConfigKeyPrefix(key, prefix []string) bool {
+	if len(prefix) > len(key) {
+		return false
+	}
+	for i, segment := range prefix {
+		if !strings.EqualFold(key[i], segment) {
+			return false
+		}
+	}
+	return true
+}
+
+// lineEnd returns the offset of the start of the line after offset in data.
+func lineEnd(data []byte, offset int) int {
+	if index := bytes.IndexByte(data[offset:], '\n'); index != -1 {
+		return offset + index + 1
+	}
+	return len(data)
+}
+
+// lineStart returns the offset of the start of the line containing offset in
+// data.
+func lineStart(data []byte, offset int) int {
+	return bytes.LastIndexByte(data[:offset], '\n') + 1
+}
+
+// insertLines returns data with lines inserted at offset, which must be at the
+// start of a line or the end of data.
+func insertLines(data []byte, offset int, lines string) []byte {
+	if offset > 0 && data[offset-1] != '\n' {
+		lines = "\n" + lines
+	}
+	return slices.Concat(data[:offset], []byte(lines), data[offset:])
+}
+
+// jsonIndent returns the indent of member, or defaultIndent if member is not on
+// its own line.
+func jsonIndent(member *hujson.ObjectMember, defaultIndent string) string {
+	beforeExtra := string(member.Name.BeforeExtra)
+	index := strings.LastIndexByte(beforeExtra, '\n')
+	if index == -1 || strings.TrimSpace(beforeExtra[index+1:]) != "" {
+		return defaultIndent
+	}
+	return beforeExtra[index+1:]
+}
+
+// jsonMemberIndex returns the index of the member of object with name, or -1 if
+// there is no such member.
+func jsonMemberIndex(object *hujson.Object, name string) int {
+	return slices.IndexFunc(object.Members, func(member hujson.ObjectMember) bool {
+		literal, ok := member.Name.Value.(hujson.Literal)
+		return ok && strings.EqualFold(literal.String(), name)
+	})
+}
+
+// newJSONValue returns value nested in objects with keys.
+func newJSONValue(keys []string, value hujson.ValueTrimmed, indent, indentUnit string) hujson.Value {
+	if len(keys) == 0 {
+		return hujson.Value{
+			BeforeExtra: hujson.Extra(" "),
+			Value:       value,
+		}
+	}
+	return hujson.Value{
+		BeforeExtra: hujson.Extra(" "),
+		Value: &hujson.Object{
+			Members: []hujson.ObjectMember{
+				{
+					Name: hujson.Value{
+						BeforeExtra: hujson.Extra("\n" + indent + indentUnit),
+						Value:       hujson.String(keys[0]),
+					},
+					Value: newJSONValue(keys[1:], value, indent+indentUnit, indentUnit),
+				},
+			},
+			AfterExtra: hujson.Extra("\n" + indent),
+		},
+	}
+}
+
+// parseJSONObject parses data as a JSON object, treating empty data as an empty
+// object.
+func parseJSONObject(data []byte) (hujson.Value, *hujson.Object, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{\n}\n")
+	}
+	root, err := hujson.Parse(data)
+	if err != nil {
+		return hujson.Value{}, nil, err
+	}
+	object, ok := root.Value.(*hujson.Object)
+	if !ok {
+		return hujson.Value{}, nil, errors.New("not an object")
+	}
+	return root, object, nil
+}
+
+func setJSONValue(data []byte, key []string, value any) ([]byte, error) {
+	root, object, err := parseJSONObject(data)
+	if err != nil {
+		return nil, err
+	}
+	valueData, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := hujson.Parse(valueData)
+	if err != nil {
+		return nil, err
+	}
+
+	indentUnit := defaultConfigFileIndent
+	if len(object.Members) > 0 {
+		indentUnit = jsonIndent(&object.Members[0], defaultConfigFileIndent)
+	}
+
+	indent := ""
+	for i, segment := range key {
+		memberIndent := indent + indentUnit
+		if len(object.Members) > 0 {
+			memberIndent = jsonIndent(&object.Members[len(object.Members)-1], memberIndent)
+		}
+
+		index := jsonMemberIndex(object, segment)
+		if index == -1 {
+			member := hujson.ObjectMember{
+				Name: hujson.Value{
+					BeforeExtra: hujson.Extra("\n" + memberIndent),
+					Value:       hujson.String(segment),
+				},
+				Value: newJSONValue(key[i+1:], newValue.Value, memberIndent, indentUnit),
+			}
+			if len(object.Members) == 0 {
+				object.AfterExtra = hujson.Extra("\n" + indent)
+			} else {
+				// Keep any comment at the end of the last member's line on
+				// that line.
+				afterExtra := string(object.AfterExtra)
+				if index := strings.LastIndexByte(afterExtra, '\n'); index != -1 {
+					member.Name.BeforeExtra = hujson.Extra(afterExtra[:index] + "\n" + memberIndent)
+					object.AfterExtra = hujson.Extra(afterExtra[index:])
+				}
+				// Preserve any trailing comma.
+				lastMember := &object.Members[len(object.Members)-1]
+				member.Value.AfterExtra = lastMember.Value.AfterExtra
+				lastMember.Value.AfterExtra = nil
+			}
+			object.Members = append(object.Members, member)
+			return root.Pack(), nil
+		}
+
+		member := &object.Members[index]
+		if i == len(key)-1 {
+			member.Value.Value = newValue.Value
+			return root.Pack(), nil
+		}
+		var ok bool
+		if object, ok = member.Value.Value.(*hujson.Object); !ok {
+			return nil, fmt.Errorf("%s: not an object", strings.Join(key[:i+1], "."))
+		}
+		indent = memberIndent
+	}
+	return root.Pack(), nil
+}
+
+func unsetJSONValue(data []byte, key []string) ([]byte, error) {
+	root, object, err := parseJSONObject(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, segment := range key {
+		index := jsonMemberIndex(object, segment)
+		if index == -1 {
+			return data, nil
+		}
+		if i == len(key)-1 {
+			if lastIndex := len(object.Members) - 1; index == lastIndex && index > 0 {
+				// Preserve any trailing comma.
+				object.Members[index-1].Value.AfterExtra = object.Members[index].Value.AfterExtra
+			}
+			object.Members = slices.Delete(object.Members, index, index+1)
+			return root.Pack(), nil
+		}
+		var ok bool
+		if object, ok = object.Members[index].Value.Value.(*hujson.Object); !ok {
+			return data, nil
+		}
+	}
+	return data, nil
+}
+
+// parseTOMLDocument returns the location of the tables and key/value pairs in
+// data.
+func parseTOMLDocument(data []byte) (*tomlDocument, error) {
+	document := &tomlDocument{
+		data: data,
+	}
+	var table *tomlTable
+	var p unstable.Parser
+	p.Reset(data)
+	for p.NextExpression() {
+		expression := p.Expression()
+		switch expression.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			key, keyStart, keyEnd := tomlKey(expression.Key())
+			arrayTable := expression.Kind == unstable.ArrayTable
+			start := bytes.LastIndexByte(data[:keyStart], '[')
+			end := keyEnd + bytes.IndexByte(data[keyEnd:], ']') + 1
+			if arrayTable {
+				start--
+				end++
+			}
+			table = &tomlTable{
+				key:        key,
+				start:      start,
+				end:        end,
+				arrayTable: arrayTable,
+			}
+			document.tables = append(document.tables, table)
+		case unstable.KeyValue:
+			key, _, keyEnd := tomlKey(expression.Key())
+			valueStart := keyEnd
+			for data[valueStart] == ' ' || data[valueStart] == '\t' || data[valueStart] == '=' {
+				valueStart++
+			}
+			start := int(expression.Raw.Offset)
+			end := start + int(expression.Raw.Length)
+			if table != nil {
+				key = slices.Concat(table.key, key)
+				table.end = end
+			}
+			document.keyValues = append(document.keyValues, &tomlKeyValue{
+				key:        key,
+				start:      start,
+				valueStart: valueStart,
+				end:        end,
+				table:      table,
+			})
+		}
+	}
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// indent returns the indent of key/value pairs in tables in d.
+func (d *tomlDocument) indent() string {
+	for _, keyValue := range d.keyValues {
+		if keyValue.table == nil {
+			continue
+		}
+		if indent := d.data[lineStart(d.data, keyValue.start):keyValue.start]; len(bytes.TrimSpace(indent)) == 0 {
+			return string(indent)
+		}
+	}
+	return defaultConfigFileIndent
+}
+
+// tomlKey returns the key and its start and end offsets from the key nodes in
+// iterator.
+func tomlKey(iterator unstable.Iterator) (key []string, start, end int) {
+	start = -1
+	for iterator.Next() {
+		node := iterator.Node()
+		key = append(key, string(node.Data))
+		if start == -1 {
+			start = int(node.Raw.Offset)
+		}
+		end = int(node.Raw.Offset + node.Raw.Length)
+	}
+	return key, start, end
+}
+
+// tomlKeyString returns key formatted as a TOML dotted key.
+func tomlKeyString(key []string) string {
+	segments := make([]string, 0, len(key))
+	for _, segment := range key {
+		if tomlBareKeyRx.MatchString(segment) {
+			segments = append(segments, segment)
+		} else {
+			segments = append(segments, `"`+strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(segment)+`"`)
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// tomlValueString returns value formatted as a TOML value.
+func tomlValueString(value any) (string, error) {
+	data, err := chezmoi.FormatTOML.Marshal(map[string]any{
+		"value": value,
+	})
+	if err != nil {
+		return "", err
+	}
+	valueStr, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "value = ")
+	if !ok {
+		return "", fmt.Errorf("%v: cannot format as TOML value", value)
+	}
+	return valueStr, nil
+}
+
+func setTOMLValue(data []byte, key []string, value any) ([]byte, error) {
+	document, err := parseTOMLDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	valueStr, err := tomlValueString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the key already exists then replace its value.
+	var lastRootKeyValue *tomlKeyValue
+	hasRootKey := false
+	for _, keyValue := range document.keyValues {
+		if keyValue.table != nil && keyValue.table.arrayTable {
+			continue
+		}
+		if len(keyValue.key) == len(key) && hasConfigKeyPrefix(keyValue.key, key) {
+			return slices.Concat(data[:keyValue.valueStart], []byte(valueStr), data[keyValue.end:]), nil
+		}
+		if keyValue.table == nil {
+			lastRootKeyValue = keyValue
+			hasRootKey = hasRootKey || strings.EqualFold(keyValue.key[0], key[0])
+		}
+	}
+
+	// Otherwise, add the key to the table with the longest matching key.
+	var table *tomlTable
+	for _, t := range document.tables {
+		if !t.arrayTable && len(t.key) < len(key) && hasConfigKeyPrefix(key, t.key) &&
+			(table == nil || len(t.key) > len(table.key)) {
+			table = t
+		}
+	}
+	switch {
+	case table != nil:
+		line := document.indent() + tomlKeyString(key[len(table.key):]) + " = " + valueStr + "\n"
+		return insertLines(data, lineEnd(data, table.end), line), nil
+	case len(key) > 1 && !hasRootKey:
+		lines := "[" + tomlKeyString(key[:len(key)-1]) + "]\n" +
+			document.indent() + tomlKeyString(key[len(key)-1:]) + " = " + valueStr + "\n"
+		if len(bytes.TrimSpace(data)) != 0 {
+			lines = "\n" + lines
+		}
+		return insertLines(data, len(data), lines), nil
+	default:
+		line := tomlKeyString(key) + " = " + valueStr + "\n"
+		switch {
+		case lastRootKeyValue != nil:
+			return insertLines(data, lineEnd(data, lastRootKeyValue.end), line), nil
+		case len(document.tables) > 0:
+			return insertLines(data, lineStart(data, document.tables[0].start), line+"\n"), nil
+		default:
+			return insertLines(data, len(data), line), nil
+		}
+	}
+}
+
+func unsetTOMLValue(data []byte, key []string) ([]byte, error) {
+	document, err := parseTOMLDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	type byteRange struct {
+		start, end int
+	}
+	var byteRanges []byteRange
+	for _, keyValue := range document.keyValues {
+		if hasConfigKeyPrefix(keyValue.key, key) {
+			byteRanges = append(byteRanges, byteRange{
+				start: lineStart(data, keyValue.start),
+				end:   lineEnd(data, keyValue.end),
+			})
+		}
+	}
+	for _, table := range document.tables {
+		if hasConfigKeyPrefix(table.key, key) {
+			byteRanges = append(byteRanges, byteRange{
+				start: lineStart(data, table.start),
+				end:   lineEnd(data, table.end),
+			})
+		}
+	}
+	slices.SortFunc(byteRanges, func(a, b byteRange) int {
+		return a.start - b.start
+	})
+
+	if len(byteRanges) == 0 {
+		return data, nil
+	}
+	result := make([]byte, 0, len(data))
+	offset := 0
+	for _, byteRange := range byteRanges {
+		if byteRange.start > offset {
+			result = append(result, data[offset:byteRange.start]...)
+		}
+		offset = max(offset, byteRange.end)
+	}
+	result = append(result, data[offset:]...)
+
+	// Remove any blank lines left at the end of the document.
+	if trimmedResult := bytes.TrimRight(result, "\n"); len(trimmedResult) < len(result) {
+		result = append(trimmedResult, '\n')
+	}
+	return result, nil
+}
+
+// yamlFileBytes returns the contents of file.
+func yamlFileBytes(file *ast.File) []byte {
+	data := file.String()
+	if !strings.HasSuffix(data, "\n") {
+		data += "\n"
+	}
+	return []byte(data)
+}
+
+// yamlMappingValueIndex returns the index of the value with key in mapping, or
+// -1 if there is no such value.
+func yamlMappingValueIndex(mapping *ast.MappingNode, key string) int {
+	return slices.IndexFunc(mapping.Values, func(mappingValue *ast.MappingValueNode) bool {
+		return strings.EqualFold(yamlKey(mappingValue), key)
+	})
+}
+
+// yamlKey returns the key of mappingValue.
+func yamlKey(mappingValue *ast.MappingValueNode) string {
+	if stringNode, ok := mappingValue.Key.(*ast.StringNode); ok {
+		return stringNode.Value
+	}
+	return mappingValue.Key.GetToken().Value
+}
+
+// parseYAMLMapping parses data as YAML and returns its first document's
+// mapping, or nil if the document is empty.
+func parseYAMLMapping(data []byte) (*ast.File, *ast.MappingNode, error) {
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(file.Docs) == 0 {
+		return file, nil, nil
+	}
+	switch body := file.Docs[0].Body.(type) {
+	case nil, *ast.CommentGroupNode:
+		return file, nil, nil
+	case *ast.MappingNode:
+		return file, body, nil
+	default:
+		return nil, nil, errors.New("not a mapping")
+	}
+}
+
+func setYAMLValue(data []byte, key []string, value any) ([]byte, error) {
+	file, mapping, err := parseYAMLMapping(data)
+	if err != nil {
+		return nil, err
+	}
+	if mapping == nil {
+		valueData, err := chezmoi.FormatYAML.Marshal(nestedConfigMap(key, value))
+		if err != nil {
+			return nil, err
+		}
+		return insertLines(data, len(data), string(valueData)), nil
+	}
+
+	pathBuilder := (&yaml.PathBuilder{}).Root()
+	for i, segment := range key {
+		index := yamlMappingValueIndex(mapping, segment)
+		if index == -1 {
+			valueData, err := chezmoi.FormatYAML.Marshal(nestedConfigMap(key[i:], value))
+			if err != nil {
+				return nil, err
+			}
+			if err := pathBuilder.Build().MergeFromReader(file, bytes.NewReader(valueData)); err != nil {
+				return nil, err
+			}
+			return yamlFileBytes(file), nil
+		}
+
+		mappingValue := mapping.Values[index]
+		pathBuilder = pathBuilder.Child(yamlKey(mappingValue))
+		if i == len(key)-1 {
+			valueData, err := chezmoi.FormatYAML.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			if err := pathBuilder.Build().ReplaceWithReader(file, bytes.NewReader(valueData)); err != nil {
+				return nil, err
+			}
+			return yamlFileBytes(file), nil
+		}
+		var ok bool
+		if mapping, ok = mappingValue.Value.(*ast.MappingNode); !ok {
+			return nil, fmt.Errorf("%s: not a mapping", strings.Join(key[:i+1], "."))
+		}
+	}
+	return yamlFileBytes(file), nil
+}
+
+func unsetYAMLValue(data []byte, key []string) ([]byte, error) {
+	file, mapping, err := parseYAMLMapping(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, segment := range key {
+		if mapping == nil {
+			return data, nil
+		}
+		index := yamlMappingValueIndex(mapping, segment)
+		if index == -1 {
+			return data, nil
+		}
+		if i == len(key)-1 {
+			mapping.Values = slices.Delete(mapping.Values, index, index+1)
+			return yamlFileBytes(file), nil
+		}
+		mapping, _ = mapping.Values[index].Value.(*ast.MappingNode)
+	}
+	return data, nil
+}
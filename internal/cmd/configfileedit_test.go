@@ -0,0 +1,362 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+	"chezmoi.io/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestSetConfigFileValue(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		format   chezmoi.Format
+		data     string
+		key      []string
+		value    any
+		expected string
+	}{
+		{
+			name:   "json_empty",
+			format: chezmoi.FormatJSON,
+			key:    []string{"git", "autoCommit"},
+			value:  true,
+			expected: chezmoitest.JoinLines(
+				`{`,
+				`    "git": {`,
+				`        "autoCommit": true`,
+				`    }`,
+				`}`,
+			),
+		},
+		{
+			name:   "json_replace",
+			format: chezmoi.FormatJSON,
+			data: chezmoitest.JoinLines(
+				`{`,
+				`  "git": {`,
+				`    "autoCommit": false`,
+				`  }`,
+				`}`,
+			),
+			key:   []string{"git", "autocommit"},
+			value: true,
+			expected: chezmoitest.JoinLines(
+				`{`,
+				`  "git": {`,
+				`    "autoCommit": true`,
+				`  }`,
+				`}`,
+			),
+		},
+		{
+			name:   "json_add",
+			format: chezmoi.FormatJSON,
+			data: chezmoitest.JoinLines(
+				`{`,
+				`  "git": {`,
+				`    "autoCommit": false`,
+				`  }`,
+				`}`,
+			),
+			key:   []string{"diff", "pager"},
+			value: "less",
+			expected: chezmoitest.JoinLines(
+				`{`,
+				`  "git": {`,
+				`    "autoCommit": false`,
+				`  },`,
+				`  "diff": {`,
+				`    "pager": "less"`,
+				`  }`,
+				`}`,
+			),
+		},
+		{
+			name:   "jsonc_comments",
+			format: chezmoi.FormatJSONC,
+			data: chezmoitest.JoinLines(
+				`{`,
+				`    // Git configuration`,
+				`    "git": {`,
+				`        "autoCommit": false, // commit`,
+				`    },`,
+				`}`,
+			),
+			key:   []string{"git", "autoPush"},
+			value: true,
+			expected: chezmoitest.JoinLines(
+				`{`,
+				`    // Git configuration`,
+				`    "git": {`,
+				`        "autoCommit": false, // commit`,
+				`        "autoPush": true,`,
+				`    },`,
+				`}`,
+			),
+		},
+		{
+			name:   "toml_empty",
+			format: chezmoi.FormatTOML,
+			key:    []string{"git", "autoCommit"},
+			value:  true,
+			expected: chezmoitest.JoinLines(
+				`[git]`,
+				`    autoCommit = true`,
+			),
+		},
+		{
+			name:   "toml_replace",
+			format: chezmoi.FormatTOML,
+			data: chezmoitest.JoinLines(
+				`# Config`,
+				`[git]`,
+				`  autoCommit = false # commit`,
+				`  autoPush = true`,
+			),
+			key:   []string{"git", "autocommit"},
+			value: true,
+			expected: chezmoitest.JoinLines(
+				`# Config`,
+				`[git]`,
+				`  autoCommit = true # commit`,
+				`  autoPush = true`,
+			),
+		},
+		{
+			name:   "toml_add_to_table",
+			format: chezmoi.FormatTOML,
+			data: chezmoitest.JoinLines(
+				`[git]`,
+				`  autoCommit = false`,
+				``,
+				`[diff]`,
+				`  exclude = ["scripts"]`,
+			),
+			key:   []string{"git", "autoPush"},
+			value: true,
+			expected: chezmoitest.JoinLines(
+				`[git]`,
+				`  autoCommit = false`,
+				`  autoPush = true`,
+				``,
+				`[diff]`,
+				`  exclude = ["scripts"]`,
+			),
+		},
+		{
+			name:   "toml_add_nested_to_table",
+			format: chezmoi.FormatTOML,
+			data: chezmoitest.JoinLines(
+				`[data]`,
+				`    email = "me@home.org"`,
+			),
+			key:   []string{"data", "work", "email"},
+			value: "me@work.com",
+			expected: chezmoitest.JoinLines(
+				`[data]`,
+				`    email = "me@home.org"`,
+				`    work.email = "me@work.com"`,
+			),
+		},
+		{
+			name:   "toml_add_root",
+			format: chezmoi.FormatTOML,
+			data: chezmoitest.JoinLines(
+				`# Config`,
+				`[git]`,
+				`    autoCommit = false`,
+			),
+			key:   []string{"pager"},
+			value: "less -R",
+			expected: chezmoitest.JoinLines(
+				`# Config`,
+				`pager = "less -R"`,
+				``,
+				`[git]`,
+				`    autoCommit = false`,
+			),
+		},
+		{
+			name:   "toml_add_table",
+			format: chezmoi.FormatTOML,
+			data: chezmoitest.JoinLines(
+				`pager = "less"`,
+			),
+			key:   []string{"diff", "exclude"},
+			value: []string{"scripts", "externals"},
+			expected: chezmoitest.JoinLines(
+				`pager = "less"`,
+				``,
+				`[diff]`,
+				`    exclude = ["scripts", "externals"]`,
+			),
+		},
+		{
+			name:   "toml_add_dotted",
+			format: chezmoi.FormatTOML,
+			data: chezmoitest.JoinLines(
+				`git.autoCommit = true`,
+			),
+			key:   []string{"git", "autoPush"},
+			value: true,
+			expected: chezmoitest.JoinLines(
+				`git.autoCommit = true`,
+				`git.autoPush = true`,
+			),
+		},
+		{
+			name:     "yaml_empty",
+			format:   chezmoi.FormatYAML,
+			key:      []string{"git", "autoCommit"},
+			value:    true,
+			expected: "git:\n  autoCommit: true\n",
+		},
+		{
+			name:   "yaml_replace",
+			format: chezmoi.FormatYAML,
+			data: chezmoitest.JoinLines(
+				`# Config`,
+				`git:`,
+				`    # commit`,
+				`    autoCommit: false`,
+				`    autoPush: true # push`,
+			),
+			key:   []string{"git", "autocommit"},
+			value: true,
+			expected: chezmoitest.JoinLines(
+				`# Config`,
+				`git:`,
+				`    # commit`,
+				`    autoCommit: true`,
+				`    autoPush: true # push`,
+			),
+		},
+		{
+			name:   "yaml_add",
+			format: chezmoi.FormatYAML,
+			data: chezmoitest.JoinLines(
+				`# Config`,
+				`git:`,
+				`    autoCommit: false # commit`,
+			),
+			key:   []string{"git", "autoPush"},
+			value: true,
+			expected: chezmoitest.JoinLines(
+				`# Config`,
+				`git:`,
+				`    autoCommit: false # commit`,
+				`    autoPush: true`,
+			),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := setConfigFileValue(tc.format, []byte(tc.data), tc.key, tc.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
+
+func TestUnsetConfigFileValue(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		format   chezmoi.Format
+		data     string
+		key      []string
+		expected string
+	}{
+		{
+			name:   "json",
+			format: chezmoi.FormatJSON,
+			data: chezmoitest.JoinLines(
+				`{`,
+				`    "git": {`,
+				`        "autoCommit": true,`,
+				`        "autoPush": true`,
+				`    }`,
+				`}`,
+			),
+			key: []string{"git", "autopush"},
+			expected: chezmoitest.JoinLines(
+				`{`,
+				`    "git": {`,
+				`        "autoCommit": true`,
+				`    }`,
+				`}`,
+			),
+		},
+		{
+			name:   "json_missing",
+			format: chezmoi.FormatJSON,
+			data:   "{}\n",
+			key:    []string{"git", "autoPush"},
+			expected: chezmoitest.JoinLines(
+				`{}`,
+			),
+		},
+		{
+			name:   "toml_key",
+			format: chezmoi.FormatTOML,
+			data: chezmoitest.JoinLines(
+				`[git]`,
+				`    # commit`,
+				`    autoCommit = true`,
+				`    autoPush = true # push`,
+			),
+			key: []string{"git", "autopush"},
+			expected: chezmoitest.JoinLines(
+				`[git]`,
+				`    # commit`,
+				`    autoCommit = true`,
+			),
+		},
+		{
+			name:   "toml_table",
+			format: chezmoi.FormatTOML,
+			data: chezmoitest.JoinLines(
+				`pager = "less"`,
+				``,
+				`[git]`,
+				`    autoCommit = true`,
+				``,
+				`# Diff configuration`,
+				`[diff]`,
+				`    pager = "delta"`,
+			),
+			key: []string{"git"},
+			expected: chezmoitest.JoinLines(
+				`pager = "less"`,
+				``,
+				``,
+				`# Diff configuration`,
+				`[diff]`,
+				`    pager = "delta"`,
+			),
+		},
+		{
+			name:   "yaml",
+			format: chezmoi.FormatYAML,
+			data: chezmoitest.JoinLines(
+				`# Config`,
+				`git:`,
+				`    autoCommit: true # commit`,
+				`    autoPush: true`,
+			),
+			key: []string{"git", "autoPush"},
+			expected: chezmoitest.JoinLines(
+				`# Config`,
+				`git:`,
+				`    autoCommit: true # commit`,
+			),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := unsetConfigFileValue(tc.format, []byte(tc.data), tc.key)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+// configFileSchemaID is the URL of the config file JSON Schema.
+const configFileSchemaID = "https://chezmoi.io/reference/configuration-file/chezmoi.schema.json"
+
+// compiledConfigFileSchema returns the compiled config file JSON Schema.
+var compiledConfigFileSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	// Round-trip the schema through JSON so that it only contains the types
+	// that the compiler understands.
+	data, err := json.Marshal(ConfigFileSchema())
+	if err != nil {
+		return nil, err
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(configFileSchemaID, doc); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(configFileSchemaID)
+})
+
+// ConfigFileSchema returns a JSON Schema for the config file, generated from
+// the mapstructure tags of [ConfigFile].
+func ConfigFileSchema() map[string]any {
+	schema := configTypeSchema(reflect.TypeFor[ConfigFile]())
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = configFileSchemaID
+	schema["title"] = "chezmoi config file"
+	properties := schema["properties"].(map[string]any) //nolint:forcetypeassert
+	// Allow JSON config files to refer to the schema.
+	properties["$schema"] = map[string]any{
+		"type": "string",
+	}
+	// sourceDir may be a list of source directory layers.
+	properties["sourceDir"] = map[string]any{
+		"type": []string{"string", "array"},
+		"items": map[string]any{
+			"type": "string",
+		},
+	}
+	return schema
+}
+
+// canonicalConfigValue returns value, a value of type t in a config map, with
+// the keys of all structs replaced by their mapstructure keys. Config file keys
+// are case insensitive but JSON Schema properties are not.
+func canonicalConfigValue(t reflect.Type, value any) any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		switch {
+		case hasFieldsWithMapstructureTags(t):
+			result := make(map[string]any, len(value))
+		KEY:
+			for key, elem := range value {
+				for field := range fieldsWithMapstructureTags(t) {
+					if fieldKey := mapstructureKey(field); strings.EqualFold(fieldKey, key) {
+						result[fieldKey] = canonicalConfigValue(field.Type, elem)
+						continue KEY
+					}
+				}
+				result[key] = elem
+			}
+			return result
+		case t.Kind() == reflect.Map:
+			result := make(map[string]any, len(value))
+			for key, elem := range value {
+				result[key] = canonicalConfigValue(t.Elem(), elem)
+			}
+			return result
+		}
+	case []any:
+		if t.Kind() == reflect.Slice {
+			result := make([]any, 0, len(value))
+			for _, elem := range value {
+				result = append(result, canonicalConfigValue(t.Elem(), elem))
+			}
+			return result
+		}
+	}
+	return value
+}
+
+// configFileSchemaViolations returns a description of every violation of the
+// config file JSON Schema in configMap, each prefixed with the key of the
+// violating value.
+func configFileSchemaViolations(configMap map[string]any) ([]string, error) {
+	schema, err := compiledConfigFileSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip configMap through JSON so that it only contains the types
+	// that the validator understands.
+	data, err := json.Marshal(canonicalConfigValue(reflect.TypeFor[ConfigFile](), configMap))
+	if err != nil {
+		return nil, err
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var validationError *jsonschema.ValidationError
+	switch err := schema.Validate(instance); {
+	case errors.As(err, &validationError):
+		printer := message.NewPrinter(language.English)
+		var violations []string
+		var addViolations func(*jsonschema.ValidationError)
+		addViolations = func(validationError *jsonschema.ValidationError) {
+			if len(validationError.Causes) == 0 {
+				if additionalProperties, ok := validationError.ErrorKind.(*kind.AdditionalProperties); ok {
+					for _, property := range additionalProperties.Properties {
+						key := append(slices.Clone(validationError.InstanceLocation), property)
+						violations = append(violations, strings.Join(key, ".")+": unknown key")
+					}
+				} else {
+					key := strings.Join(validationError.InstanceLocation, ".")
+					violations = append(violations, key+": "+validationError.ErrorKind.LocalizedString(printer))
+				}
+			}
+			for _, cause := range validationError.Causes {
+				addViolations(cause)
+			}
+		}
+		addViolations(validationError)
+		slices.Sort(violations)
+		return violations, nil
+	case err != nil:
+		return nil, err
+	default:
+		return nil, nil
+	}
+}
+
+// configFileSchemaWarningsEnabled returns whether schema violations in
+// configMap should be reported. configMap is a config file that might not be
+// decoded into c yet, so its warnings settings and any CHEZMOI_CONFIG_WARNINGS_*
+// environment variables are applied on top of c's.
+func (c *Config) configFileSchemaWarningsEnabled(configMap map[string]any) bool {
+	configFile := ConfigFile{
+		Warnings: c.Warnings,
+	}
+	for key, value := range configMap {
+		if strings.EqualFold(key, "warnings") {
+			_ = c.decodeConfigMap(map[string]any{"warnings": value}, &configFile)
+		}
+	}
+	if configEnvVars, err := configEnvVars(os.Environ()); err == nil {
+		for _, configEnvVar := range configEnvVars {
+			if configEnvVar.key[0] == "warnings" {
+				_ = c.decodeConfigMap(nestedConfigMap(configEnvVar.key, configEnvVar.value), &configFile)
+			}
+		}
+	}
+	return configFile.Warnings.ConfigFileSchema
+}
+
+// configTypeSchema returns the JSON Schema for values of type t in a config
+// file. Types that are handled by decode hooks accept the values that their
+// hooks accept.
+func configTypeSchema(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeFor[autoBool]():
+		return map[string]any{
+			"type": []string{"boolean", "string"},
+		}
+	case reflect.TypeFor[chezmoi.AbsPath](), reflect.TypeFor[choiceFlag]():
+		return map[string]any{
+			"type": "string",
+		}
+	case reflect.TypeFor[chezmoi.EntryTypeSet]():
+		return map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "string",
+			},
+		}
+	case reflect.TypeFor[chezmoi.Mode]():
+		return map[string]any{
+			"type": "string",
+			"enum": []chezmoi.Mode{chezmoi.ModeFile, chezmoi.ModeSymlink},
+		}
+	case reflect.TypeFor[time.Duration]():
+		return map[string]any{
+			"type": []string{"integer", "string"},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return configTypeSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{
+			"type": "boolean",
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{
+			"type": "integer",
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{
+			"type":    "integer",
+			"minimum": 0,
+		}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{
+			"type": "number",
+		}
+	case reflect.String:
+		return map[string]any{
+			"type": "string",
+		}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": configTypeSchema(t.Elem()),
+		}
+	case reflect.Slice:
+		// Strings are split into lists by a decode hook.
+		return map[string]any{
+			"type":  []string{"array", "string"},
+			"items": configTypeSchema(t.Elem()),
+		}
+	case reflect.Struct:
+		if !hasFieldsWithMapstructureTags(t) {
+			return map[string]any{}
+		}
+		properties := make(map[string]any)
+		for field := range fieldsWithMapstructureTags(t) {
+			properties[mapstructureKey(field)] = configTypeSchema(field.Type)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestConfigFileSchemaViolations(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		configMap map[string]any
+		expected  []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			configMap: map[string]any{
+				"color": "auto",
+				"data": map[string]any{
+					"email": "me@home.org",
+				},
+				"diff": map[string]any{
+					"exclude": []any{"scripts"},
+				},
+				"edit": map[string]any{
+					"minDuration": "1s",
+				},
+				"git": map[string]any{
+					"autoCommit": true,
+				},
+				"pagerArgs": "-R",
+				"sourceDir": []any{"/home/user/.dotfiles", "/home/user/.work-dotfiles"},
+				"umask":     int64(0o22),
+			},
+		},
+		{
+			name: "case_insensitive",
+			configMap: map[string]any{
+				"GIT": map[string]any{
+					"AutoPush": true,
+				},
+				"Warnings": map[string]any{
+					"configfileschema": false,
+				},
+			},
+		},
+		{
+			name: "unknown_keys",
+			configMap: map[string]any{
+				"git": map[string]any{
+					"autocomit": true,
+				},
+				"interpreters": map[string]any{
+					"py": map[string]any{
+						"command": "python3",
+						"option":  "-u",
+					},
+				},
+				"sourcedirectory": "/home/user/.dotfiles",
+			},
+			expected: []string{
+				"git.autocomit: unknown key",
+				"interpreters.py.option: unknown key",
+				"sourcedirectory: unknown key",
+			},
+		},
+		{
+			name: "type_mismatches",
+			configMap: map[string]any{
+				"git": map[string]any{
+					"autoPush": "yes",
+				},
+				"mode":  "copy",
+				"pager": int64(1),
+			},
+			expected: []string{
+				"git.autopush: got string, want boolean",
+				"mode: value must be one of 'file', 'symlink'",
+				"pager: got number, want string",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := configFileSchemaViolations(tc.configMap)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
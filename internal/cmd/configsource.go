@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/twpayne/go-vfs/v5"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+	"chezmoi.io/chezmoi/v2/internal/chezmoiset"
+)
+
+// configDropInDirName is the name of the directory next to the config file
+// that contains config file snippets.
+const configDropInDirName = "chezmoi.d"
+
+// A configSource is a file that contributes to the configuration.
+type configSource struct {
+	absPath    chezmoi.AbsPath
+	configMap  map[string]any
+	violations []string // Violations of the config file JSON Schema.
+}
+
+// A configSourceDecodeError is an error decoding a config source.
+type configSourceDecodeError struct {
+	configSource configSource
+	err          error
+}
+
+func (e *configSourceDecodeError) Error() string {
+	return e.configSource.absPath.String() + ": " + e.err.Error()
+}
+
+func (e *configSourceDecodeError) Unwrap() error {
+	return e.err
+}
+
+// configKeySources returns the source of each key in configSources, where
+// nested keys are separated by dots.
+func configKeySources(configSources []configSource) map[string]chezmoi.AbsPath {
+	keySources := make(map[string]chezmoi.AbsPath)
+	var addKeySources func(prefix string, configMap map[string]any, absPath chezmoi.AbsPath)
+	addKeySources = func(prefix string, configMap map[string]any, absPath chezmoi.AbsPath) {
+		for key, value := range configMap {
+			if nestedConfigMap, ok := value.(map[string]any); ok && len(nestedConfigMap) != 0 {
+				delete(keySources, prefix+key)
+				addKeySources(prefix+key+".", nestedConfigMap, absPath)
+				continue
+			}
+			// Remove the sources of any nested keys that this key replaces.
+			for nestedKey := range keySources {
+				if strings.HasPrefix(nestedKey, prefix+key+".") {
+					delete(keySources, nestedKey)
+				}
+			}
+			keySources[prefix+key] = absPath
+		}
+	}
+	for _, configSource := range configSources {
+		addKeySources("", configSource.configMap, configSource.absPath)
+	}
+	return keySources
+}
+
+// configSourceViolations returns the violations in configSources, prefixed
+// with the path of the config source that contains them.
+func configSourceViolations(configSources ...configSource) []string {
+	var violations []string
+	for _, configSource := range configSources {
+		for _, violation := range configSource.violations {
+			violations = append(violations, fmt.Sprintf("%s: %s", configSource.absPath, violation))
+		}
+	}
+	return violations
+}
+
+// findConfigFile returns the chezmoi.$FORMAT config file in dirAbsPath. It
+// returns an empty path if there is no config file and an error if there are
+// config files in more than one format.
+func findConfigFile(fileSystem vfs.FS, dirAbsPath chezmoi.AbsPath) (chezmoi.AbsPath, error) {
+	dirEntries, err := fileSystem.ReadDir(dirAbsPath.String())
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return chezmoi.EmptyAbsPath, nil
+	case err != nil:
+		return chezmoi.EmptyAbsPath, err
+	}
+
+	dirEntryNames := chezmoiset.NewWithCapacity[string](len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		dirEntryNames.Add(dirEntry.Name())
+	}
+
+	var names []string
+	for _, extension := range chezmoi.FormatExtensions {
+		name := "chezmoi." + extension
+		if dirEntryNames.Contains(name) {
+			names = append(names, name)
+		}
+	}
+
+	switch len(names) {
+	case 0:
+		return chezmoi.EmptyAbsPath, nil
+	case 1:
+		return dirAbsPath.JoinString(names[0]), nil
+	default:
+		configFileAbsPathStrs := make([]string, 0, len(names))
+		for _, name := range names {
+			configFileAbsPathStrs = append(configFileAbsPathStrs, dirAbsPath.JoinString(name).String())
+		}
+		return chezmoi.EmptyAbsPath, fmt.Errorf("multiple config files: %s", englishList(configFileAbsPathStrs))
+	}
+}
+
+// mergeConfigSources returns the configuration from configSources, with later
+// sources taking precedence.
+func mergeConfigSources(configSources []configSource) map[string]any {
+	configMap := make(map[string]any)
+	for _, configSource := range configSources {
+		chezmoi.RecursiveMerge(configMap, configSource.configMap)
+	}
+	return configMap
+}
+
+// readConfigSources returns the files that contribute to the configuration in
+// the order in which they are merged: the system config file, the config file
+// at configFileAbsPath, and the files in the chezmoi.d directory next to the
+// config file in lexical order. Files that do not exist are skipped.
+func (c *Config) readConfigSources(configFileAbsPath chezmoi.AbsPath) ([]configSource, error) {
+	return c.readConfigSourcesWithConfigFileData(configFileAbsPath, nil, nil)
+}
+
+// readConfigSourcesWithConfigFileData is like readConfigSources except that if
+// configFileData is not nil then it is used, in configFileFormat, as the
+// contents of the config file at configFileAbsPath instead of reading it.
+func (c *Config) readConfigSourcesWithConfigFileData(
+	configFileAbsPath chezmoi.AbsPath,
+	configFileFormat chezmoi.Format,
+	configFileData []byte,
+) ([]configSource, error) {
+	var configSources []configSource
+	readConfigSource := func(absPath chezmoi.AbsPath, format chezmoi.Format) error {
+		data, err := c.fileSystem.ReadFile(absPath.String())
+		switch {
+		case absPath == configFileAbsPath && configFileData != nil:
+			data, format = configFileData, configFileFormat
+		case errors.Is(err, fs.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		}
+		var configMap map[string]any
+		if err := format.Unmarshal(data, &configMap); err != nil {
+			return fmt.Errorf("%s: %w", absPath, err)
+		}
+		violations, err := configFileSchemaViolations(configMap)
+		if err != nil {
+			return fmt.Errorf("%s: %w", absPath, err)
+		}
+		configSource := configSource{
+			absPath:    absPath,
+			configMap:  configMap,
+			violations: violations,
+		}
+		// Decode each file individually so that errors refer to the file that
+		// contains them. If the file cannot be decoded then return its
+		// violations with the error, as they are likely to explain why.
+		if err := c.decodeConfigMap(configMap, &ConfigFile{}); err != nil {
+			return &configSourceDecodeError{
+				configSource: configSource,
+				err:          err,
+			}
+		}
+		configSources = append(configSources, configSource)
+		return nil
+	}
+
+	if !c.systemConfigDirAbsPath.IsEmpty() {
+		systemConfigFileAbsPath, err := findConfigFile(c.fileSystem, c.systemConfigDirAbsPath)
+		if err != nil {
+			return nil, err
+		}
+		if !systemConfigFileAbsPath.IsEmpty() && systemConfigFileAbsPath != configFileAbsPath {
+			systemConfigFileFormat, err := chezmoi.FormatFromAbsPath(systemConfigFileAbsPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := readConfigSource(systemConfigFileAbsPath, systemConfigFileFormat); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	format, err := c.configFileFormat(configFileAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := readConfigSource(configFileAbsPath, format); err != nil {
+		return nil, err
+	}
+
+	dropInDirAbsPath := configFileAbsPath.Dir().JoinString(configDropInDirName)
+	dirEntries, err := c.fileSystem.ReadDir(dropInDirAbsPath.String())
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return configSources, nil
+	case err != nil:
+		return nil, err
+	}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		absPath := dropInDirAbsPath.JoinString(dirEntry.Name())
+		format, ok := chezmoi.FormatsByExtension[strings.TrimPrefix(absPath.Ext(), ".")]
+		if !ok {
+			continue
+		}
+		if err := readConfigSource(absPath, format); err != nil {
+			return nil, err
+		}
+	}
+
+	return configSources, nil
+}
+
+// warnConfigSourceDecodeErrorViolations warns about the violations in the
+// config source that could not be decoded if err is a configSourceDecodeError.
+func (c *Config) warnConfigSourceDecodeErrorViolations(err error) {
+	var configSourceDecodeErr *configSourceDecodeError
+	if !errors.As(err, &configSourceDecodeErr) {
+		return
+	}
+	configSource := configSourceDecodeErr.configSource
+	if c.configFileSchemaWarningsEnabled(configSource.configMap) {
+		c.warnConfigFileSchemaViolations(configSource.absPath, configSource.violations)
+	}
+}
+
+// warnConfigFileSchemaViolations warns about each of violations in the config
+// file at absPath.
+func (c *Config) warnConfigFileSchemaViolations(absPath chezmoi.AbsPath, violations []string) {
+	for _, violation := range violations {
+		c.errorf("warning: %s: %s\n", absPath, violation)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 type dataCmdConfig struct {
 	format *choiceFlag
+	target string
 }
 
 func (c *Config) newDataCmd() *cobra.Command {
@@ -29,16 +30,47 @@ func (c *Config) newDataCmd() *cobra.Command {
 
 	dataCmd.Flags().VarP(c.data.format, "format", "f", "Output format")
 	must(dataCmd.RegisterFlagCompletionFunc("format", c.data.format.FlagCompletionFunc()))
+	dataCmd.Flags().StringVar(&c.data.target, "target", c.data.target, "Print the template data for target")
+	must(dataCmd.RegisterFlagCompletionFunc("target", c.targetValidArgs))
 
 	return dataCmd
 }
 
 func (c *Config) runDataCmd(cmd *cobra.Command, args []string) error {
-	sourceState, err := c.newSourceState(cmd.Context(), cmd,
-		chezmoi.WithTemplateDataOnly(true),
-	)
+	format := cmp.Or(c.data.format.String(), c.Format.String())
+
+	if c.data.target == "" {
+		sourceState, err := c.newSourceState(cmd.Context(), cmd,
+			chezmoi.WithTemplateDataOnly(true),
+		)
+		if err != nil {
+			return err
+		}
+		return c.marshal(format, sourceState.TemplateData())
+	}
+
+	sourceState, err := c.getSourceState(cmd.Context(), cmd)
 	if err != nil {
 		return err
 	}
-	return c.marshal(cmp.Or(c.data.format.String(), c.Format.String()), sourceState.TemplateData())
+	targetRelPaths, err := c.targetRelPaths(sourceState, []string{c.data.target}, targetRelPathsOptions{})
+	if err != nil {
+		return err
+	}
+	targetRelPath := targetRelPaths[0]
+	sourceStateEntry := sourceState.MustEntry(targetRelPath)
+	sourceRelPath := sourceStateEntry.SourceRelPath().RelPath()
+
+	// The data for a directory are the data seen by templates in the
+	// directory.
+	dirRelPath := sourceRelPath.Dir()
+	if _, ok := sourceStateEntry.(*chezmoi.SourceStateDir); ok {
+		dirRelPath = sourceRelPath
+	}
+	templateData := sourceState.TemplateDataInDir(dirRelPath)
+	if chezmoiTemplateData, ok := templateData["chezmoi"].(map[string]any); ok {
+		chezmoiTemplateData["sourceFile"] = sourceRelPath.String()
+		chezmoiTemplateData["targetFile"] = c.DestDirAbsPath.Join(targetRelPath).String()
+	}
+	return c.marshal(format, templateData)
 }
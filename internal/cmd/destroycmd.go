@@ -67,9 +67,8 @@ func (c *Config) runDestroyCmd(cmd *cobra.Command, args []string, sourceState *c
 			continue
 		}
 		if _, ok := sourceStateEntry.(*chezmoi.SourceStateRemove); !ok {
-			relPath := sourceStateEntry.SourceRelPath().RelPath()
-			if !relPath.IsEmpty() {
-				sourceAbsPath = c.SourceDirAbsPath.Join(relPath)
+			if !sourceStateEntry.SourceRelPath().RelPath().IsEmpty() {
+				sourceAbsPath = sourceState.SourceAbsPath(sourceStateEntry)
 			}
 		}
 		if !c.force {
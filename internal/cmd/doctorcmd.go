@@ -98,6 +98,10 @@ type configFileCheck struct {
 	bds      *xdg.BaseDirectorySpecification
 }
 
+// A configFileSchemaCheck checks that the config files match the config file
+// JSON Schema.
+type configFileSchemaCheck struct{}
+
 // A dirCheck checks that a directory exists.
 type dirCheck struct {
 	name    string
@@ -184,7 +188,7 @@ func (c *Config) runDoctorCmd(cmd *cobra.Command, args []string) error {
 	}
 	httpClient, httpClientErr := c.getHTTPClient()
 	shellCommand, _ := shell.CurrentUserShell()
-	shellCommand, shellArgs, _ := parseCommand(shellCommand, nil)
+	shellCommand, shellArgs, _ := chezmoi.ParseCommand(shellCommand, nil)
 	cdCommand, cdArgs, _ := c.cdCommand()
 	editCommand, editArgs, _ := c.editor(nil)
 	checks := []check{
@@ -209,6 +213,7 @@ func (c *Config) runDoctorCmd(cmd *cobra.Command, args []string) error {
 			basename: chezmoiRelPath,
 			bds:      c.bds,
 		},
+		configFileSchemaCheck{},
 		&dirCheck{
 			name:    "source-dir",
 			dirname: c.SourceDirAbsPath,
@@ -599,6 +604,39 @@ func (c *configFileCheck) Run(config *Config) (checkResult, string) {
 	return checkResultOK, message
 }
 
+func (configFileSchemaCheck) Name() string {
+	return "config-file-schema"
+}
+
+func (configFileSchemaCheck) Run(config *Config) (checkResult, string) {
+	configFileAbsPath, err := config.getConfigFileAbsPath()
+	if err != nil {
+		return checkResultError, err.Error()
+	}
+
+	configSources, err := config.readConfigSources(configFileAbsPath)
+	// If a config file cannot be decoded then report its violations, as they
+	// are likely to explain why.
+	var configSourceDecodeErr *configSourceDecodeError
+	switch {
+	case errors.As(err, &configSourceDecodeErr):
+		if violations := configSourceViolations(configSourceDecodeErr.configSource); len(violations) > 0 {
+			return checkResultError, englishList(violations)
+		}
+		return checkResultError, err.Error()
+	case err != nil:
+		return checkResultError, err.Error()
+	case len(configSources) == 0:
+		return checkResultSkipped, "no config files"
+	}
+
+	violations := configSourceViolations(configSources...)
+	if len(violations) > 0 {
+		return checkResultWarning, englishList(violations)
+	}
+	return checkResultOK, "no problems found"
+}
+
 func (c *dirCheck) Name() string {
 	return c.name
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"io/fs"
 	"log/slog"
 	"os"
 	"runtime"
@@ -64,7 +65,9 @@ func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
 				filter:       c.Edit.filter,
 				init:         c.Edit.init,
 				recursive:    true,
+				reload:       true,
 				umask:        c.Umask,
+				validate:     true,
 				preApplyFunc: c.defaultPreApplyFunc,
 			}); err != nil {
 				return err
@@ -96,6 +99,10 @@ TARGET_REL_PATH:
 	for _, targetRelPath := range targetRelPaths {
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
 		sourceRelPath := sourceStateEntry.SourceRelPath()
+		sourceAbsPath, err := c.copyToSourceWriteDir(sourceState, sourceStateEntry)
+		if err != nil {
+			return err
+		}
 		switch sourceStateFile, ok := sourceStateEntry.(*chezmoi.SourceStateFile); {
 		case ok && sourceStateFile.Attr().Encrypted:
 			// FIXME in the case that the file is an encrypted template then we
@@ -124,7 +131,7 @@ TARGET_REL_PATH:
 				return err
 			}
 			transparentlyDecryptedFile := transparentlyDecryptedFile{
-				sourceAbsPath:    c.SourceDirAbsPath.Join(sourceRelPath.RelPath()),
+				sourceAbsPath:    sourceAbsPath,
 				decryptedAbsPath: decryptedAbsPath,
 				preEditPlaintext: contents,
 			}
@@ -160,7 +167,7 @@ TARGET_REL_PATH:
 			if err := os.MkdirAll(hardlinkAbsPath.Dir().String(), 0o700); err != nil {
 				return err
 			}
-			if err := c.baseSystem.Link(c.SourceDirAbsPath.Join(sourceRelPath.RelPath()), hardlinkAbsPath); err == nil {
+			if err := c.baseSystem.Link(sourceAbsPath, hardlinkAbsPath); err == nil {
 				editorArgs = append(editorArgs, hardlinkAbsPath.String())
 				continue TARGET_REL_PATH
 			}
@@ -169,7 +176,6 @@ TARGET_REL_PATH:
 			// source file in the source state.
 			fallthrough
 		default:
-			sourceAbsPath := c.SourceDirAbsPath.Join(sourceRelPath.RelPath())
 			editorArgs = append(editorArgs, sourceAbsPath.String())
 		}
 	}
@@ -205,7 +211,9 @@ TARGET_REL_PATH:
 				filter:       c.Edit.filter,
 				init:         c.Edit.init,
 				recursive:    true,
+				reload:       true,
 				umask:        c.Umask,
+				validate:     true,
 				preApplyFunc: c.defaultPreApplyFunc,
 			}); err != nil {
 				return err
@@ -255,3 +263,36 @@ TARGET_REL_PATH:
 
 	return postEditFunc()
 }
+
+// copyToSourceWriteDir copies sourceStateEntry to the same relative path in the
+// source directory if it is in a different source directory layer, so that
+// edits to it override the entry in the other layer. It returns the absolute
+// path of sourceStateEntry in the source directory.
+func (c *Config) copyToSourceWriteDir(
+	sourceState *chezmoi.SourceState,
+	sourceStateEntry chezmoi.SourceStateEntry,
+) (chezmoi.AbsPath, error) {
+	sourceAbsPath := sourceState.SourceAbsPath(sourceStateEntry)
+	if sourceAbsPath.HasDirPrefix(c.SourceDirAbsPath) {
+		return sourceAbsPath, nil
+	}
+	writeAbsPath := c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath())
+	if _, ok := sourceStateEntry.(*chezmoi.SourceStateDir); ok {
+		return writeAbsPath, chezmoi.MkdirAll(c.sourceSystem, writeAbsPath, fs.ModePerm)
+	}
+	if err := chezmoi.MkdirAll(c.sourceSystem, writeAbsPath.Dir(), fs.ModePerm); err != nil {
+		return chezmoi.EmptyAbsPath, err
+	}
+	fileInfo, err := c.sourceSystem.Stat(sourceAbsPath)
+	if err != nil {
+		return chezmoi.EmptyAbsPath, err
+	}
+	data, err := c.sourceSystem.ReadFile(sourceAbsPath)
+	if err != nil {
+		return chezmoi.EmptyAbsPath, err
+	}
+	if err := c.sourceSystem.WriteFile(writeAbsPath, data, fileInfo.Mode().Perm()); err != nil {
+		return chezmoi.EmptyAbsPath, err
+	}
+	return writeAbsPath, nil
+}
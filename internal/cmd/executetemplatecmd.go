@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+	"chezmoi.io/chezmoi/v2/internal/chezmoierrors"
 )
 
 type executeTemplateCmdConfig struct {
@@ -23,6 +24,7 @@ type executeTemplateCmdConfig struct {
 	promptString      map[string]string
 	stdinIsATTY       bool
 	templateOptions   chezmoi.TemplateOptions
+	trace             bool
 	withStdin         bool
 }
 
@@ -58,13 +60,19 @@ func (c *Config) newExecuteTemplateCmd() *cobra.Command {
 		StringVar(&c.executeTemplate.templateOptions.LeftDelimiter, "left-delimiter", c.executeTemplate.templateOptions.LeftDelimiter, "Set left template delimiter")
 	executeTemplateCmd.Flags().
 		StringVar(&c.executeTemplate.templateOptions.RightDelimiter, "right-delimiter", c.executeTemplate.templateOptions.RightDelimiter, "Set right template delimiter")
+	executeTemplateCmd.Flags().
+		BoolVar(&c.executeTemplate.trace, "trace", c.executeTemplate.trace, "Trace template execution")
 	executeTemplateCmd.Flags().
 		BoolVar(&c.executeTemplate.withStdin, "with-stdin", c.executeTemplate.withStdin, "Set .chezmoi.stdin to the contents of the standard input")
 
 	return executeTemplateCmd
 }
 
-func (c *Config) runExecuteTemplateCmd(cmd *cobra.Command, args []string) error {
+func (c *Config) runExecuteTemplateCmd(cmd *cobra.Command, args []string) (err error) {
+	if c.executeTemplate.trace {
+		defer chezmoierrors.CombineFunc(&err, c.traceTemplates())
+	}
+
 	options := []chezmoi.SourceStateOption{
 		chezmoi.WithTemplateDataOnly(true),
 		chezmoi.WithReadTemplates(!c.executeTemplate.init),
@@ -253,6 +261,9 @@ func (c *Config) runExecuteTemplateCmd(cmd *cobra.Command, args []string) error
 		}
 
 		chezmoi.RecursiveMerge(c.templateFuncs, initTemplateFuncs)
+		if c.Template.Restricted {
+			c.restrictTemplateFuncs()
+		}
 	}
 
 	if len(args) == 0 {
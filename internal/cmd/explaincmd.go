@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+type explainCmdConfig struct {
+	format *choiceFlag
+}
+
+func (c *Config) newExplainCmd() *cobra.Command {
+	explainCmd := &cobra.Command{
+		GroupID:           groupIDTemplate,
+		Use:               "explain target...",
+		Short:             "Explain how targets are derived from the source state",
+		Long:              mustLongHelp("explain"),
+		Example:           example("explain"),
+		ValidArgsFunction: c.targetValidArgs,
+		Args:              cobra.MinimumNArgs(1),
+		RunE:              c.runExplainCmd,
+		Annotations: newAnnotations(
+			persistentStateModeReadOnly,
+			requiresSourceDirectory,
+		),
+	}
+
+	explainCmd.Flags().VarP(c.explain.format, "format", "f", "Output format")
+	must(explainCmd.RegisterFlagCompletionFunc("format", c.explain.format.FlagCompletionFunc()))
+
+	return explainCmd
+}
+
+func (c *Config) runExplainCmd(cmd *cobra.Command, args []string) error {
+	// Trace template execution to find the dependencies of templates.
+	c.templateTracer = chezmoi.NewTemplateTracer(secretTemplateFuncNames)
+	return c.makeRunEWithSourceState(c.runExplainCmdWithSourceState)(cmd, args)
+}
+
+func (c *Config) runExplainCmdWithSourceState(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+	explanations := make([]*chezmoi.Explanation, 0, len(args))
+	for _, arg := range args {
+		argAbsPath, err := chezmoi.NewAbsPathFromExtPath(arg, c.homeDirAbsPath)
+		if err != nil {
+			return err
+		}
+		targetRelPath, err := c.targetRelPath(argAbsPath)
+		if err != nil {
+			return err
+		}
+		explanation, err := sourceState.Explain(targetRelPath, c.destSystem, c.persistentState)
+		if err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+		explanations = append(explanations, explanation)
+	}
+	return c.marshal(cmp.Or(c.explain.format.String(), c.Format.String()), explanations)
+}
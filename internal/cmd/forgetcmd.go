@@ -52,13 +52,12 @@ func (c *Config) runForgetCmd(cmd *cobra.Command, args []string, sourceState *ch
 			panic(fmt.Sprintf("%s: %T: unknown source state origin type", targetRelPath, sourceStateOrigin))
 		}
 
-		relPath := sourceStateEntry.SourceRelPath().RelPath()
-		if relPath.IsEmpty() {
+		if sourceStateEntry.SourceRelPath().RelPath().IsEmpty() {
 			c.errorf("warning: %s: ignoring implicitly managed file\n", targetRelPath)
 			continue
 		}
 
-		sourceAbsPath := c.SourceDirAbsPath.Join(relPath)
+		sourceAbsPath := sourceState.SourceAbsPath(sourceStateEntry)
 		if !c.force {
 			choice, err := c.promptChoice(fmt.Sprintf("Remove %s", sourceAbsPath), choicesYesNoAllQuit)
 			if err != nil {
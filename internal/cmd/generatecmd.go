@@ -32,6 +32,18 @@ func (c *Config) newGenerateCmd() *cobra.Command {
 		),
 	}
 
+	generateConfigSchemaCmd := &cobra.Command{
+		Use:   "config-schema",
+		Short: "Generate a JSON Schema for the config file",
+		Args:  cobra.NoArgs,
+		RunE:  c.runGenerateConfigSchemaCmd,
+		Annotations: newAnnotations(
+			doesNotRequireValidConfig,
+			persistentStateModeNone,
+		),
+	}
+	generateCmd.AddCommand(generateConfigSchemaCmd)
+
 	generateGitCommitMessageCmd := &cobra.Command{
 		Use:   "git-commit-message",
 		Short: "Generate a git commit message",
@@ -75,6 +87,14 @@ func (c *Config) newGenerateCmd() *cobra.Command {
 	return generateCmd
 }
 
+func (c *Config) runGenerateConfigSchemaCmd(cmd *cobra.Command, args []string) error {
+	data, err := chezmoi.FormatJSON.Marshal(ConfigFileSchema())
+	if err != nil {
+		return err
+	}
+	return c.writeOutput(data, 0o666)
+}
+
 func (c *Config) runGenerateGitCommitMessageCmd(cmd *cobra.Command, args []string) error {
 	builder := strings.Builder{}
 	builder.Grow(16384)
@@ -126,6 +126,14 @@ var helps = map[string]*help{
 			"z",
 		),
 	},
+	"cache": {
+		longHelp: "" +
+			"  Manipulate cached template function results.",
+		example: "" +
+			"  chezmoi cache clear\n" +
+			"  chezmoi cache clear --template\n" +
+			"  chezmoi cache clear --data",
+	},
 	"cat": {
 		longHelp: "" +
 			"  Write the target contents of targets to stdout. targets must be files,\n" +
@@ -133,13 +141,21 @@ var helps = map[string]*help{
 			"  scripts, the script's contents are written. For symlinks, the target is\n" +
 			"  written.",
 		example: "" +
-			"  chezmoi cat ~/.bashrc",
+			"  chezmoi cat ~/.bashrc\n" +
+			"  chezmoi cat --trace ~/.gitconfig",
+		longFlags: chezmoiset.New(
+			"trace",
+		),
 	},
 	"cat-config": {
 		longHelp: "" +
 			"  Print the configuration file.",
 		example: "" +
-			"  chezmoi cat-config",
+			"  chezmoi cat-config\n" +
+			"  chezmoi cat-config --sources",
+		longFlags: chezmoiset.New(
+			"sources",
+		),
 	},
 	"cd": {
 		longHelp: "" +
@@ -179,6 +195,7 @@ var helps = map[string]*help{
 			"   exact                               | none\n" +
 			"   executable                          | x\n" +
 			"   external                            | none\n" +
+			"   finally                             | none\n" +
 			"   once                                | o\n" +
 			"   onchange                            | none\n" +
 			"   private                             | p\n" +
@@ -223,14 +240,30 @@ var helps = map[string]*help{
 			"  chezmoi completion bash\n" +
 			"  chezmoi completion fish --output=~/.config/fish/completions/chezmoi.fish",
 	},
+	"config": {
+		longHelp: "" +
+			"  Get and set values in the config file without editing it by hand.\n" +
+			"\n" +
+			"  Keys are dot-separated paths, for example git.autoCommit or data.email, and\n" +
+			"  are checked against the known configuration variables. As in the config\n" +
+			"  file, the names of configuration variables are case insensitive.",
+		example: "" +
+			"  chezmoi config get sourceDir\n" +
+			"  chezmoi config set git.autoCommit true\n" +
+			"  chezmoi config set data.email me@home.org\n" +
+			"  chezmoi config set diff.exclude scripts,externals\n" +
+			"  chezmoi config unset git.autoCommit",
+	},
 	"data": {
 		longHelp: "" +
 			"  Write the computed template data to stdout.",
 		example: "" +
 			"  chezmoi data\n" +
-			"  chezmoi data --format=yaml",
+			"  chezmoi data --format=yaml\n" +
+			"  chezmoi data --target ~/.config/nvim/init.lua",
 		longFlags: chezmoiset.New(
 			"format",
+			"target",
 		),
 		shortFlags: chezmoiset.New(
 			"f",
@@ -328,7 +361,10 @@ var helps = map[string]*help{
 	},
 	"dump-config": {
 		longHelp: "" +
-			"  Dump the configuration.",
+			"  Dump the configuration.\n" +
+			"\n" +
+			"  Keys that are overridden by environment variables are listed under configEnv\n" +
+			"  together with the variable that sets them.",
 		example: "" +
 			"  chezmoi dump-config",
 		longFlags: chezmoiset.New(
@@ -373,7 +409,11 @@ var helps = map[string]*help{
 	},
 	"edit-config": {
 		longHelp: "" +
-			"  Edit the configuration file.",
+			"  Edit the configuration file.\n" +
+			"\n" +
+			"  After the editor exits, chezmoi checks the configuration file against the\n" +
+			"  config file schema and warns about any unknown keys or values of the wrong\n" +
+			"  type.",
 		example: "" +
 			"  chezmoi edit-config",
 	},
@@ -412,7 +452,9 @@ var helps = map[string]*help{
 			"  chezmoi execute-template '{{ .chezmoi.os }}' / '{{ .chezmoi.arch }}'\n" +
 			"  echo '{{ .chezmoi | toJson }}' | chezmoi execute-template\n" +
 			"  chezmoi execute-template --init --promptString email=me@home.org < ~/.\n" +
-			"local/share/chezmoi/.chezmoi.toml.tmpl",
+			"local/share/chezmoi/.chezmoi.toml.tmpl\n" +
+			"  chezmoi execute-template --trace --file ~/.local/share/chezmoi/dot_gitconfig.\n" +
+			"tmpl",
 		longFlags: chezmoiset.New(
 			"file",
 			"init",
@@ -424,6 +466,7 @@ var helps = map[string]*help{
 			"promptString",
 			"right-delimiter",
 			"stdinisatty",
+			"trace",
 			"with-stdin",
 		),
 		shortFlags: chezmoiset.New(
@@ -432,6 +475,40 @@ var helps = map[string]*help{
 			"p",
 		),
 	},
+	"explain": {
+		longHelp: "" +
+			"  Explain how each target is derived from the source state. For each target,\n" +
+			"  chezmoi prints:\n" +
+			"\n" +
+			"  • the path of its source file or directory and its origin, which is the\n" +
+			"  source\n" +
+			"  path, the URL of an external, or remove for entries removed by\n" +
+			"  .chezmoiremove or renames,\n" +
+			"  • if sourceDir is a list of layers, the source directory layer that\n" +
+			"  contributed it,\n" +
+			"  • its type and the attributes parsed from its source name,\n" +
+			"  • whether it is ignored and the patterns in .chezmoiignore that match it or\n" +
+			"  any of its parent directories, with patterns that un-ignore it prefixed with\n" +
+			"  !,\n" +
+			"  • for templates, the template data keys, template functions, and templates\n" +
+			"  in\n" +
+			"  .chezmoitemplates that the template references,\n" +
+			"  • the entry states (type, mode, and SHA256 sum of the contents) last written\n" +
+			"  by chezmoi, in the destination directory, and in the target state, and\n" +
+			"  • any error encountered while computing its target state.\n" +
+			"\n" +
+			"  Template dependencies are recorded while executing the template, so they\n" +
+			"  only include the references that are used when computing the target state.",
+		example: "" +
+			"  chezmoi explain ~/.gitconfig\n" +
+			"  chezmoi explain --format=yaml ~/.bashrc ~/.config/nvim",
+		longFlags: chezmoiset.New(
+			"format",
+		),
+		shortFlags: chezmoiset.New(
+			"f",
+		),
+	},
 	"forget": {
 		longHelp: "" +
 			"  Remove targets from the source state, i.e. stop managing them. targets must\n" +
@@ -445,6 +522,7 @@ var helps = map[string]*help{
 			"\n" +
 			"   Output                | Description\n" +
 			"  -----------------------|--------------------------------------------------\n" +
+			"   config-schema         | A JSON Schema for the config file\n" +
 			"   git-commit-message    | A git commit message, describing the changes to\n" +
 			"                         | the source directory.\n" +
 			"   install.sh            | An install script, suitable for use with GitHub\n" +
@@ -453,6 +531,7 @@ var helps = map[string]*help{
 			"                         | init, and executes your shell",
 		example: "" +
 			"  chezmoi generate install.sh > install.sh\n" +
+			"  chezmoi generate config-schema > chezmoi.schema.json\n" +
 			"  chezmoi git -- commit -m \"$(chezmoi generate git-commit-message)\"\n" +
 			"  chezmoi generate install-init-shell.sh $GITHUB_USERNAME",
 	},
@@ -598,6 +677,39 @@ var helps = map[string]*help{
 		example: "" +
 			"  chezmoi license",
 	},
+	"lint": {
+		longHelp: "" +
+			"  Check the source state for problems without applying it. lint parses every\n" +
+			"  template, file in .chezmoitemplates, .chezmoiexternal.$FORMAT,\n" +
+			"  .chezmoiignore, .chezmoiremove, and .chezmoireload with the configured\n" +
+			"  template functions and options, and reports:\n" +
+			"\n" +
+			"  • template syntax and execution errors,\n" +
+			"  • undefined template functions,\n" +
+			"  • references to template data keys that do not exist,\n" +
+			"  • data keys from .chezmoidata files or the data section of the config file\n" +
+			"  that are not referenced by any template,\n" +
+			"  • .chezmoiignore patterns that do not match any target in the source state,\n" +
+			"  • redundant attributes in source names, for example literal_ when it is not\n" +
+			"  needed or empty_ on a file that is not empty, and\n" +
+			"  • source entries that have the same target after their attributes are parsed.\n" +
+			"\n" +
+			"  chezmoi exits with code 0 (success) if no problems were found, or 1\n" +
+			"  (failure) otherwise.\n" +
+			"\n" +
+			"  Templates are checked with the template data of the current machine, so\n" +
+			"  references inside conditional blocks for other machines may be reported as\n" +
+			"  missing. Encrypted files are not checked.",
+		example: "" +
+			"  chezmoi lint\n" +
+			"  chezmoi lint --format=json",
+		longFlags: chezmoiset.New(
+			"format",
+		),
+		shortFlags: chezmoiset.New(
+			"f",
+		),
+	},
 	"list": {
 		longHelp: "" +
 			"  list is an alias for managed.",
@@ -671,6 +783,14 @@ var helps = map[string]*help{
 			"r",
 		),
 	},
+	"modules": {
+		longHelp: "" +
+			"  Manage modules.",
+		example: "" +
+			"  chezmoi modules list\n" +
+			"  chezmoi modules enable work\n" +
+			"  chezmoi modules disable gui",
+	},
 	"podman": {
 		longHelp: "" +
 			"  podman is an alias for docker.",
@@ -719,6 +839,48 @@ var helps = map[string]*help{
 			"  The remove command has been removed. Use the forget command or the destroy\n" +
 			"  command instead.",
 	},
+	"render-matrix": {
+		longHelp: "" +
+			"  Render every target with the template data of each profile configured in\n" +
+			"  renderMatrix.profiles and report each profile and target whose template\n" +
+			"  fails to execute or whose contents are empty. chezmoi exits with code 0\n" +
+			"  (success) if all targets render for all profiles, or 1 (failure) otherwise.\n" +
+			"\n" +
+			"  If no profiles are specified then all profiles are rendered.\n" +
+			"\n" +
+			"  Each profile has a name and can override the os, arch, hostname, and\n" +
+			"  osRelease template variables in .chezmoi. If a profile sets os to a value\n" +
+			"  different to the current machine's and does not set osRelease, then\n" +
+			"  .chezmoi.osRelease is not defined. A profile's data is merged over the\n" +
+			"  template data, like --override-data.\n" +
+			"\n" +
+			"  Template functions that query secret managers, for example bitwarden and\n" +
+			"  onepasswordRead, are replaced by stubs that return empty values. The values\n" +
+			"  returned by the stubs can be set in renderMatrix.stubs, keyed by template\n" +
+			"  function name. Targets with the empty_ attribute, modify_ scripts, and\n" +
+			"  targets from externals are not reported.\n" +
+			"\n" +
+			"    [[renderMatrix.profiles]]\n" +
+			"        name = \"laptop\"\n" +
+			"        os = \"darwin\"\n" +
+			"        arch = \"arm64\"\n" +
+			"        hostname = \"laptop\"\n" +
+			"\n" +
+			"    [[renderMatrix.profiles]]\n" +
+			"        name = \"server\"\n" +
+			"        os = \"linux\"\n" +
+			"        hostname = \"server\"\n" +
+			"        [renderMatrix.profiles.osRelease]\n" +
+			"            id = \"debian\"\n" +
+			"        [renderMatrix.profiles.data]\n" +
+			"            email = \"me@work.example.com\"\n" +
+			"\n" +
+			"    [renderMatrix.stubs.bitwarden.login]\n" +
+			"        password = \"stub\"",
+		example: "" +
+			"  chezmoi render-matrix\n" +
+			"  chezmoi render-matrix server",
+	},
 	"rm": {
 		longHelp: "" +
 			"  The rm command has been removed. Use the forget command or the destroy\n" +
@@ -726,8 +888,11 @@ var helps = map[string]*help{
 	},
 	"secret": {
 		longHelp: "" +
-			"  Verify chezmoi's integration with the system's keyring.",
+			"  Manage cached password manager responses and verify chezmoi's integration\n" +
+			"  with the system's keyring.",
 		example: "" +
+			"  chezmoi secret cache clear\n" +
+			"  chezmoi secret cache clear onepassword\n" +
 			"  chezmoi secret keyring set --service=service --user=user --value=password\n" +
 			"  chezmoi secret keyring get --service=service --user=user\n" +
 			"  chezmoi secret keyring delete --service=service --user=user",
@@ -735,7 +900,8 @@ var helps = map[string]*help{
 	"source-path": {
 		longHelp: "" +
 			"  Print the path to each target's source state. If no targets are specified\n" +
-			"  then print the source directory.",
+			"  then print the source directory. If sourceDir is a list of layers, the path\n" +
+			"  is in the layer that contributed the target.",
 		example: "" +
 			"  chezmoi source-path\n" +
 			"  chezmoi source-path ~/.bashrc",
@@ -812,6 +978,24 @@ var helps = map[string]*help{
 			"  chezmoi target-path\n" +
 			"  chezmoi target-path ~/.local/share/chezmoi/dot_zshrc",
 	},
+	"test": {
+		longHelp: "" +
+			"  Render targets with the template data from each test fixture in\n" +
+			"  .chezmoitests/ and compare them with their expected contents. Differences\n" +
+			"  are printed as diffs, followed by a line with the result of each fixture.\n" +
+			"  chezmoi exits with code 0 (success) if all fixtures pass, or 1 (failure)\n" +
+			"  otherwise.\n" +
+			"\n" +
+			"  If no fixtures are specified then all fixtures are run. Fixtures are named\n" +
+			"  by their filename without the .txtar extension.\n" +
+			"\n" +
+			"  Targets are rendered as if the destination directory were empty, so modify_\n" +
+			"  scripts receive empty input. Template functions that query secret managers\n" +
+			"  or run commands are executed normally.",
+		example: "" +
+			"  chezmoi test\n" +
+			"  chezmoi test work-laptop",
+	},
 	"unmanage": {
 		longHelp: "" +
 			"  unmanage is an alias for forget for symmetry with manage.",
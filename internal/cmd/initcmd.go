@@ -215,7 +215,9 @@ func (c *Config) runInitCmd(cmd *cobra.Command, args []string) error {
 			cmd:          cmd,
 			filter:       c.init.filter,
 			recursive:    false,
+			reload:       true,
 			umask:        c.Umask,
+			validate:     true,
 			preApplyFunc: c.defaultPreApplyFunc,
 		}); err != nil {
 			return err
@@ -10,6 +10,7 @@ import (
 )
 
 type interactiveTemplateFuncsConfig struct {
+	dataSchema        *chezmoi.TemplateDataSchema
 	forcePromptOnce   bool
 	promptBool        map[string]string
 	promptChoice      map[string]string
@@ -95,6 +96,10 @@ func (c *Config) promptBoolOnceInteractiveTemplateFunc(m map[string]any, path an
 		}
 	}
 
+	if defaultValue, ok := c.dataSchemaDefault(path).(bool); ok && len(args) == 0 {
+		args = []bool{defaultValue}
+	}
+
 	return c.promptBoolInteractiveTemplateFunc(prompt, args...)
 }
 
@@ -131,6 +136,10 @@ func (c *Config) promptChoiceOnceInteractiveTemplateFunc(
 		}
 	}
 
+	if defaultValue, ok := c.dataSchemaDefault(path).(string); ok && len(args) == 0 {
+		args = []string{defaultValue}
+	}
+
 	return c.promptChoiceInteractiveTemplateFunc(prompt, choices, args...)
 }
 
@@ -173,6 +182,10 @@ func (c *Config) promptMultichoiceOnceInteractiveTemplateFunc(
 		}
 	}
 
+	if defaultValue, ok := c.dataSchemaDefault(path).([]any); ok && len(args) == 0 {
+		args = []any{defaultValue}
+	}
+
 	return c.promptMultichoiceInteractiveTemplateFunc(prompt, choices, args...)
 }
 
@@ -202,6 +215,10 @@ func (c *Config) promptIntOnceInteractiveTemplateFunc(m map[string]any, path any
 		}
 	}
 
+	if defaultValue, ok := c.dataSchemaDefault(path).(int64); ok && len(args) == 0 {
+		args = []int64{defaultValue}
+	}
+
 	return c.promptIntInteractiveTemplateFunc(prompt, args...)
 }
 
@@ -231,5 +248,48 @@ func (c *Config) promptStringOnceInteractiveTemplateFunc(m map[string]any, path
 		}
 	}
 
+	if defaultValue, ok := c.dataSchemaDefault(path).(string); ok && len(args) == 0 {
+		args = []string{defaultValue}
+	}
+
+	// If the schema restricts the value to a set of strings, prompt for one
+	// of them.
+	if _, ok := c.interactiveTemplateFuncs.promptString[prompt]; !ok {
+		if choices := c.dataSchemaStringEnum(path); len(choices) != 0 {
+			return c.promptChoiceInteractiveTemplateFunc(prompt, choices, args...)
+		}
+	}
+
 	return c.promptStringInteractiveTemplateFunc(prompt, args...)
 }
+
+// dataSchemaDefault returns the default value of the template data at path
+// from the template data schema, or nil if there is no default value.
+func (c *Config) dataSchemaDefault(path any) any {
+	if c.interactiveTemplateFuncs.dataSchema == nil {
+		return nil
+	}
+	defaultValue, _ := c.interactiveTemplateFuncs.dataSchema.Default(dataSchemaPath(path))
+	return defaultValue
+}
+
+// dataSchemaStringEnum returns the allowed values of the template data at path
+// from the template data schema, if they are all strings.
+func (c *Config) dataSchemaStringEnum(path any) []any {
+	if c.interactiveTemplateFuncs.dataSchema == nil {
+		return nil
+	}
+	enum := c.interactiveTemplateFuncs.dataSchema.Enum(dataSchemaPath(path))
+	for _, value := range enum {
+		if _, ok := value.(string); !ok {
+			return nil
+		}
+	}
+	return enum
+}
+
+// dataSchemaPath returns the keys of path, which has already been validated.
+func dataSchemaPath(path any) []string {
+	keys, lastKey, _ := keysFromPath(path)
+	return append(keys, lastKey)
+}
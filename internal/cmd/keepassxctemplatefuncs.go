@@ -39,6 +39,7 @@ type keepassxcConfig struct {
 	Mode            keepassxcMode   `json:"mode"     mapstructure:"mode"     yaml:"mode"`
 	Args            []string        `json:"args"     mapstructure:"args"     yaml:"args"`
 	Prompt          bool            `json:"prompt"   mapstructure:"prompt"   yaml:"prompt"`
+	CacheTTL        time.Duration   `json:"cacheTTL" mapstructure:"cacheTTL" yaml:"cacheTTL"`
 	cmd             *exec.Cmd
 	console         *expect.Console
 	promptStr       string
@@ -154,14 +155,17 @@ func (c *Config) keepassxcOutput(command string, args ...string) ([]byte, error)
 		panic(errors.New("keepassxc.database not set"))
 	}
 
-	switch c.Keepassxc.Mode {
-	case keepassxcModeCachePassword:
-		return c.keepassxcOutputCachePassword(command, args...)
-	case keepassxcModeOpen:
-		return c.keepassxcOutputOpen(command, args...)
-	default:
-		panic(fmt.Sprintf("%s: invalid mode", c.Keepassxc.Mode))
-	}
+	cacheArgs := append([]string{c.Keepassxc.Database.String(), command}, args...)
+	return c.secretCacheOutput("keepassxc", c.Keepassxc.CacheTTL, cacheArgs, func() ([]byte, error) {
+		switch c.Keepassxc.Mode {
+		case keepassxcModeCachePassword:
+			return c.keepassxcOutputCachePassword(command, args...)
+		case keepassxcModeOpen:
+			return c.keepassxcOutputOpen(command, args...)
+		default:
+			panic(fmt.Sprintf("%s: invalid mode", c.Keepassxc.Mode))
+		}
+	})
 }
 
 // keepassxcOutputCachePassword returns the output of command and args,
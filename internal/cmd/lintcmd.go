@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+type lintCmdConfig struct {
+	format *choiceFlag
+}
+
+func (c *Config) newLintCmd() *cobra.Command {
+	lintCmd := &cobra.Command{
+		GroupID:           groupIDTemplate,
+		Use:               "lint",
+		Short:             "Check the source state for problems",
+		Long:              mustLongHelp("lint"),
+		Example:           example("lint"),
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE:              c.runLintCmd,
+		Annotations: newAnnotations(
+			persistentStateModeReadOnly,
+			requiresSourceDirectory,
+		),
+	}
+
+	lintCmd.Flags().VarP(c.lint.format, "format", "f", "Output format")
+	must(lintCmd.RegisterFlagCompletionFunc("format", c.lint.format.FlagCompletionFunc()))
+
+	return lintCmd
+}
+
+func (c *Config) runLintCmd(cmd *cobra.Command, args []string) error {
+	sourceState, err := c.newSourceState(cmd.Context(), cmd,
+		chezmoi.WithReadTemplates(false),
+		chezmoi.WithTemplateDataOnly(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	problems, err := sourceState.Lint()
+	if err != nil {
+		return err
+	}
+
+	if format := c.lint.format.String(); format != "" {
+		if problems == nil {
+			problems = []*chezmoi.LintProblem{}
+		}
+		if err := c.marshal(format, problems); err != nil {
+			return err
+		}
+	} else {
+		for _, problem := range problems {
+			if _, err := fmt.Fprintln(c.stdout, problem); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return chezmoi.ExitCodeError(1)
+	}
+	return nil
+}
@@ -944,6 +944,7 @@ func setup(env *testscript.Env) error {
 	env.Setenv("CHEZMOICONFIGDIR", path.Join(absSlashHomeDir, ".config", "chezmoi"))
 	env.Setenv("CHEZMOISOURCEDIR", path.Join(absSlashHomeDir, ".local", "share", "chezmoi"))
 	env.Setenv("CHEZMOI_GITHUB_TOKEN", os.Getenv("CHEZMOI_GITHUB_TOKEN"))
+	env.Setenv("CHEZMOI_SYSTEM_CONFIG_DIR", path.Join(filepath.ToSlash(env.WorkDir), "etc", "chezmoi"))
 
 	switch runtime.GOOS {
 	case "windows":
@@ -97,7 +97,7 @@ func (c *Config) runManagedCmd(cmd *cobra.Command, args []string, sourceState *c
 			entryPaths := &entryPaths{
 				targetRelPath:  targetRelPath,
 				Absolute:       c.DestDirAbsPath.Join(targetRelPath),
-				SourceAbsolute: c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath()),
+				SourceAbsolute: sourceState.SourceAbsPath(sourceStateEntry),
 				SourceRelative: sourceStateEntry.SourceRelPath(),
 			}
 			allEntryPaths = append(allEntryPaths, entryPaths)
@@ -47,7 +47,7 @@ func (c *Config) runMergeCmd(cmd *cobra.Command, args []string, sourceState *che
 
 	for _, targetRelPath := range targetRelPaths {
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
-		if err := c.doMerge(targetRelPath, sourceStateEntry); err != nil {
+		if err := c.doMerge(targetRelPath, sourceStateEntry, sourceState.SourceAbsPath(sourceStateEntry)); err != nil {
 			return err
 		}
 	}
@@ -57,9 +57,14 @@ func (c *Config) runMergeCmd(cmd *cobra.Command, args []string, sourceState *che
 
 // doMerge is the core merge functionality. It invokes the merge tool to do a
 // three-way merge between the destination, source, and target, including
-// transparently decrypting the file in the source state.
-func (c *Config) doMerge(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) (err error) {
-	sourceAbsPath := c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath())
+// transparently decrypting the file in the source state. sourceEntryAbsPath is
+// the absolute path of sourceStateEntry.
+func (c *Config) doMerge(
+	targetRelPath chezmoi.RelPath,
+	sourceStateEntry chezmoi.SourceStateEntry,
+	sourceEntryAbsPath chezmoi.AbsPath,
+) (err error) {
+	sourceAbsPath := sourceEntryAbsPath
 
 	// If the source state entry is an encrypted file, then decrypt it to a
 	// temporary directory and pass the plaintext to the merge command
@@ -188,7 +193,7 @@ func (c *Config) doMerge(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi
 			return err
 		}
 		if err := c.baseSystem.WriteFile(
-			c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath()),
+			sourceEntryAbsPath,
 			encryptedContents,
 			0o644,
 		); err != nil {
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+func (c *Config) newModulesCmd() *cobra.Command {
+	modulesCmd := &cobra.Command{
+		GroupID: groupIDAdvanced,
+		Use:     "modules",
+		Args:    cobra.NoArgs,
+		Short:   "Manage modules",
+		Long:    mustLongHelp("modules"),
+		Example: example("modules"),
+		Annotations: newAnnotations(
+			persistentStateModeNone,
+		),
+	}
+
+	modulesListCmd := &cobra.Command{
+		Use:               "list",
+		Short:             "List modules",
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE:              c.makeRunEWithSourceState(c.runModulesListCmd),
+		Annotations: newAnnotations(
+			persistentStateModeReadMockWrite,
+		),
+	}
+	modulesCmd.AddCommand(modulesListCmd)
+
+	modulesEnableCmd := &cobra.Command{
+		Use:               "enable module...",
+		Short:             "Enable modules",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: c.modulesValidArgs,
+		RunE:              c.makeRunEWithSourceState(c.runModulesEnableCmd),
+		Annotations: newAnnotations(
+			modifiesConfigFile,
+			persistentStateModeReadMockWrite,
+			requiresConfigDirectory,
+		),
+	}
+	modulesCmd.AddCommand(modulesEnableCmd)
+
+	modulesDisableCmd := &cobra.Command{
+		Use:               "disable module...",
+		Short:             "Disable modules",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: c.modulesValidArgs,
+		RunE:              c.makeRunEWithSourceState(c.runModulesDisableCmd),
+		Annotations: newAnnotations(
+			modifiesConfigFile,
+			persistentStateModeReadMockWrite,
+			requiresConfigDirectory,
+		),
+	}
+	modulesCmd.AddCommand(modulesDisableCmd)
+
+	return modulesCmd
+}
+
+// modulesValidArgs returns the completions for module names.
+func (c *Config) modulesValidArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	sourceState, err := c.getSourceState(cmd.Context(), cmd)
+	if err != nil {
+		cobra.CompErrorln(err.Error())
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var completions []string
+	for _, module := range sourceState.Modules() {
+		completions = append(completions, module.Name)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (c *Config) runModulesDisableCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+	return c.setModulesEnabled(sourceState, args, false)
+}
+
+func (c *Config) runModulesEnableCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+	return c.setModulesEnabled(sourceState, args, true)
+}
+
+func (c *Config) runModulesListCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+	resultWriter := tabwriter.NewWriter(c.stdout, 3, 0, 3, ' ', 0)
+	fmt.Fprint(resultWriter, "NAME\tSTATUS\tDESCRIPTION\n")
+	for _, module := range sourceState.Modules() {
+		status := "disabled"
+		if module.Enabled() {
+			status = "enabled"
+		}
+		fmt.Fprintf(resultWriter, "%s\t%s\t%s\n", module.Name, status, module.Description)
+	}
+	return resultWriter.Flush()
+}
+
+// setModulesEnabled records in the config file whether the modules with names
+// are enabled.
+func (c *Config) setModulesEnabled(sourceState *chezmoi.SourceState, names []string, enabled bool) error {
+	for _, name := range names {
+		if _, ok := sourceState.Module(name); !ok {
+			return fmt.Errorf("%s: unknown module", name)
+		}
+	}
+	return c.editConfigFile(func(format chezmoi.Format, data []byte) ([]byte, error) {
+		for _, name := range names {
+			var err error
+			if data, err = setConfigFileValue(format, data, []string{"modules", name}, enabled); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/coreos/go-semver/semver"
 
@@ -43,9 +44,10 @@ type onepasswordAccount struct {
 }
 
 type onepasswordConfig struct {
-	Command       string          `json:"command" mapstructure:"command" yaml:"command"`
-	Prompt        bool            `json:"prompt"  mapstructure:"prompt"  yaml:"prompt"`
-	Mode          onepasswordMode `json:"mode"    mapstructure:"mode"    yaml:"mode"`
+	Command       string          `json:"command"  mapstructure:"command"  yaml:"command"`
+	Prompt        bool            `json:"prompt"   mapstructure:"prompt"   yaml:"prompt"`
+	Mode          onepasswordMode `json:"mode"     mapstructure:"mode"     yaml:"mode"`
+	CacheTTL      time.Duration   `json:"cacheTTL" mapstructure:"cacheTTL" yaml:"cacheTTL"`
 	outputCache   map[string][]byte
 	sessionTokens map[string]string
 	accountMap    map[string]string
@@ -191,6 +193,22 @@ func (c *Config) onepasswordOutput(args *onepasswordArgs, withSessionToken withS
 		return output, nil
 	}
 
+	output, err := c.secretCacheOutput("onepassword", c.Onepassword.CacheTTL, args.args, func() ([]byte, error) {
+		return c.onepasswordUncachedOutput(args, withSessionToken)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Onepassword.outputCache == nil {
+		c.Onepassword.outputCache = make(map[string][]byte)
+	}
+	c.Onepassword.outputCache[key] = output
+
+	return output, nil
+}
+
+func (c *Config) onepasswordUncachedOutput(args *onepasswordArgs, withSessionToken withSessionTokenType) ([]byte, error) {
 	commandArgs := args.args
 	if c.Onepassword.Mode == onepasswordModeAccount && withSessionToken {
 		sessionToken, err := c.onepasswordGetOrRefreshSessionToken(args)
@@ -209,12 +227,6 @@ func (c *Config) onepasswordOutput(args *onepasswordArgs, withSessionToken withS
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
-
-	if c.Onepassword.outputCache == nil {
-		c.Onepassword.outputCache = make(map[string][]byte)
-	}
-	c.Onepassword.outputCache[key] = output
-
 	return output, nil
 }
 
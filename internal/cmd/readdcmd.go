@@ -160,7 +160,7 @@ TARGET_REL_PATH:
 				case choice == "diff":
 					if err := c.diffFile(
 						targetRelPath,
-						c.SourceDirAbsPath.Join(sourceStateFile.SourceRelPath().RelPath()), targetContents, targetStateFile.Perm(c.Umask),
+						sourceState.SourceAbsPath(sourceStateFile), targetContents, targetStateFile.Perm(c.Umask),
 						destAbsPath, actualContents, actualStateFile.Perm(),
 					); err != nil {
 						return err
@@ -354,7 +354,7 @@ func (c *Config) processExactDirs(
 				}
 
 				// This file was deleted from target - remove it from source
-				sourceAbsPath := c.SourceDirAbsPath.Join(sourceEntry.SourceRelPath().RelPath())
+				sourceAbsPath := sourceState.SourceAbsPath(sourceEntry)
 				if err := c.sourceSystem.RemoveAll(sourceAbsPath); err != nil {
 					return err
 				}
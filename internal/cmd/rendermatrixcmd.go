@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+	"chezmoi.io/chezmoi/v2/internal/chezmoiset"
+)
+
+// secretTemplateFuncNames are the names of the template functions that are
+// stubbed by render-matrix.
+var secretTemplateFuncNames = []string{
+	"awsSecretsManager",
+	"awsSecretsManagerRaw",
+	"azureKeyVault",
+	"bitwarden",
+	"bitwardenAttachment",
+	"bitwardenAttachmentByRef",
+	"bitwardenFields",
+	"bitwardenSecrets",
+	"dashlaneNote",
+	"dashlanePassword",
+	"decrypt",
+	"doppler",
+	"dopplerProjectJson",
+	"ejsonDecrypt",
+	"ejsonDecryptWithKey",
+	"gopass",
+	"gopassRaw",
+	"keepassxc",
+	"keepassxcAttachment",
+	"keepassxcAttribute",
+	"keeper",
+	"keeperDataFields",
+	"keeperFindPassword",
+	"keyring",
+	"lastpass",
+	"lastpassRaw",
+	"onepassword",
+	"onepasswordDetailsFields",
+	"onepasswordDocument",
+	"onepasswordItemFields",
+	"onepasswordRead",
+	"pass",
+	"passFields",
+	"passRaw",
+	"passhole",
+	"protonPass",
+	"protonPassJSON",
+	"rbw",
+	"rbwFields",
+	"secret",
+	"secretJSON",
+	"vault",
+}
+
+// A renderMatrixProfile describes a machine on which templates are rendered.
+type renderMatrixProfile struct {
+	Name      string         `json:"name"      mapstructure:"name"      yaml:"name"`
+	OS        string         `json:"os"        mapstructure:"os"        yaml:"os"`
+	Arch      string         `json:"arch"      mapstructure:"arch"      yaml:"arch"`
+	Hostname  string         `json:"hostname"  mapstructure:"hostname"  yaml:"hostname"`
+	OSRelease map[string]any `json:"osRelease" mapstructure:"osRelease" yaml:"osRelease"`
+	Data      map[string]any `json:"data"      mapstructure:"data"      yaml:"data"`
+}
+
+type renderMatrixCmdConfig struct {
+	Profiles []renderMatrixProfile `json:"profiles" mapstructure:"profiles" yaml:"profiles"`
+	Stubs    map[string]any        `json:"stubs"    mapstructure:"stubs"    yaml:"stubs"`
+}
+
+func (c *Config) newRenderMatrixCmd() *cobra.Command {
+	renderMatrixCmd := &cobra.Command{
+		GroupID:           groupIDTemplate,
+		Use:               "render-matrix [profile]...",
+		Short:             "Render all targets for each profile",
+		Long:              mustLongHelp("render-matrix"),
+		Example:           example("render-matrix"),
+		ValidArgsFunction: c.renderMatrixValidArgs,
+		RunE:              c.runRenderMatrixCmd,
+		Annotations: newAnnotations(
+			persistentStateModeReadOnly,
+			requiresSourceDirectory,
+		),
+	}
+
+	return renderMatrixCmd
+}
+
+func (c *Config) runRenderMatrixCmd(cmd *cobra.Command, args []string) error {
+	profiles, err := c.renderMatrixProfiles(args)
+	if err != nil {
+		return err
+	}
+
+	// Replace c's template functions, rather than passing them as a source
+	// state option, so that the stubs are also used by includeTemplate.
+	c.templateFuncs, err = c.stubSecretTemplateFuncs()
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, profile := range profiles {
+		problems, err := c.renderProfile(cmd, profile)
+		if err != nil {
+			problems = []string{err.Error()}
+		}
+		for _, problem := range problems {
+			failed = true
+			if _, err := fmt.Fprintf(c.stdout, "%s: %s\n", profile.Name, problem); err != nil {
+				return err
+			}
+		}
+	}
+
+	if failed {
+		return chezmoi.ExitCodeError(1)
+	}
+	return nil
+}
+
+// renderMatrixProfiles returns the profiles named in args, or all profiles if
+// args is empty.
+func (c *Config) renderMatrixProfiles(args []string) ([]renderMatrixProfile, error) {
+	profileNames := chezmoiset.New[string]()
+	for i, profile := range c.RenderMatrix.Profiles {
+		switch {
+		case profile.Name == "":
+			return nil, fmt.Errorf("renderMatrix.profiles[%d]: missing name", i)
+		case profileNames.Contains(profile.Name):
+			return nil, fmt.Errorf("%s: duplicate profile", profile.Name)
+		}
+		profileNames.Add(profile.Name)
+	}
+	if len(c.RenderMatrix.Profiles) == 0 {
+		return nil, errors.New("no profiles configured")
+	}
+
+	if len(args) == 0 {
+		return c.RenderMatrix.Profiles, nil
+	}
+	profiles := make([]renderMatrixProfile, 0, len(args))
+	for _, arg := range args {
+		index := slices.IndexFunc(c.RenderMatrix.Profiles, func(profile renderMatrixProfile) bool {
+			return profile.Name == arg
+		})
+		if index == -1 {
+			return nil, fmt.Errorf("%s: profile not found", arg)
+		}
+		profiles = append(profiles, c.RenderMatrix.Profiles[index])
+	}
+	return profiles, nil
+}
+
+// renderMatrixValidArgs returns the names of all profiles.
+func (c *Config) renderMatrixValidArgs(
+	cmd *cobra.Command,
+	args []string,
+	toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	completions := make([]string, 0, len(c.RenderMatrix.Profiles))
+	for _, profile := range c.RenderMatrix.Profiles {
+		if strings.HasPrefix(profile.Name, toComplete) && !slices.Contains(args, profile.Name) {
+			completions = append(completions, profile.Name)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// renderProfile renders all targets with the template data of profile and
+// returns a description of each target that failed to render or rendered
+// empty.
+func (c *Config) renderProfile(cmd *cobra.Command, profile renderMatrixProfile) ([]string, error) {
+	priorityTemplateData := make(map[string]any)
+	chezmoi.RecursiveMerge(priorityTemplateData, c.Data)
+	chezmoi.RecursiveMerge(priorityTemplateData, profile.Data)
+	sourceState, err := c.newSourceState(cmd.Context(), cmd,
+		chezmoi.WithDefaultTemplateDataFunc(func() map[string]any {
+			return c.getProfileTemplateDataMap(cmd, profile)
+		}),
+		chezmoi.WithPriorityTemplateData(priorityTemplateData),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	nullSystem := &chezmoi.NullSystem{}
+	for _, targetRelPath := range sourceState.TargetRelPaths() {
+		sourceStateFile, ok := sourceState.Get(targetRelPath).(*chezmoi.SourceStateFile)
+		if !ok || sourceStateFile.Origin().IsExternal() {
+			continue
+		}
+		switch sourceStateFile.Attr().Type {
+		case chezmoi.SourceFileTypeModify, chezmoi.SourceFileTypeRemove:
+			continue
+		}
+		contents, err := renderTarget(sourceState, nullSystem, c.DestDirAbsPath, targetRelPath)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", targetRelPath, err))
+		case len(bytes.TrimSpace(contents)) == 0 && !sourceStateFile.Attr().Empty:
+			problems = append(problems, fmt.Sprintf("%s: empty", targetRelPath))
+		}
+	}
+	return problems, nil
+}
+
+// getProfileTemplateDataMap returns the template data as a map with the
+// machine-specific values overridden by profile.
+func (c *Config) getProfileTemplateDataMap(cmd *cobra.Command, profile renderMatrixProfile) map[string]any {
+	templateDataMap := c.getTemplateDataMap(cmd)
+	chezmoiTemplateDataMap := templateDataMap["chezmoi"].(map[string]any) //nolint:forcetypeassert
+	if profile.OS != "" {
+		if profile.OS != chezmoiTemplateDataMap["os"] {
+			delete(chezmoiTemplateDataMap, "osRelease")
+		}
+		chezmoiTemplateDataMap["os"] = profile.OS
+	}
+	if profile.Arch != "" {
+		chezmoiTemplateDataMap["arch"] = profile.Arch
+	}
+	if profile.Hostname != "" {
+		chezmoiTemplateDataMap["hostname"] = profile.Hostname
+	}
+	if profile.OSRelease != nil {
+		chezmoiTemplateDataMap["osRelease"] = profile.OSRelease
+	}
+	return templateDataMap
+}
+
+// stubSecretTemplateFuncs returns a copy of c's template functions with all
+// functions that query secret managers replaced by stubs. The stubs return the
+// values configured in renderMatrix.stubs, or zero values otherwise.
+func (c *Config) stubSecretTemplateFuncs() (template.FuncMap, error) {
+	for name := range c.RenderMatrix.Stubs {
+		if !slices.Contains(secretTemplateFuncNames, name) {
+			return nil, fmt.Errorf("renderMatrix.stubs.%s: not a secret template function", name)
+		}
+	}
+
+	templateFuncs := maps.Clone(c.templateFuncs)
+	for _, name := range secretTemplateFuncNames {
+		funcType := reflect.TypeOf(templateFuncs[name])
+		results := make([]reflect.Value, 0, funcType.NumOut())
+		for i := range funcType.NumOut() {
+			results = append(results, reflect.Zero(funcType.Out(i)))
+		}
+		if value, ok := c.RenderMatrix.Stubs[name]; ok && value != nil {
+			resultValue := reflect.ValueOf(value)
+			if !resultValue.Type().AssignableTo(funcType.Out(0)) {
+				return nil, fmt.Errorf("renderMatrix.stubs.%s: cannot use %T as %s", name, value, funcType.Out(0))
+			}
+			results[0] = resultValue
+		}
+		templateFuncs[name] = reflect.MakeFunc(funcType, func([]reflect.Value) []reflect.Value {
+			return results
+		}).Interface()
+	}
+	return templateFuncs, nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+// restrictedTemplateFuncNames are the names of the template functions that are
+// blocked in restricted mode because they run commands, access the network,
+// write to the terminal, or write to the persistent state.
+var restrictedTemplateFuncNames = []string{
+	"cachedExec",
+	"cachedLookPath",
+	"cachedOutput",
+	"exec",
+	"getRedirectedURL",
+	"gitHubKeys",
+	"gitHubLatestRelease",
+	"gitHubLatestReleaseAssetURL",
+	"gitHubLatestTag",
+	"gitHubRelease",
+	"gitHubReleaseAssetURL",
+	"gitHubReleases",
+	"gitHubTags",
+	"output",
+	"outputList",
+	"writeToStdout",
+}
+
+// restrictTemplateFuncs replaces c's template functions that are blocked in
+// restricted mode with functions that return an error. include and
+// includeTemplate check that files are in the source directory layers with
+// checkRestrictedInclude.
+func (c *Config) restrictTemplateFuncs() {
+	for _, name := range restrictedTemplateFuncNames {
+		templateFunc, ok := c.templateFuncs[name]
+		if !ok {
+			continue
+		}
+		c.templateFuncs[name] = reflect.MakeFunc(reflect.TypeOf(templateFunc), func([]reflect.Value) []reflect.Value {
+			panic(fmt.Errorf("%s: blocked in restricted mode", name))
+		}).Interface()
+	}
+}
+
+// checkRestrictedInclude returns an error if filename, with symlinks resolved,
+// is outside the source directory layers.
+func (c *Config) checkRestrictedInclude(filename string) error {
+	sourceDirAbsPaths, err := c.sourceDirAbsPaths()
+	if err != nil {
+		return err
+	}
+
+	var absPaths []chezmoi.AbsPath
+	if filepath.IsAbs(filename) {
+		absPath, err := chezmoi.NewAbsPathFromExtPath(filename, c.homeDirAbsPath)
+		if err != nil {
+			return err
+		}
+		absPaths = append(absPaths, absPath)
+	} else {
+		for _, sourceDirAbsPath := range sourceDirAbsPaths {
+			absPaths = append(absPaths, sourceDirAbsPath.JoinString(filename))
+		}
+	}
+
+	realSourceDirAbsPaths := make([]chezmoi.AbsPath, 0, len(sourceDirAbsPaths))
+	for _, sourceDirAbsPath := range sourceDirAbsPaths {
+		realSourceDirAbsPath, err := evalSymlinks(sourceDirAbsPath)
+		if err != nil {
+			return err
+		}
+		realSourceDirAbsPaths = append(realSourceDirAbsPaths, realSourceDirAbsPath)
+	}
+
+	// Files that do not exist cannot be included.
+	for _, absPath := range absPaths {
+		switch realAbsPath, err := evalSymlinks(absPath); {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return err
+		case !isInDirs(absPath, sourceDirAbsPaths) || !isInDirs(realAbsPath, realSourceDirAbsPaths):
+			return fmt.Errorf("%s: outside source directory blocked in restricted mode", filename)
+		}
+	}
+	return nil
+}
+
+// evalSymlinks returns absPath with all symlinks resolved.
+func evalSymlinks(absPath chezmoi.AbsPath) (chezmoi.AbsPath, error) {
+	realPath, err := filepath.EvalSymlinks(absPath.String())
+	if err != nil {
+		return chezmoi.EmptyAbsPath, err
+	}
+	return chezmoi.NewAbsPath(realPath), nil
+}
+
+// isInDirs returns if absPath is in any of dirAbsPaths.
+func isInDirs(absPath chezmoi.AbsPath, dirAbsPaths []chezmoi.AbsPath) bool {
+	return slices.ContainsFunc(dirAbsPaths, func(dirAbsPath chezmoi.AbsPath) bool {
+		_, err := absPath.TrimDirPrefix(dirAbsPath)
+		return err == nil
+	})
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+// secretCacheProviders are the password managers whose responses can be cached
+// on disk.
+var secretCacheProviders = []string{
+	"bitwarden",
+	"keepassxc",
+	"onepassword",
+}
+
+var secretCacheDirRelPath = chezmoi.NewRelPath("secrets")
+
+// A secretCacheEntry is a cached password manager response.
+type secretCacheEntry struct {
+	RequestedAt time.Time `json:"requestedAt"`
+	Output      []byte    `json:"output"`
+}
+
+// secretCacheOutput returns the cached output of provider for args if it is
+// younger than ttl, otherwise it calls f and caches its output. Outputs are
+// only cached if ttl is positive and secrets can be encrypted.
+func (c *Config) secretCacheOutput(provider string, ttl time.Duration, args []string, f func() ([]byte, error)) ([]byte, error) {
+	if ttl <= 0 || !c.secretCacheEnabled {
+		return f()
+	}
+
+	absPath := c.secretCacheDirAbsPath().JoinString(provider, secretCacheKey(args))
+	now := time.Now()
+	switch entry, err := c.readSecretCacheEntry(absPath); {
+	case err != nil:
+		return nil, fmt.Errorf("%s: %w", absPath, err)
+	case entry != nil && now.Before(entry.RequestedAt.Add(ttl)):
+		return entry.Output, nil
+	}
+
+	output, err := f()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.writeSecretCacheEntry(absPath, &secretCacheEntry{
+		RequestedAt: now,
+		Output:      output,
+	}); err != nil {
+		return nil, fmt.Errorf("%s: %w", absPath, err)
+	}
+	return output, nil
+}
+
+// readSecretCacheEntry reads and decrypts the secret cache entry at absPath. It
+// returns nil if there is no entry. Entries that cannot be decrypted or decoded,
+// for example because the encryption key changed, are removed and treated as
+// missing.
+func (c *Config) readSecretCacheEntry(absPath chezmoi.AbsPath) (*secretCacheEntry, error) {
+	ciphertext, err := c.baseSystem.ReadFile(absPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	var entry secretCacheEntry
+	if plaintext, err := c.encryption.Decrypt(ciphertext); err != nil || json.Unmarshal(plaintext, &entry) != nil {
+		if err := c.baseSystem.Remove(absPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// writeSecretCacheEntry encrypts and writes entry to absPath.
+func (c *Config) writeSecretCacheEntry(absPath chezmoi.AbsPath, entry *secretCacheEntry) error {
+	plaintext, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := c.encryption.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+	if err := chezmoi.MkdirAll(c.baseSystem, absPath.Dir(), 0o700); err != nil {
+		return err
+	}
+	return c.baseSystem.WriteFile(absPath, ciphertext, 0o600)
+}
+
+// secretCacheDirAbsPath returns the absolute path to the secret cache.
+func (c *Config) secretCacheDirAbsPath() chezmoi.AbsPath {
+	return c.CacheDirAbsPath.Join(secretCacheDirRelPath)
+}
+
+// secretCacheKey returns the filename of the secret cache entry for args. The
+// filename is a hash so that it does not reveal which secrets are cached.
+func secretCacheKey(args []string) string {
+	sha256Sum := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	return hex.EncodeToString(sha256Sum[:])
+}
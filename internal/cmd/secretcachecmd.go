@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+)
+
+func (c *Config) newSecretCacheCmd() *cobra.Command {
+	secretCacheCmd := &cobra.Command{
+		Use:   "cache",
+		Args:  cobra.NoArgs,
+		Short: "Manipulate cached password manager responses",
+		Annotations: newAnnotations(
+			persistentStateModeNone,
+		),
+	}
+
+	secretCacheClearCmd := &cobra.Command{
+		Use:       "clear [provider]...",
+		Short:     "Clear cached password manager responses",
+		ValidArgs: secretCacheProviders,
+		Args:      cobra.OnlyValidArgs,
+		RunE:      c.runSecretCacheClearCmd,
+		Annotations: newAnnotations(
+			doesNotRequireValidConfig,
+			persistentStateModeNone,
+		),
+	}
+	secretCacheCmd.AddCommand(secretCacheClearCmd)
+
+	return secretCacheCmd
+}
+
+func (c *Config) runSecretCacheClearCmd(cmd *cobra.Command, args []string) error {
+	absPaths := []chezmoi.AbsPath{c.secretCacheDirAbsPath()}
+	if len(args) != 0 {
+		absPaths = absPaths[:0]
+		for _, provider := range args {
+			absPaths = append(absPaths, c.secretCacheDirAbsPath().JoinString(provider))
+		}
+	}
+	for _, absPath := range absPaths {
+		if err := c.baseSystem.RemoveAll(absPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -19,6 +19,8 @@ func (c *Config) newSecretCmd() *cobra.Command {
 		),
 	}
 
+	secretCmd.AddCommand(c.newSecretCacheCmd())
+
 	if secretKeyringCmd := c.newSecretKeyringCmd(); secretKeyringCmd != nil {
 		secretCmd.AddCommand(secretKeyringCmd)
 	}
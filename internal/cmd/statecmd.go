@@ -200,6 +200,7 @@ func (c *Config) runStateDumpCmd(cmd *cobra.Command, args []string) error {
 		"gitHubVersionReleaseState": gitHubVersionReleaseStateBucket,
 		"gitRepoExternalState":      chezmoi.GitRepoExternalStateBucket,
 		"scriptState":               chezmoi.ScriptStateBucket,
+		"templateFuncCacheState":    templateFuncCacheStateBucket,
 	})
 	if err != nil {
 		return err
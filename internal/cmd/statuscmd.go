@@ -51,6 +51,7 @@ func (c *Config) newStatusCmd() *cobra.Command {
 
 func (c *Config) runStatusCmd(cmd *cobra.Command, args []string) error {
 	builder := strings.Builder{}
+	var changedTargetRelPaths []chezmoi.RelPath
 	preApplyFunc := func(targetRelPath chezmoi.RelPath, targetEntryState, lastWrittenEntryState, actualEntryState *chezmoi.EntryState) error {
 		c.logger.Info("statusPreApplyFunc",
 			chezmoilog.Stringer("targetRelPath", targetRelPath),
@@ -71,6 +72,10 @@ func (c *Config) runStatusCmd(cmd *cobra.Command, args []string) error {
 			y = statusRune(actualEntryState, targetEntryState)
 		}
 
+		if y != ' ' && targetEntryState.Type != chezmoi.EntryStateTypeScript {
+			changedTargetRelPaths = append(changedTargetRelPaths, targetRelPath)
+		}
+
 		if x != ' ' || y != ' ' {
 			var path string
 			switch pathStyle := c.Status.PathStyle.String(); pathStyle {
@@ -86,9 +91,10 @@ func (c *Config) runStatusCmd(cmd *cobra.Command, args []string) error {
 		}
 		return fs.SkipDir
 	}
+	filter := chezmoi.NewEntryTypeFilter(c.Status.include.Bits(), c.Status.Exclude.Bits())
 	if err := c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
 		cmd:          cmd,
-		filter:       chezmoi.NewEntryTypeFilter(c.Status.include.Bits(), c.Status.Exclude.Bits()),
+		filter:       filter,
 		init:         c.Status.init,
 		parentDirs:   c.Status.parentDirs,
 		recursive:    c.Status.recursive,
@@ -97,6 +103,18 @@ func (c *Config) runStatusCmd(cmd *cobra.Command, args []string) error {
 	}); err != nil {
 		return err
 	}
+
+	// Report reloads that would be run by chezmoi apply.
+	if filter.IncludeEntryTypeBits(chezmoi.EntryTypeScripts) {
+		sourceState, err := c.getSourceState(cmd.Context(), cmd)
+		if err != nil {
+			return err
+		}
+		for _, reload := range sourceState.Reloads(changedTargetRelPaths) {
+			fmt.Fprintf(&builder, " R %s\n", reload.Command)
+		}
+	}
+
 	return c.writeOutputString(builder.String(), 0o666)
 }
 
@@ -18,6 +18,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/bradenhilton/mozillainstallhash"
@@ -252,11 +253,38 @@ func (c *Config) hexEncodeTemplateFunc(s string) string {
 }
 
 func (c *Config) includeTemplateFunc(filename string) string {
-	searchDirAbsPaths := []chezmoi.AbsPath{c.sourceDirAbsPath}
-	return string(mustValue(c.readFile(filename, searchDirAbsPaths)))
+	return c.includeInSourceLayer(chezmoi.EmptyAbsPath, filename)
 }
 
 func (c *Config) includeTemplateTemplateFunc(filename string, args ...any) string {
+	return c.includeTemplateInSourceLayer(chezmoi.EmptyAbsPath, filename, args...)
+}
+
+// sourceLayerTemplateFuncs returns the include and includeTemplate template
+// functions for templates in the source directory layer sourceLayerAbsPath.
+func (c *Config) sourceLayerTemplateFuncs(sourceLayerAbsPath chezmoi.AbsPath) template.FuncMap {
+	return template.FuncMap{
+		"include": func(filename string) string {
+			return c.includeInSourceLayer(sourceLayerAbsPath, filename)
+		},
+		"includeTemplate": func(filename string, args ...any) string {
+			return c.includeTemplateInSourceLayer(sourceLayerAbsPath, filename, args...)
+		},
+	}
+}
+
+// includeInSourceLayer returns the contents of filename, searching
+// sourceLayerAbsPath first.
+func (c *Config) includeInSourceLayer(sourceLayerAbsPath chezmoi.AbsPath, filename string) string {
+	if c.Template.Restricted {
+		must(c.checkRestrictedInclude(filename))
+	}
+	return string(mustValue(c.readFile(filename, c.includeSearchDirAbsPaths(sourceLayerAbsPath))))
+}
+
+// includeTemplateInSourceLayer returns the result of executing the template
+// filename, searching sourceLayerAbsPath first.
+func (c *Config) includeTemplateInSourceLayer(sourceLayerAbsPath chezmoi.AbsPath, filename string, args ...any) string {
 	var data any
 	switch len(args) {
 	case 0:
@@ -267,20 +295,41 @@ func (c *Config) includeTemplateTemplateFunc(filename string, args ...any) strin
 		panic(fmt.Errorf("expected 0 or 1 arguments, got %d", len(args)))
 	}
 
-	searchDirAbsPaths := []chezmoi.AbsPath{
-		c.sourceDirAbsPath.JoinString(chezmoi.TemplatesDirName),
-		c.sourceDirAbsPath,
+	if c.Template.Restricted {
+		must(c.checkRestrictedInclude(filename))
+	}
+	var searchDirAbsPaths []chezmoi.AbsPath
+	for _, searchDirAbsPath := range c.includeSearchDirAbsPaths(sourceLayerAbsPath) {
+		searchDirAbsPaths = append(searchDirAbsPaths, searchDirAbsPath.JoinString(chezmoi.TemplatesDirName), searchDirAbsPath)
 	}
 	contents := mustValue(c.readFile(filename, searchDirAbsPaths))
 
 	tmpl := mustValue(chezmoi.ParseTemplate(filename, contents, chezmoi.TemplateOptions{
 		Funcs:   c.templateFuncs,
 		Options: slices.Clone(c.Template.Options),
+		Tracer:  c.templateTracer,
 	}))
 
 	return string(mustValue(tmpl.Execute(data)))
 }
 
+// includeSearchDirAbsPaths returns the directories that are searched for
+// included files: sourceLayerAbsPath, if set, followed by the other source
+// directory layers from last to first.
+func (c *Config) includeSearchDirAbsPaths(sourceLayerAbsPath chezmoi.AbsPath) []chezmoi.AbsPath {
+	sourceDirAbsPaths := mustValue(c.sourceDirAbsPaths())
+	searchDirAbsPaths := make([]chezmoi.AbsPath, 0, len(sourceDirAbsPaths)+1)
+	if !sourceLayerAbsPath.IsEmpty() {
+		searchDirAbsPaths = append(searchDirAbsPaths, sourceLayerAbsPath)
+	}
+	for _, sourceDirAbsPath := range slices.Backward(sourceDirAbsPaths) {
+		if sourceDirAbsPath != sourceLayerAbsPath {
+			searchDirAbsPaths = append(searchDirAbsPaths, sourceDirAbsPath)
+		}
+	}
+	return searchDirAbsPaths
+}
+
 func (c *Config) ioregTemplateFunc() map[string]any {
 	if runtime.GOOS != "darwin" {
 		return nil
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/rogpeppe/go-internal/txtar"
+	"github.com/spf13/cobra"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+	"chezmoi.io/chezmoi/v2/internal/chezmoiset"
+)
+
+// A testFixture is a set of template data and expected target contents.
+type testFixture struct {
+	name     string
+	absPath  chezmoi.AbsPath
+	data     map[string]any
+	expected []txtar.File
+}
+
+func (c *Config) newTestCmd() *cobra.Command {
+	testCmd := &cobra.Command{
+		GroupID:           groupIDTemplate,
+		Use:               "test [fixture]...",
+		Short:             "Test templates against fixtures",
+		Long:              mustLongHelp("test"),
+		Example:           example("test"),
+		ValidArgsFunction: c.testValidArgs,
+		RunE:              c.runTestCmd,
+		Annotations: newAnnotations(
+			persistentStateModeReadOnly,
+			requiresSourceDirectory,
+		),
+	}
+
+	return testCmd
+}
+
+func (c *Config) runTestCmd(cmd *cobra.Command, args []string) error {
+	testFixtures, err := c.readTestFixtures(args)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, testFixture := range testFixtures {
+		ok, err := c.runTestFixture(cmd, testFixture)
+		if err != nil {
+			return fmt.Errorf("%s: %w", testFixture.absPath, err)
+		}
+		result := "ok"
+		if !ok {
+			failed = true
+			result = "FAIL"
+		}
+		if _, err := fmt.Fprintf(c.stdout, "%s\t%s\n", result, testFixture.name); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return chezmoi.ExitCodeError(1)
+	}
+	return nil
+}
+
+// readTestFixtures reads the test fixtures named in args, or all test fixtures
+// if args is empty.
+func (c *Config) readTestFixtures(args []string) ([]*testFixture, error) {
+	testsDirAbsPath, err := c.testsDirAbsPath()
+	if err != nil {
+		return nil, err
+	}
+	names, err := c.testFixtureNames(testsDirAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 0 {
+		for _, arg := range args {
+			if !slices.Contains(names, arg) {
+				return nil, fmt.Errorf("%s: test fixture not found", arg)
+			}
+		}
+		names = args
+	}
+
+	testFixtures := make([]*testFixture, 0, len(names))
+	for _, name := range names {
+		absPath := testsDirAbsPath.JoinString(name + ".txtar")
+		data, err := c.sourceSystem.ReadFile(absPath)
+		if err != nil {
+			return nil, err
+		}
+		testFixture, err := parseTestFixture(name, absPath, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", absPath, err)
+		}
+		testFixtures = append(testFixtures, testFixture)
+	}
+	return testFixtures, nil
+}
+
+// runTestFixture renders the targets in testFixture and writes the differences
+// from their expected contents to c.stdout. It returns whether all targets
+// matched their expected contents.
+func (c *Config) runTestFixture(cmd *cobra.Command, testFixture *testFixture) (bool, error) {
+	priorityTemplateData := make(map[string]any)
+	chezmoi.RecursiveMerge(priorityTemplateData, c.Data)
+	chezmoi.RecursiveMerge(priorityTemplateData, testFixture.data)
+	sourceState, err := c.newSourceState(cmd.Context(), cmd,
+		chezmoi.WithPriorityTemplateData(priorityTemplateData),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	var builder strings.Builder
+	unifiedEncoder := diff.NewUnifiedEncoder(&builder, diff.DefaultContextLines)
+	if c.Color.Value(c.colorAutoFunc) {
+		unifiedEncoder.SetColor(diff.NewColorConfig())
+	}
+	nullSystem := &chezmoi.NullSystem{}
+	ok := true
+	for _, file := range testFixture.expected {
+		targetRelPath := chezmoi.NewRelPath(file.Name)
+		actual, err := renderTarget(sourceState, nullSystem, c.DestDirAbsPath, targetRelPath)
+		if err != nil {
+			ok = false
+			fmt.Fprintf(&builder, "%s: %s: %v\n", testFixture.name, targetRelPath, err)
+			continue
+		}
+		if bytes.Equal(actual, file.Data) {
+			continue
+		}
+		ok = false
+		diffPatch, err := chezmoi.DiffPatch(targetRelPath, file.Data, 0o644, actual, 0o644)
+		if err != nil {
+			return false, err
+		}
+		if err := unifiedEncoder.Encode(diffPatch); err != nil {
+			return false, err
+		}
+	}
+	return ok, c.writeOutputString(builder.String(), 0o666)
+}
+
+// testFixtureNames returns the names of all test fixtures in testsDirAbsPath.
+func (c *Config) testFixtureNames(testsDirAbsPath chezmoi.AbsPath) ([]string, error) {
+	dirEntries, err := c.sourceSystem.ReadDir(testsDirAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if name, ok := strings.CutSuffix(dirEntry.Name(), ".txtar"); ok && dirEntry.Type().IsRegular() {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// testValidArgs returns the names of all test fixtures.
+func (c *Config) testValidArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	testsDirAbsPath, err := c.testsDirAbsPath()
+	if err != nil {
+		cobra.CompErrorln(err.Error())
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names, err := c.testFixtureNames(testsDirAbsPath)
+	if err != nil {
+		cobra.CompErrorln(err.Error())
+		return nil, cobra.ShellCompDirectiveError
+	}
+	completions := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) && !slices.Contains(args, name) {
+			completions = append(completions, name)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// testsDirAbsPath returns the absolute path to the test fixtures directory.
+func (c *Config) testsDirAbsPath() (chezmoi.AbsPath, error) {
+	sourceDirAbsPath, err := c.getSourceDirAbsPath(nil)
+	if err != nil {
+		return chezmoi.EmptyAbsPath, err
+	}
+	return sourceDirAbsPath.JoinString(chezmoi.TestsDirName), nil
+}
+
+// parseTestFixture parses the test fixture called name at absPath from data.
+// Files called .chezmoidata.$FORMAT contain template data, all other files
+// contain the expected contents of targets.
+func parseTestFixture(name string, absPath chezmoi.AbsPath, data []byte) (*testFixture, error) {
+	archive := txtar.Parse(data)
+	testFixture := &testFixture{
+		name:    name,
+		absPath: absPath,
+		data:    make(map[string]any),
+	}
+	filenames := chezmoiset.New[string]()
+	for _, file := range archive.Files {
+		switch {
+		case file.Name == "":
+			return nil, errors.New("empty filename")
+		case filenames.Contains(file.Name):
+			return nil, fmt.Errorf("%s: duplicate filename", file.Name)
+		}
+		filenames.Add(file.Name)
+		if strings.HasPrefix(file.Name, chezmoi.Prefix+"data.") {
+			var data map[string]any
+			if err := chezmoi.UnmarshalFileData(chezmoi.NewAbsPath(file.Name), file.Data, &data); err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name, err)
+			}
+			chezmoi.RecursiveMerge(testFixture.data, data)
+			continue
+		}
+		testFixture.expected = append(testFixture.expected, file)
+	}
+	return testFixture, nil
+}
+
+// renderTarget returns the rendered contents of targetRelPath in sourceState.
+func renderTarget(
+	sourceState *chezmoi.SourceState,
+	destSystem chezmoi.System,
+	destDirAbsPath chezmoi.AbsPath,
+	targetRelPath chezmoi.RelPath,
+) ([]byte, error) {
+	sourceStateEntry := sourceState.Get(targetRelPath)
+	if sourceStateEntry == nil {
+		return nil, errors.New("not managed")
+	}
+	targetStateEntry, err := sourceStateEntry.TargetStateEntry(destSystem, destDirAbsPath.Join(targetRelPath))
+	if err != nil {
+		return nil, err
+	}
+	switch targetStateEntry := targetStateEntry.(type) {
+	case *chezmoi.TargetStateFile:
+		return targetStateEntry.Contents()
+	case *chezmoi.TargetStateScript:
+		return targetStateEntry.Contents()
+	case *chezmoi.TargetStateSymlink:
+		linkname, err := targetStateEntry.Linkname()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(linkname + "\n"), nil
+	default:
+		return nil, errors.New("not a file, script, or symlink")
+	}
+}
@@ -98,7 +98,9 @@ func (c *Config) runUpdateCmd(cmd *cobra.Command, args []string) error {
 			init:         c.Update.init,
 			parentDirs:   c.Update.parentDirs,
 			recursive:    c.Update.recursive,
+			reload:       true,
 			umask:        c.Umask,
+			validate:     true,
 			preApplyFunc: c.defaultPreApplyFunc,
 		}); err != nil {
 			return err
@@ -13,6 +13,12 @@ import (
 
 const defaultEditor = "vi"
 
+// defaultSystemConfigDir returns the default directory that contains the
+// system config file.
+func defaultSystemConfigDir() string {
+	return "/etc/chezmoi"
+}
+
 func fileInfoUID(info fs.FileInfo) int {
 	return int(info.Sys().(*syscall.Stat_t).Uid) //nolint:forcetypeassert
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/sys/windows/registry"
@@ -12,6 +13,16 @@ import (
 
 const defaultEditor = "notepad.exe"
 
+// defaultSystemConfigDir returns the default directory that contains the
+// system config file.
+func defaultSystemConfigDir() string {
+	programData, ok := os.LookupEnv("ProgramData")
+	if !ok {
+		return ""
+	}
+	return filepath.Join(programData, "chezmoi")
+}
+
 // getPS1Interpreter returns the appropriate Interpreter for PowerShell
 // scripts (.ps1) on Windows systems. It uses the provided findExecutable
 // function to check for the presence of 'pwsh'. If 'pwsh' is not found, it
@@ -0,0 +1,41 @@
+// generate-config-schema generates the JSON Schema for chezmoi's config file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"chezmoi.io/chezmoi/v2/internal/chezmoi"
+	"chezmoi.io/chezmoi/v2/internal/cmd"
+)
+
+var output = flag.String("o", "", "output")
+
+func run() error {
+	flag.Parse()
+
+	data, err := chezmoi.FormatJSON.Marshal(cmd.ConfigFileSchema())
+	if err != nil {
+		return err
+	}
+
+	if *output == "" || *output == "-" {
+		if _, err := os.Stdout.Write(data); err != nil {
+			return err
+		}
+	} else {
+		if err := os.WriteFile(*output, data, 0o666); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
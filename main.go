@@ -2,6 +2,7 @@
 //go:generate go tool chezmoi completion fish -o completions/chezmoi.fish
 //go:generate go tool chezmoi completion powershell -o completions/chezmoi.ps1
 //go:generate go tool chezmoi completion zsh -o completions/chezmoi.zsh
+//go:generate go tool generate-config-schema -o assets/chezmoi.io/docs/reference/configuration-file/chezmoi.schema.json
 //go:generate go tool generate-helps -o internal/cmd/helps.gen.go
 //go:generate go tool generate-install.sh -o assets/scripts/install.sh
 //go:generate go tool generate-install.sh -b .local/bin -o assets/scripts/install-local-bin.sh